@@ -6,10 +6,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 
 	"golang.org/x/net/context"
 
@@ -48,6 +50,10 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	rm := cmd.Bool([]string{"-rm"}, true, "Remove intermediate containers after a successful build")
 	forceRm := cmd.Bool([]string{"-force-rm"}, false, "Always remove intermediate containers")
 	pull := cmd.Bool([]string{"-pull"}, false, "Always attempt to pull a newer version of the image")
+	squash := cmd.Bool([]string{"-squash"}, false, "Squash newly built layers into a single new layer")
+	check := cmd.Bool([]string{"-check"}, false, "Check the Dockerfile for problems, without building it")
+	skipOnBuild := cmd.Bool([]string{"-skip-onbuild"}, false, "Skip the ONBUILD triggers of the base images")
+	parallel := cmd.Int([]string{"-parallel"}, 1, "Number of independent build stages to build at the same time")
 	dockerfileName := cmd.String([]string{"f", "-file"}, "", "Name of the Dockerfile (Default is 'PATH/Dockerfile')")
 	flMemoryString := cmd.String([]string{"m", "-memory"}, "", "Memory limit")
 	flMemorySwap := cmd.String([]string{"-memory-swap"}, "", "Swap limit equal to memory plus swap: '-1' to enable unlimited swap")
@@ -69,6 +75,16 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	flUlimits := runconfigopts.NewUlimitOpt(&ulimits)
 	cmd.Var(flUlimits, []string{"-ulimit"}, "Ulimit options")
 
+	flCacheFrom := opts.NewListOpts(nil)
+	cmd.Var(&flCacheFrom, []string{"-cache-from"}, "Images to consider as cache sources")
+
+	flNetworkMode := cmd.String([]string{"-network"}, "default", "Networking mode for the RUN instructions during build")
+	flExtraHosts := opts.NewListOpts(runconfigopts.ValidateExtraHost)
+	cmd.Var(&flExtraHosts, []string{"-add-host"}, "Add a custom host-to-IP mapping (host:ip)")
+
+	flSecrets := opts.NewListOpts(nil)
+	cmd.Var(&flSecrets, []string{"-secret"}, "Secret file to expose to the build (id=mysecret,src=/local/secret)")
+
 	cmd.Require(flag.Exact, 1)
 
 	// For trusted pull on "FROM <image>" instruction.
@@ -87,6 +103,7 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		contextDir    string
 		tempDir       string
 		relDockerfile string
+		contextSize   int64
 		progBuff      io.Writer
 		buildBuff     io.Writer
 	)
@@ -121,6 +138,13 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		contextDir = tempDir
 	}
 
+	if *check {
+		if contextDir == "" {
+			return fmt.Errorf("--check requires the build context to be a local directory or a Git repository")
+		}
+		return cli.checkDockerfile(contextDir, relDockerfile)
+	}
+
 	if ctx == nil {
 		// And canonicalize dockerfile name to a platform-independent one
 		relDockerfile, err = archive.CanonicalTarNameForPath(relDockerfile)
@@ -141,7 +165,8 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 			}
 		}
 
-		if err := builder.ValidateContextDirectory(contextDir, excludes); err != nil {
+		contextSize, err = builder.ValidateContextDirectory(contextDir, excludes)
+		if err != nil {
 			return fmt.Errorf("Error checking context: '%s'.", err)
 		}
 
@@ -179,7 +204,7 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	// Setup an upload progress bar
 	progressOutput := streamformatter.NewStreamFormatter().NewProgressOutput(progBuff, true)
 
-	var body io.Reader = progress.NewProgressReader(ctx, progressOutput, 0, "", "Sending build context to Docker daemon")
+	var body io.Reader = progress.NewProgressReader(ctx, progressOutput, contextSize, "", "Sending build context to Docker daemon")
 
 	var memory int64
 	if *flMemoryString != "" {
@@ -211,6 +236,11 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		}
 	}
 
+	secrets, err := readBuildSecrets(flSecrets.GetAll())
+	if err != nil {
+		return err
+	}
+
 	options := types.ImageBuildOptions{
 		Context:        body,
 		Memory:         memory,
@@ -234,6 +264,13 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		BuildArgs:      runconfigopts.ConvertKVStringsToMap(flBuildArg.GetAll()),
 		AuthConfigs:    cli.retrieveAuthConfigs(),
 		Labels:         runconfigopts.ConvertKVStringsToMap(flLabels.GetAll()),
+		CacheFrom:      flCacheFrom.GetAll(),
+		Squash:         *squash,
+		Secrets:        secrets,
+		NetworkMode:    *flNetworkMode,
+		ExtraHosts:     flExtraHosts.GetAll(),
+		Parallelism:    *parallel,
+		SkipOnBuild:    *skipOnBuild,
 	}
 
 	response, err := cli.client.ImageBuild(context.Background(), options)
@@ -291,8 +328,78 @@ func validateTag(rawRepo string) (string, error) {
 	return rawRepo, nil
 }
 
+// readBuildSecrets reads the files of the secrets given with --secret, in the
+// form id=<id>,src=<path>, and returns their content by ID.
+func readBuildSecrets(specs []string) (map[string][]byte, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	secrets := make(map[string][]byte)
+	for _, spec := range specs {
+		var id, src string
+		for _, field := range strings.Split(spec, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid field %q in secret %q", field, spec)
+			}
+			switch strings.ToLower(parts[0]) {
+			case "id":
+				id = parts[1]
+			case "src", "source":
+				src = parts[1]
+			default:
+				return nil, fmt.Errorf("invalid field %q in secret %q", field, spec)
+			}
+		}
+		if id == "" || src == "" {
+			return nil, fmt.Errorf("secret %q must have an id and a src", spec)
+		}
+		if _, exists := secrets[id]; exists {
+			return nil, fmt.Errorf("secret %s is specified more than once", id)
+		}
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read secret %s: %v", id, err)
+		}
+		secrets[id] = data
+	}
+	return secrets, nil
+}
+
 var dockerfileFromLinePattern = regexp.MustCompile(`(?i)^[\s]*FROM[ \f\r\t\v]+(?P<image>[^ \f\r\t\v\n#]+)`)
 
+// checkDockerfile sends the Dockerfile relDockerfile of contextDir to the
+// daemon to be checked, and prints the problems found in it. It fails if any
+// of them would fail the build.
+func (cli *DockerCli) checkDockerfile(contextDir, relDockerfile string) error {
+	f, err := os.Open(filepath.Join(contextDir, relDockerfile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	problems, err := cli.client.DockerfileCheck(context.Background(), f)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, p := range problems {
+		location := relDockerfile
+		if p.Line > 0 {
+			location = fmt.Sprintf("%s:%d", relDockerfile, p.Line)
+		}
+		fmt.Fprintf(cli.out, "%s: %s: %s\n", location, p.Severity, p.Message)
+		if p.Severity == "error" {
+			failed = true
+		}
+	}
+	if failed {
+		return Cli.StatusError{StatusCode: 1}
+	}
+	return nil
+}
+
 // resolvedTag records the repository, tag, and resolved digest reference
 // from a Dockerfile rewrite.
 type resolvedTag struct {
@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -165,5 +166,19 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 			fmt.Fprintf(cli.out, " %s/%d\n", registry.IP.String(), mask)
 		}
 	}
+
+	if info.RegistryConfig != nil && len(info.RegistryConfig.MirrorStatus) > 0 {
+		fmt.Fprintln(cli.out, "Registry Mirrors:")
+		for _, mirror := range info.RegistryConfig.MirrorStatus {
+			switch {
+			case !mirror.Available:
+				fmt.Fprintf(cli.out, " %s (unavailable until %s, %d failures: %s)\n", mirror.URL, mirror.RetryAt.Format(time.RFC3339), mirror.Failures, mirror.LastError)
+			case mirror.Failures > 0:
+				fmt.Fprintf(cli.out, " %s (%d failures: %s)\n", mirror.URL, mirror.Failures, mirror.LastError)
+			default:
+				fmt.Fprintf(cli.out, " %s\n", mirror.URL)
+			}
+		}
+	}
 	return nil
 }
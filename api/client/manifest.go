@@ -0,0 +1,329 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	Cli "github.com/docker/docker/cli"
+	"github.com/docker/docker/cliconfig"
+	"github.com/docker/docker/pkg/jsonmessage"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	"github.com/docker/engine-api/types"
+)
+
+// CmdManifest is the parent subcommand for all manifest list commands
+//
+// Usage: docker manifest <COMMAND> <OPTS>
+func (cli *DockerCli) CmdManifest(args ...string) error {
+	description := Cli.DockerCommands["manifest"].Description + "\n\nCommands:\n"
+	commands := [][]string{
+		{"annotate", "Set the platform of an image of a manifest list"},
+		{"create", "Create a manifest list"},
+		{"inspect", "Display the images of a manifest list"},
+		{"push", "Push a manifest list to a registry"},
+		{"rm", "Remove a manifest list"},
+	}
+
+	for _, cmd := range commands {
+		description += fmt.Sprintf("  %-25.25s%s\n", cmd[0], cmd[1])
+	}
+
+	description += "\nRun 'docker manifest COMMAND --help' for more information on a command"
+	cmd := Cli.Subcmd("manifest", []string{"[COMMAND]"}, description, false)
+
+	cmd.Require(flag.Exact, 0)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdManifestCreate creates a manifest list from images of a repository.
+// The list is kept by the client until it is pushed.
+//
+// Usage: docker manifest create [OPTIONS] MANIFEST_LIST IMAGE [IMAGE...]
+func (cli *DockerCli) CmdManifestCreate(args ...string) error {
+	cmd := Cli.Subcmd("manifest create", []string{"MANIFEST_LIST IMAGE [IMAGE...]"}, "Create a manifest list from images of its repository", true)
+	amend := cmd.Bool([]string{"a", "-amend"}, false, "Add the images to an existing manifest list")
+	cmd.Require(flag.Min, 2)
+
+	cmd.ParseFlags(args, true)
+
+	listRef, err := parseManifestListRef(cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	images, err := loadManifestList(listRef)
+	if err != nil {
+		return err
+	}
+	if images != nil && !*amend {
+		return fmt.Errorf("manifest list %s already exists, use --amend to add images to it", listRef.String())
+	}
+
+	for _, name := range cmd.Args()[1:] {
+		imageRef, err := reference.ParseNamed(name)
+		if err != nil {
+			return err
+		}
+		if imageRef.Name() != listRef.Name() {
+			return fmt.Errorf("%s is not in repository %s, the images of a manifest list must be pushed to its repository", name, listRef.Name())
+		}
+		imageRef = reference.WithDefaultTag(imageRef)
+		if manifestListIndex(images, imageRef) < 0 {
+			images = append(images, types.ManifestListEntry{Image: imageRef.String()})
+		}
+	}
+
+	if err := saveManifestList(listRef, images); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Created manifest list %s\n", listRef.String())
+	return nil
+}
+
+// CmdManifestAnnotate sets the platform of an image of a manifest list. The
+// fields that are not set are taken from the configuration of the image
+// when the list is pushed.
+//
+// Usage: docker manifest annotate [OPTIONS] MANIFEST_LIST IMAGE
+func (cli *DockerCli) CmdManifestAnnotate(args ...string) error {
+	cmd := Cli.Subcmd("manifest annotate", []string{"MANIFEST_LIST IMAGE"}, "Set the platform of an image of a manifest list", true)
+	flOS := cmd.String([]string{"-os"}, "", "Operating system of the image")
+	flArch := cmd.String([]string{"-arch"}, "", "Architecture of the image")
+	flOSVersion := cmd.String([]string{"-os-version"}, "", "Operating system version of the image")
+	flOSFeatures := cmd.String([]string{"-os-features"}, "", "Comma separated operating system features of the image")
+	flVariant := cmd.String([]string{"-variant"}, "", "Architecture variant of the image")
+	flFeatures := cmd.String([]string{"-cpu-features"}, "", "Comma separated CPU features of the image")
+	cmd.Require(flag.Exact, 2)
+
+	cmd.ParseFlags(args, true)
+
+	listRef, err := parseManifestListRef(cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	images, err := loadManifestList(listRef)
+	if err != nil {
+		return err
+	}
+	if images == nil {
+		return fmt.Errorf("manifest list %s does not exist", listRef.String())
+	}
+	imageRef, err := reference.ParseNamed(cmd.Arg(1))
+	if err != nil {
+		return err
+	}
+	i := manifestListIndex(images, reference.WithDefaultTag(imageRef))
+	if i < 0 {
+		return fmt.Errorf("%s is not in manifest list %s", cmd.Arg(1), listRef.String())
+	}
+
+	platform := &images[i].Platform
+	if *flOS != "" {
+		platform.OS = *flOS
+	}
+	if *flArch != "" {
+		platform.Architecture = *flArch
+	}
+	if *flOSVersion != "" {
+		platform.OSVersion = *flOSVersion
+	}
+	if *flOSFeatures != "" {
+		platform.OSFeatures = strings.Split(*flOSFeatures, ",")
+	}
+	if *flVariant != "" {
+		platform.Variant = *flVariant
+	}
+	if *flFeatures != "" {
+		platform.Features = strings.Split(*flFeatures, ",")
+	}
+
+	return saveManifestList(listRef, images)
+}
+
+// CmdManifestInspect displays the images of a manifest list and their
+// platform, as set by annotate.
+//
+// Usage: docker manifest inspect MANIFEST_LIST
+func (cli *DockerCli) CmdManifestInspect(args ...string) error {
+	cmd := Cli.Subcmd("manifest inspect", []string{"MANIFEST_LIST"}, "Display the images of a manifest list", true)
+	cmd.Require(flag.Exact, 1)
+
+	cmd.ParseFlags(args, true)
+
+	listRef, err := parseManifestListRef(cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	images, err := loadManifestList(listRef)
+	if err != nil {
+		return err
+	}
+	if images == nil {
+		return fmt.Errorf("manifest list %s does not exist", listRef.String())
+	}
+
+	b, err := json.MarshalIndent(images, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.out, string(b))
+	return nil
+}
+
+// CmdManifestPush pushes a manifest list to the registry of its repository.
+//
+// Usage: docker manifest push [OPTIONS] MANIFEST_LIST
+func (cli *DockerCli) CmdManifestPush(args ...string) error {
+	cmd := Cli.Subcmd("manifest push", []string{"MANIFEST_LIST"}, "Push a manifest list to a registry", true)
+	purge := cmd.Bool([]string{"p", "-purge"}, false, "Remove the manifest list after it is pushed")
+	cmd.Require(flag.Exact, 1)
+
+	cmd.ParseFlags(args, true)
+
+	listRef, err := parseManifestListRef(cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	images, err := loadManifestList(listRef)
+	if err != nil {
+		return err
+	}
+	if images == nil {
+		return fmt.Errorf("manifest list %s does not exist", listRef.String())
+	}
+
+	// Resolve the Repository name from fqn to RepositoryInfo
+	repoInfo, err := registry.ParseRepositoryInfo(listRef)
+	if err != nil {
+		return err
+	}
+	// Resolve the Auth config relevant for this server
+	authConfig := cli.resolveAuthConfig(repoInfo.Index)
+	encodedAuth, err := encodeAuthToBase64(authConfig)
+	if err != nil {
+		return err
+	}
+	options := types.ManifestListPushOptions{
+		Name:         listRef.Name(),
+		Tag:          listRef.Tag(),
+		Images:       images,
+		RegistryAuth: encodedAuth,
+	}
+	requestPrivilege := cli.registryAuthenticationPrivilegedFunc(repoInfo.Index, "push")
+
+	responseBody, err := cli.client.ManifestListPush(context.Background(), options, requestPrivilege)
+	if err != nil {
+		return err
+	}
+	defer responseBody.Close()
+
+	if err := jsonmessage.DisplayJSONMessagesStream(responseBody, cli.out, cli.outFd, cli.isTerminalOut, nil); err != nil {
+		return err
+	}
+	if *purge {
+		return os.Remove(manifestListPath(listRef))
+	}
+	return nil
+}
+
+// CmdManifestRm removes manifest lists that were not pushed, or are no
+// longer needed.
+//
+// Usage: docker manifest rm MANIFEST_LIST [MANIFEST_LIST...]
+func (cli *DockerCli) CmdManifestRm(args ...string) error {
+	cmd := Cli.Subcmd("manifest rm", []string{"MANIFEST_LIST [MANIFEST_LIST...]"}, "Remove one or more manifest lists", true)
+	cmd.Require(flag.Min, 1)
+
+	cmd.ParseFlags(args, true)
+
+	var status = 0
+	for _, name := range cmd.Args() {
+		listRef, err := parseManifestListRef(name)
+		if err == nil {
+			err = os.Remove(manifestListPath(listRef))
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("manifest list %s does not exist", listRef.String())
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(cli.out, "%s\n", name)
+	}
+
+	if status != 0 {
+		return Cli.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+// parseManifestListRef parses the name of a manifest list, which is tagged
+// with the default tag if it has none.
+func parseManifestListRef(name string) (reference.NamedTagged, error) {
+	ref, err := reference.ParseNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, isDigested := ref.(reference.Canonical); isDigested {
+		return nil, errors.New("a manifest list cannot be referred to by digest")
+	}
+	return reference.WithDefaultTag(ref).(reference.NamedTagged), nil
+}
+
+// manifestListPath returns the file the images of a manifest list are kept
+// in, in the manifests directory of the client configuration.
+func manifestListPath(ref reference.NamedTagged) string {
+	return filepath.Join(cliconfig.ConfigDir(), "manifests", url.QueryEscape(ref.String()))
+}
+
+// loadManifestList returns the images of a manifest list, or nil if the
+// list does not exist.
+func loadManifestList(ref reference.NamedTagged) ([]types.ManifestListEntry, error) {
+	b, err := ioutil.ReadFile(manifestListPath(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	images := []types.ManifestListEntry{}
+	if err := json.Unmarshal(b, &images); err != nil {
+		return nil, fmt.Errorf("invalid manifest list %s: %v", ref.String(), err)
+	}
+	return images, nil
+}
+
+func saveManifestList(ref reference.NamedTagged, images []types.ManifestListEntry) error {
+	path := manifestListPath(ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(images)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// manifestListIndex returns the index of the image ref in images, or -1.
+func manifestListIndex(images []types.ManifestListEntry, ref reference.Named) int {
+	for i, entry := range images {
+		if entry.Image == ref.String() {
+			return i
+		}
+	}
+	return -1
+}
@@ -371,6 +371,43 @@ func subnetMatches(subnet, data string) (bool, error) {
 	return s.Contains(ip), nil
 }
 
+// CmdNetworkPrune removes all networks not used by any container
+//
+// Usage: docker network prune [OPTIONS]
+func (cli *DockerCli) CmdNetworkPrune(args ...string) error {
+	cmd := Cli.Subcmd("network prune", nil, "Remove all unused networks", false)
+	force := cmd.Bool([]string{"f", "-force"}, false, "Do not prompt for confirmation")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"-filter"}, "Provide filter values (i.e. 'label=<key>=<value>')")
+
+	cmd.Require(flag.Exact, 0)
+	if err := cmd.ParseFlags(args, true); err != nil {
+		return err
+	}
+
+	pruneFilters, err := parseFilters(flFilter.GetAll())
+	if err != nil {
+		return err
+	}
+
+	if !*force && !cli.confirm("WARNING! This will remove all networks not used by at least one container.") {
+		return nil
+	}
+
+	report, err := cli.client.NetworksPrune(context.Background(), pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	if len(report.NetworksDeleted) > 0 {
+		fmt.Fprintln(cli.out, "Deleted Networks:")
+		for _, name := range report.NetworksDeleted {
+			fmt.Fprintln(cli.out, name)
+		}
+	}
+	return nil
+}
+
 func networkUsage() string {
 	networkCommands := [][]string{
 		{"create", "Create a network"},
@@ -378,6 +415,7 @@ func networkUsage() string {
 		{"disconnect", "Disconnect container from a network"},
 		{"inspect", "Display detailed network information"},
 		{"ls", "List all networks"},
+		{"prune", "Remove all unused networks"},
 		{"rm", "Remove a network"},
 	}
 
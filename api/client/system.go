@@ -0,0 +1,170 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/context"
+
+	Cli "github.com/docker/docker/cli"
+	flag "github.com/docker/docker/pkg/mflag"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/stringutils"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/go-units"
+)
+
+// CmdSystem is the parent subcommand for all system commands
+//
+// Usage: docker system <COMMAND> <OPTS>
+func (cli *DockerCli) CmdSystem(args ...string) error {
+	description := Cli.DockerCommands["system"].Description + "\n\nCommands:\n"
+	commands := [][]string{
+		{"df", "Show docker disk usage"},
+	}
+
+	for _, cmd := range commands {
+		description += fmt.Sprintf("  %-25.25s%s\n", cmd[0], cmd[1])
+	}
+
+	description += "\nRun 'docker system COMMAND --help' for more information on a command"
+	cmd := Cli.Subcmd("system", []string{"[COMMAND]"}, description, false)
+
+	cmd.Require(flag.Exact, 0)
+	err := cmd.ParseFlags(args, true)
+	cmd.Usage()
+	return err
+}
+
+// CmdSystemDf shows the disk space used by images, containers and volumes.
+//
+// Usage: docker system df [OPTIONS]
+func (cli *DockerCli) CmdSystemDf(args ...string) error {
+	cmd := Cli.Subcmd("system df", nil, "Show docker disk usage", true)
+	verbose := cmd.Bool([]string{"v", "-verbose"}, false, "Show detailed information on space usage")
+
+	cmd.Require(flag.Exact, 0)
+	cmd.ParseFlags(args, true)
+
+	du, err := cli.client.DiskUsage(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		cli.printDiskUsageVerbose(du)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE")
+
+	// Layers shared with images in use cannot be reclaimed, but the unique
+	// layers of unused images can.
+	var activeImages int
+	var usedImagesSize int64
+	for _, i := range du.Images {
+		if i.Containers > 0 {
+			activeImages++
+			usedImagesSize += i.Size - i.SharedSize
+		}
+	}
+	printDiskUsageSummary(w, "Images", len(du.Images), activeImages, du.LayersSize, du.LayersSize-usedImagesSize)
+
+	var activeContainers int
+	var containersSize, reclaimableContainersSize int64
+	for _, c := range du.Containers {
+		containersSize += c.SizeRw
+		if c.State == "running" || c.State == "paused" || c.State == "restarting" {
+			activeContainers++
+		} else {
+			reclaimableContainersSize += c.SizeRw
+		}
+	}
+	printDiskUsageSummary(w, "Containers", len(du.Containers), activeContainers, containersSize, reclaimableContainersSize)
+
+	var localVolumes, activeVolumes int
+	var volumesSize, reclaimableVolumesSize int64
+	for _, v := range du.Volumes {
+		if v.UsageData == nil || v.UsageData.Size == -1 {
+			continue
+		}
+		localVolumes++
+		volumesSize += v.UsageData.Size
+		if v.UsageData.RefCount > 0 {
+			activeVolumes++
+		} else {
+			reclaimableVolumesSize += v.UsageData.Size
+		}
+	}
+	printDiskUsageSummary(w, "Local Volumes", localVolumes, activeVolumes, volumesSize, reclaimableVolumesSize)
+
+	w.Flush()
+	return nil
+}
+
+func printDiskUsageSummary(w *tabwriter.Writer, typ string, total, active int, size, reclaimable int64) {
+	if reclaimable < 0 {
+		reclaimable = 0
+	}
+	var percent int64
+	if size > 0 {
+		percent = reclaimable * 100 / size
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s (%d%%)\n", typ, total, active, units.HumanSize(float64(size)), units.HumanSize(float64(reclaimable)), percent)
+}
+
+func (cli *DockerCli) printDiskUsageVerbose(du types.DiskUsage) {
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+
+	fmt.Fprintf(w, "Images space usage:\n\n")
+	fmt.Fprintln(w, "REPOSITORY\tTAG\tIMAGE ID\tCREATED\tSIZE\tSHARED SIZE\tUNIQUE SIZE\tCONTAINERS")
+	for _, i := range du.Images {
+		created := units.HumanDuration(time.Now().UTC().Sub(time.Unix(i.Created, 0))) + " ago"
+		repoTags := i.RepoTags
+		if len(repoTags) == 0 {
+			repoTags = []string{"<none>:<none>"}
+		}
+		for _, repoTag := range repoTags {
+			repo, tag := repoTag, "<none>"
+			if n := strings.LastIndex(repoTag, ":"); n >= 0 && !strings.Contains(repoTag[n:], "/") {
+				repo, tag = repoTag[:n], repoTag[n+1:]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", repo, tag, stringid.TruncateID(i.ID), created,
+				units.HumanSize(float64(i.Size)), units.HumanSize(float64(i.SharedSize)), units.HumanSize(float64(i.Size-i.SharedSize)), i.Containers)
+		}
+	}
+
+	fmt.Fprintf(w, "\nContainers space usage:\n\n")
+	fmt.Fprintln(w, "CONTAINER ID\tIMAGE\tCOMMAND\tLOCAL VOLUMES\tSIZE\tCREATED\tSTATUS\tNAMES")
+	for _, c := range du.Containers {
+		var localVolumes int
+		for _, m := range c.Mounts {
+			if m.Driver == "local" {
+				localVolumes++
+			}
+		}
+		var names []string
+		for _, name := range c.Names {
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+		created := units.HumanDuration(time.Now().UTC().Sub(time.Unix(c.Created, 0))) + " ago"
+		command := strconv.Quote(stringutils.Truncate(c.Command, 20))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", stringid.TruncateID(c.ID), c.Image, command, localVolumes,
+			units.HumanSize(float64(c.SizeRw)), created, c.Status, strings.Join(names, ","))
+	}
+
+	fmt.Fprintf(w, "\nLocal Volumes space usage:\n\n")
+	fmt.Fprintln(w, "NAME\tLINKS\tSIZE")
+	for _, v := range du.Volumes {
+		if v.UsageData == nil || v.UsageData.Size == -1 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", v.Name, v.UsageData.RefCount, units.HumanSize(float64(v.UsageData.Size)))
+	}
+
+	w.Flush()
+}
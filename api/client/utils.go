@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	gosignal "os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -20,6 +22,7 @@ import (
 	"github.com/docker/docker/registry"
 	"github.com/docker/engine-api/client"
 	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/filters"
 	registrytypes "github.com/docker/engine-api/types/registry"
 )
 
@@ -200,3 +203,24 @@ func (cli *DockerCli) retrieveAuthConfigs() map[string]types.AuthConfig {
 	acs, _ := getAllCredentials(cli.configFile)
 	return acs
 }
+
+// confirm prints the given warning followed by a prompt, and returns
+// whether the user answered yes.
+func (cli *DockerCli) confirm(warning string) bool {
+	fmt.Fprintf(cli.out, "%s\nAre you sure you want to continue? [y/N] ", warning)
+	answer, _, _ := bufio.NewReader(cli.in).ReadLine()
+	return strings.ToLower(strings.TrimSpace(string(answer))) == "y"
+}
+
+// parseFilters converts the values of a --filter flag to filter arguments.
+func parseFilters(flFilter []string) (filters.Args, error) {
+	filterArgs := filters.NewArgs()
+	for _, f := range flFilter {
+		var err error
+		filterArgs, err = filters.ParseFlag(f, filterArgs)
+		if err != nil {
+			return filterArgs, err
+		}
+	}
+	return filterArgs, nil
+}
@@ -13,6 +13,7 @@ import (
 	runconfigopts "github.com/docker/docker/runconfig/opts"
 	"github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/filters"
+	"github.com/docker/go-units"
 )
 
 // CmdVolume is the parent subcommand for all volume commands
@@ -24,6 +25,7 @@ func (cli *DockerCli) CmdVolume(args ...string) error {
 		{"create", "Create a volume"},
 		{"inspect", "Return low-level information on a volume"},
 		{"ls", "List volumes"},
+		{"prune", "Remove all unused volumes"},
 		{"rm", "Remove a volume"},
 	}
 
@@ -175,3 +177,40 @@ func (cli *DockerCli) CmdVolumeRm(args ...string) error {
 	}
 	return nil
 }
+
+// CmdVolumePrune removes all volumes not used by any container.
+//
+// Usage: docker volume prune [OPTIONS]
+func (cli *DockerCli) CmdVolumePrune(args ...string) error {
+	cmd := Cli.Subcmd("volume prune", nil, "Remove all unused volumes", true)
+	force := cmd.Bool([]string{"f", "-force"}, false, "Do not prompt for confirmation")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"-filter"}, "Provide filter values (i.e. 'label=<key>=<value>')")
+
+	cmd.Require(flag.Exact, 0)
+	cmd.ParseFlags(args, true)
+
+	pruneFilters, err := parseFilters(flFilter.GetAll())
+	if err != nil {
+		return err
+	}
+
+	if !*force && !cli.confirm("WARNING! This will remove all volumes not used by at least one container.") {
+		return nil
+	}
+
+	report, err := cli.client.VolumesPrune(context.Background(), pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	if len(report.VolumesDeleted) > 0 {
+		fmt.Fprintln(cli.out, "Deleted Volumes:")
+		for _, name := range report.VolumesDeleted {
+			fmt.Fprintln(cli.out, name)
+		}
+		fmt.Fprintln(cli.out)
+	}
+	fmt.Fprintf(cli.out, "Total reclaimed space: %s\n", units.HumanSize(float64(report.SpaceReclaimed)))
+	return nil
+}
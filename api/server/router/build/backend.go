@@ -17,4 +17,8 @@ type Backend interface {
 	//
 	// TODO: make this return a reference instead of string
 	BuildFromContext(ctx context.Context, src io.ReadCloser, remote string, buildOptions *types.ImageBuildOptions, pg backend.ProgressWriter) (string, error)
+
+	// CheckDockerfile reports the problems found in a Dockerfile, without
+	// building it.
+	CheckDockerfile(dockerfile io.Reader) []types.DockerfileProblem
 }
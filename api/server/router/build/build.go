@@ -25,5 +25,6 @@ func (r *buildRouter) Routes() []router.Route {
 func (r *buildRouter) initRoutes() {
 	r.routes = []router.Route{
 		router.Cancellable(router.NewPostRoute("/build", r.postBuild)),
+		router.NewPostRoute("/build/validate", r.postBuildValidate),
 	}
 }
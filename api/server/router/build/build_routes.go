@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,6 +18,8 @@ import (
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/docker/pkg/urlutil"
+	runconfigopts "github.com/docker/docker/runconfig/opts"
 	"github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/go-units"
@@ -50,6 +53,24 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 	options.CPUSetMems = r.FormValue("cpusetmems")
 	options.CgroupParent = r.FormValue("cgroupparent")
 	options.Tags = r.Form["t"]
+	options.Squash = httputils.BoolValue(r, "squash")
+	options.StructuredOutput = httputils.BoolValue(r, "structured")
+	options.Parallelism = int(httputils.Int64ValueOrZero(r, "parallelism"))
+	options.SkipOnBuild = httputils.BoolValue(r, "skiponbuild")
+
+	if runtime.GOOS == "windows" && options.Squash {
+		return nil, fmt.Errorf("squash is not supported for Windows containers")
+	}
+
+	if secretsEncoded := r.Header.Get("X-Build-Secrets"); secretsEncoded != "" {
+		if runtime.GOOS == "windows" {
+			return nil, fmt.Errorf("build secrets are not supported for Windows containers")
+		}
+		secretsJSON := base64.NewDecoder(base64.URLEncoding, strings.NewReader(secretsEncoded))
+		if err := json.NewDecoder(secretsJSON).Decode(&options.Secrets); err != nil {
+			return nil, fmt.Errorf("invalid build secrets: %v", err)
+		}
+	}
 
 	if r.Form.Get("shmsize") != "" {
 		shmSize, err := strconv.ParseInt(r.Form.Get("shmsize"), 10, 64)
@@ -92,6 +113,36 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 		options.Labels = labels
 	}
 
+	var cacheFrom = []string{}
+	cacheFromJSON := r.FormValue("cachefrom")
+	if cacheFromJSON != "" {
+		if err := json.NewDecoder(strings.NewReader(cacheFromJSON)).Decode(&cacheFrom); err != nil {
+			return nil, err
+		}
+		options.CacheFrom = cacheFrom
+	}
+
+	if nm := container.NetworkMode(r.FormValue("networkmode")); nm != "" {
+		if !nm.IsDefault() && !nm.IsBridge() && !nm.IsNone() && !nm.IsHost() && !nm.IsContainer() {
+			return nil, fmt.Errorf("Unsupported network mode for build: %q", nm)
+		}
+		options.NetworkMode = string(nm)
+	}
+
+	var extraHosts = []string{}
+	extraHostsJSON := r.FormValue("extrahosts")
+	if extraHostsJSON != "" {
+		if err := json.NewDecoder(strings.NewReader(extraHostsJSON)).Decode(&extraHosts); err != nil {
+			return nil, err
+		}
+		for _, extraHost := range extraHosts {
+			if _, err := runconfigopts.ValidateExtraHost(extraHost); err != nil {
+				return nil, err
+			}
+		}
+		options.ExtraHosts = extraHosts
+	}
+
 	return options, nil
 }
 
@@ -107,6 +158,16 @@ func (s *syncWriter) Write(b []byte) (count int, err error) {
 	return
 }
 
+// postBuildValidate checks the Dockerfile sent as the body of the request,
+// without a build context.
+func (br *buildRouter) postBuildValidate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	problems := br.backend.CheckDockerfile(r.Body)
+	if problems == nil {
+		problems = []types.DockerfileProblem{}
+	}
+	return httputils.WriteJSON(w, http.StatusOK, problems)
+}
+
 func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	var (
 		authConfigs        = map[string]types.AuthConfig{}
@@ -159,7 +220,7 @@ func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *
 		if buildOptions.SuppressOutput {
 			progressOutput = sf.NewProgressOutput(notVerboseBuffer, true)
 		}
-		return progress.NewProgressReader(in, progressOutput, r.ContentLength, "Downloading context", remoteURL)
+		return progress.NewProgressReader(in, progressOutput, r.ContentLength, "Downloading context", urlutil.Redact(remoteURL))
 	}
 
 	var out io.Writer = output
@@ -11,6 +11,7 @@ import (
 
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
 	"github.com/docker/docker/reference"
 	"github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/container"
@@ -138,12 +139,29 @@ type Backend interface {
 	//ContainerCopy(name string, res string) (io.ReadCloser, error)
 	// TODO: use copyBackend api
 	CopyOnBuild(containerID string, destPath string, src FileInfo, decompress bool) error
+	// MountImage mounts the root filesystem of the image referenced by
+	// `name` and returns its path, along with a function releasing it.
+	MountImage(name string) (string, func() error, error)
+	// SquashImage squashes the layers the image `id` adds on top of the
+	// image `parent` into one, and returns the ID of the new image.
+	SquashImage(id, parent string) (string, error)
+	// MountBuildSecrets writes `secrets` by ID into a directory kept off the
+	// disk and returns its path, along with a function removing it.
+	MountBuildSecrets(secrets map[string][]byte) (string, func() error, error)
 }
 
 // Image represents a Docker image used by the builder.
 type Image interface {
 	ImageID() string
 	RunConfig() *container.Config
+	LastLayer() layer.DiffID
+}
+
+// ImageCacheBuilder creates the image cache used by a build.
+type ImageCacheBuilder interface {
+	// MakeImageCache returns an image cache looking up the local images, and
+	// the history of the images referenced by `cacheFrom`.
+	MakeImageCache(cacheFrom []string) ImageCache
 }
 
 // ImageCache abstracts an image cache store.
@@ -151,5 +169,7 @@ type Image interface {
 type ImageCache interface {
 	// GetCachedImageOnBuild returns a reference to a cached image whose parent equals `parent`
 	// and runconfig equals `cfg`. A cache miss is expected to return an empty ID and a nil error.
-	GetCachedImageOnBuild(parentID string, cfg *container.Config) (imageID string, err error)
+	// `imageCfg` is the config the image would be committed with, in case the cached image
+	// has to be created from the history of another image.
+	GetCachedImageOnBuild(parentID string, cfg, imageCfg *container.Config) (imageID string, err error)
 }
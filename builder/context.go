@@ -18,24 +18,36 @@ import (
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/docker/pkg/urlutil"
 )
 
 // ValidateContextDirectory checks if all the contents of the directory
 // can be read and returns an error if some files can't be read
-// symlinks which point to non-existing files don't trigger an error
-func ValidateContextDirectory(srcPath string, excludes []string) error {
+// symlinks which point to non-existing files don't trigger an error.
+// It returns an estimate of the size of the archive of the context, used to
+// report the progress of its upload.
+func ValidateContextDirectory(srcPath string, excludes []string) (int64, error) {
 	contextRoot, err := getContextRoot(srcPath)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	pm, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return 0, err
 	}
-	return filepath.Walk(contextRoot, func(filePath string, f os.FileInfo, err error) error {
+
+	// the archive ends with two empty blocks
+	size := int64(2 * tarBlockSize)
+	err = filepath.Walk(contextRoot, func(filePath string, f os.FileInfo, err error) error {
 		// skip this directory/file if it's not in the path, it won't get added to the context
-		if relFilePath, err := filepath.Rel(contextRoot, filePath); err != nil {
-			return err
-		} else if skip, err := fileutils.Matches(relFilePath, excludes); err != nil {
-			return err
-		} else if skip {
-			if f.IsDir() {
+		relFilePath, relErr := filepath.Rel(contextRoot, filePath)
+		if relErr != nil {
+			return relErr
+		}
+		if relFilePath != "." && pm.Matches(relFilePath) {
+			// do not even look at the content of excluded directories,
+			// unless an exception pattern could match something in them
+			if f != nil && f.IsDir() && pm.CanSkipDir(relFilePath) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -51,6 +63,8 @@ func ValidateContextDirectory(srcPath string, excludes []string) error {
 			return err
 		}
 
+		size += tarEntrySize(f)
+
 		// skip checking if symlinks point to non-existing files, such symlinks can be useful
 		// also skip named pipes, because they hanging on open
 		if f.Mode()&(os.ModeSymlink|os.ModeNamedPipe) != 0 {
@@ -66,6 +80,19 @@ func ValidateContextDirectory(srcPath string, excludes []string) error {
 		}
 		return nil
 	})
+	return size, err
+}
+
+const tarBlockSize = 512
+
+// tarEntrySize returns the size of the entry of f in a tar archive, without
+// the extended headers of long names and attributes.
+func tarEntrySize(f os.FileInfo) int64 {
+	size := int64(tarBlockSize)
+	if f.Mode().IsRegular() {
+		size += (f.Size() + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+	}
+	return size
 }
 
 // GetContextFromReader will read the contents of the given reader as either a
@@ -142,12 +169,12 @@ func GetContextFromGitURL(gitURL, dockerfileName string) (absContextDir, relDock
 func GetContextFromURL(out io.Writer, remoteURL, dockerfileName string) (io.ReadCloser, string, error) {
 	response, err := httputils.Download(remoteURL)
 	if err != nil {
-		return nil, "", fmt.Errorf("unable to download remote context %s: %v", remoteURL, err)
+		return nil, "", fmt.Errorf("unable to download remote context %s: %v", urlutil.Redact(remoteURL), err)
 	}
 	progressOutput := streamformatter.NewStreamFormatter().NewProgressOutput(out, true)
 
 	// Pass the response body through a progress reader.
-	progReader := progress.NewProgressReader(response.Body, progressOutput, response.ContentLength, "", fmt.Sprintf("Downloading build context from remote url: %s", remoteURL))
+	progReader := progress.NewProgressReader(response.Body, progressOutput, response.ContentLength, "", fmt.Sprintf("Downloading build context from remote url: %s", urlutil.Redact(remoteURL)))
 
 	return GetContextFromReader(ioutils.NewReadCloserWrapper(progReader, func() error { return response.Body.Close() }), dockerfileName)
 }
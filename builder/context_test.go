@@ -54,7 +54,7 @@ func testValidateContextDirectory(t *testing.T, prepare func(t *testing.T) strin
 	contextDir := prepare(t)
 
 	defer os.RemoveAll(contextDir)
-	err := ValidateContextDirectory(contextDir, excludes)
+	_, err := ValidateContextDirectory(contextDir, excludes)
 
 	if err != nil {
 		t.Fatalf("Error should be nil, got: %s", err)
@@ -421,3 +421,34 @@ func TestValidateContextDirectoryWithOneFile(t *testing.T) {
 func TestValidateContextDirectoryWithOneFileExcludes(t *testing.T) {
 	testValidateContextDirectory(t, prepareOneFile, []string{dockerfileTestName})
 }
+
+func TestValidateContextDirectoryExceptions(t *testing.T) {
+	contextDir := prepareOneFile(t)
+	defer os.RemoveAll(contextDir)
+
+	files := map[string]string{
+		"node_modules/module/index.js": "module.exports = {}",
+		"docs/README.md":               "docs",
+		"docs/other.md":                "other",
+	}
+	for name, content := range files {
+		path := filepath.Join(contextDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size, err := ValidateContextDirectory(contextDir, []string{"node_modules", "docs", "!docs/README.md"})
+	if err != nil {
+		t.Fatalf("Error should be nil, got: %s", err)
+	}
+
+	// the end of the archive, the context root, the Dockerfile and the
+	// README, with a header and one block of content each
+	if expected := int64(2*512 + 512 + 2*(512+512)); size != expected {
+		t.Fatalf("expected an archive size of %d, got %d", expected, size)
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/command"
 	"github.com/docker/docker/builder/dockerfile/parser"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
@@ -28,6 +30,7 @@ var validCommitCommands = map[string]bool{
 	"expose":     true,
 	"label":      true,
 	"onbuild":    true,
+	"shell":      true,
 	"user":       true,
 	"volume":     true,
 	"workdir":    true,
@@ -54,22 +57,36 @@ type Builder struct {
 	Stderr io.Writer
 	Output io.Writer
 
-	docker    builder.Backend
-	context   builder.Context
-	clientCtx context.Context
-	cancel    context.CancelFunc
+	docker     builder.Backend
+	imageCache builder.ImageCache
+	context    builder.Context
+	clientCtx  context.Context
+	cancel     context.CancelFunc
 
 	dockerfile       *parser.Node
 	runConfig        *container.Config // runconfig for cmd, run, entrypoint etc.
 	flags            *BFlags
 	tmpContainers    map[string]struct{}
 	image            string // imageID
+	fromImage        string // imageID of the FROM of the current build stage
 	noBaseImage      bool
 	maintainer       string
 	cmdSet           bool
 	disableCommit    bool
 	cacheBusted      bool
-	allowedBuildArgs map[string]bool // list of build-time args that are allowed for expansion/substitution and passing to commands in 'run'.
+	cacheHit         bool              // whether the current step was taken from the cache
+	allowedBuildArgs map[string]bool   // list of build-time args that are allowed for expansion/substitution and passing to commands in 'run'.
+	buildArgDefaults map[string]string // default values given to the allowed build-time args by 'arg'.
+	declaredArgs     map[string]bool   // build-time args defined by an 'arg' in any build stage.
+
+	// state of multi-stage builds, where every FROM starts a new stage
+	inStage    bool
+	stages     []string       // image IDs of the completed build stages
+	stageNames map[string]int // index in stages of the named build stages
+
+	// secrets of the build, written on first use by a RUN instruction
+	secretsDir     string
+	releaseSecrets func() error
 
 	// TODO: remove once docker.Commit can receive a tag
 	id string
@@ -106,6 +123,12 @@ func (bm *BuildManager) BuildFromContext(ctx context.Context, src io.ReadCloser,
 	return b.build(pg.StdoutFormatter, pg.StderrFormatter, pg.Output)
 }
 
+// CheckDockerfile reports the problems found in a Dockerfile, without
+// building it.
+func (bm *BuildManager) CheckDockerfile(dockerfile io.Reader) []types.DockerfileProblem {
+	return Check(dockerfile)
+}
+
 // NewBuilder creates a new Dockerfile builder from an optional dockerfile and a Config.
 // If dockerfile is nil, the Dockerfile specified by Config.DockerfileName,
 // will be read from the Context passed to Build().
@@ -129,6 +152,12 @@ func NewBuilder(clientCtx context.Context, config *types.ImageBuildOptions, back
 		tmpContainers:    map[string]struct{}{},
 		id:               stringid.GenerateNonCryptoID(),
 		allowedBuildArgs: make(map[string]bool),
+		buildArgDefaults: make(map[string]string),
+		declaredArgs:     make(map[string]bool),
+		stageNames:       make(map[string]int),
+	}
+	if icb, ok := backend.(builder.ImageCacheBuilder); ok {
+		b.imageCache = icb.MakeImageCache(config.CacheFrom)
 	}
 	if dockerfile != nil {
 		b.dockerfile, err = parser.Parse(dockerfile)
@@ -212,49 +241,32 @@ func (b *Builder) build(stdout io.Writer, stderr io.Writer, out io.Writer) (stri
 		return "", err
 	}
 
-	var shortImgID string
-	for i, n := range b.dockerfile.Children {
-		// we only want to add labels to the last layer
-		if i == len(b.dockerfile.Children)-1 {
-			b.addLabels()
-		}
-		select {
-		case <-b.clientCtx.Done():
-			logrus.Debug("Builder: build cancelled!")
-			fmt.Fprintf(b.Stdout, "Build cancelled")
-			return "", fmt.Errorf("Build cancelled")
-		default:
-			// Not cancelled yet, keep going...
-		}
-		if err := b.dispatch(i, n); err != nil {
-			if b.options.ForceRemove {
-				b.clearTmp()
+	defer func() {
+		if b.releaseSecrets != nil {
+			if err := b.releaseSecrets(); err != nil {
+				logrus.Errorf("Error removing the secrets of the build: %v", err)
 			}
-			return "", err
 		}
+	}()
 
-		// Commit the layer when there are only one children in
-		// the dockerfile, this is only the `FROM` tag, and
-		// build labels. Otherwise, the new image won't be
-		// labeled properly.
-		// Commit here, so the ID of the final image is reported
-		// properly.
-		if len(b.dockerfile.Children) == 1 && len(b.options.Labels) > 0 {
-			b.commit("", b.runConfig.Cmd, "")
+	if stages := splitStages(b.dockerfile.Children); b.options.Parallelism > 1 && len(stages) > 1 {
+		if err := b.buildStages(stages); err != nil {
+			return "", err
 		}
-
-		shortImgID = stringid.TruncateID(b.image)
-		fmt.Fprintf(b.Stdout, " ---> %s\n", shortImgID)
-		if b.options.Remove {
-			b.clearTmp()
+	} else {
+		for i, n := range b.dockerfile.Children {
+			if err := b.step(i, n); err != nil {
+				return "", err
+			}
 		}
 	}
+	shortImgID := stringid.TruncateID(b.image)
 
 	// check if there are any leftover build-args that were passed but not
 	// consumed during build. Return an error, if there are any.
 	leftoverArgs := []string{}
 	for arg := range b.options.BuildArgs {
-		if !b.isBuildArgAllowed(arg) {
+		if !b.isBuildArgDeclared(arg) {
 			leftoverArgs = append(leftoverArgs, arg)
 		}
 	}
@@ -266,6 +278,16 @@ func (b *Builder) build(stdout io.Writer, stderr io.Writer, out io.Writer) (stri
 		return "", fmt.Errorf("No image was generated. Is your Dockerfile empty?")
 	}
 
+	if b.options.Squash && b.image != b.fromImage {
+		squashedID, err := b.docker.SquashImage(b.image, b.fromImage)
+		if err != nil {
+			return "", fmt.Errorf("failed to squash image: %v", err)
+		}
+		b.image = squashedID
+		shortImgID = stringid.TruncateID(b.image)
+		fmt.Fprintf(b.Stdout, "Squashed image layers into %s\n", shortImgID)
+	}
+
 	imageID := image.ID(b.image)
 	for _, rt := range repoAndTags {
 		if err := b.docker.TagImageWithReference(imageID, rt); err != nil {
@@ -277,6 +299,52 @@ func (b *Builder) build(stdout io.Writer, stderr io.Writer, out io.Writer) (stri
 	return b.image, nil
 }
 
+// step runs the instruction n, the i-th one of the Dockerfile.
+func (b *Builder) step(i int, n *parser.Node) error {
+	// a FROM that is not the first one starts a new build stage
+	if n.Value == command.From && b.inStage {
+		b.nextStage()
+	}
+	// we only want to add labels to the last layer
+	if i == len(b.dockerfile.Children)-1 {
+		b.addLabels()
+	}
+	select {
+	case <-b.clientCtx.Done():
+		logrus.Debug("Builder: build cancelled!")
+		fmt.Fprintf(b.Stdout, "Build cancelled")
+		return fmt.Errorf("Build cancelled")
+	default:
+		// Not cancelled yet, keep going...
+	}
+	start := time.Now()
+	parentImage := b.image
+	b.cacheHit = false
+	if err := b.dispatch(i, n); err != nil {
+		if b.options.ForceRemove {
+			b.clearTmp()
+		}
+		return err
+	}
+
+	// Commit the layer when there are only one children in
+	// the dockerfile, this is only the `FROM` tag, and
+	// build labels. Otherwise, the new image won't be
+	// labeled properly.
+	// Commit here, so the ID of the final image is reported
+	// properly.
+	if len(b.dockerfile.Children) == 1 && len(b.options.Labels) > 0 {
+		b.commit("", b.runConfig.Cmd, "")
+	}
+
+	b.status(" ---> %s\n", stringid.TruncateID(b.image))
+	b.reportStep(i, n, parentImage, time.Since(start))
+	if b.options.Remove {
+		b.clearTmp()
+	}
+	return nil
+}
+
 // Cancel cancels an ongoing Dockerfile build.
 func (b *Builder) Cancel() {
 	b.cancel()
@@ -0,0 +1,188 @@
+package dockerfile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/engine-api/types"
+)
+
+// Severities of the problems reported by Check.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// instructions of which only the last one of a build stage takes effect
+var lastOneWins = map[string]bool{
+	command.Cmd:         true,
+	command.Entrypoint:  true,
+	command.Healthcheck: true,
+}
+
+// Check parses dockerfile and reports the problems found in it, in the
+// order of the lines they are on, without running any instruction. Errors
+// are the problems that would fail the build: syntax errors, unknown
+// instructions, or invalid references to build stages. Warnings are about
+// deprecated instructions and instructions that have no effect.
+func Check(dockerfile io.Reader) []types.DockerfileProblem {
+	ast, err := parser.Parse(dockerfile)
+	if err != nil {
+		line := 0
+		if lineErr, ok := err.(*parser.LineError); ok {
+			line = lineErr.Line
+		}
+		return []types.DockerfileProblem{{Line: line, Severity: severityError, Message: err.Error()}}
+	}
+
+	c := &dockerfileChecker{
+		stageNames: make(map[string]bool),
+		lastOnes:   make(map[string]*parser.Node),
+	}
+	if len(ast.Children) == 0 {
+		c.report(0, severityError, "the Dockerfile has no instructions")
+	}
+	for _, n := range ast.Children {
+		c.check(n)
+	}
+	sort.Stable(byLine(c.problems))
+	return c.problems
+}
+
+// dockerfileChecker holds the state of Check from one instruction to the next.
+type dockerfileChecker struct {
+	problems []types.DockerfileProblem
+
+	stages           int             // number of build stages started so far
+	stageNames       map[string]bool // names of the build stages started so far
+	currentStageName string
+	lastOnes         map[string]*parser.Node // last CMD, ENTRYPOINT, ... of the current stage
+}
+
+func (c *dockerfileChecker) report(line int, severity, format string, a ...interface{}) {
+	c.problems = append(c.problems, types.DockerfileProblem{
+		Line:     line,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *dockerfileChecker) check(n *parser.Node) {
+	upperCasedCmd := strings.ToUpper(n.Value)
+	if !c.checkInstruction(n, n.StartLine) {
+		return
+	}
+
+	if n.Value == command.From {
+		c.checkFrom(n)
+		return
+	}
+	if c.stages == 0 {
+		c.report(n.StartLine, severityError, "%s is not allowed before the first FROM instruction", upperCasedCmd)
+	}
+
+	switch n.Value {
+	case command.Copy:
+		c.checkCopyFrom(n)
+	case command.Onbuild:
+		c.checkOnbuild(n)
+	}
+	if lastOneWins[n.Value] {
+		if previous, ok := c.lastOnes[n.Value]; ok {
+			c.report(previous.StartLine, severityWarning, "%s has no effect, it is overridden by the %s on line %d", upperCasedCmd, upperCasedCmd, n.StartLine)
+		}
+		c.lastOnes[n.Value] = n
+	}
+}
+
+// checkInstruction checks the problems common to all instructions, including
+// the ones of ONBUILD triggers, and returns whether the instruction is known.
+func (c *dockerfileChecker) checkInstruction(n *parser.Node, line int) bool {
+	upperCasedCmd := strings.ToUpper(n.Value)
+	if _, ok := evaluateTable[n.Value]; !ok {
+		c.report(line, severityError, "Unknown instruction: %s", upperCasedCmd)
+		return false
+	}
+	if err := platformSupports(n.Value); err != nil {
+		c.report(line, severityError, "%v", err)
+	}
+
+	switch n.Value {
+	case command.Maintainer:
+		c.report(line, severityWarning, "MAINTAINER is deprecated, use a LABEL instead, such as LABEL maintainer=\"name\"")
+	case command.Run, command.Cmd, command.Entrypoint, command.Add, command.Copy, command.Volume:
+		if n.Next != nil && !n.Attributes["json"] && strings.HasPrefix(n.Next.Value, "[") {
+			c.report(line, severityWarning, "the arguments of %s are not a valid JSON array, they are used in shell form", upperCasedCmd)
+		}
+	}
+	return true
+}
+
+func (c *dockerfileChecker) checkFrom(n *parser.Node) {
+	c.stages++
+	c.currentStageName = ""
+	c.lastOnes = make(map[string]*parser.Node)
+
+	var args []string
+	for arg := n.Next; arg != nil; arg = arg.Next {
+		args = append(args, arg.Value)
+	}
+	switch {
+	case len(args) == 1:
+	case len(args) == 3 && strings.EqualFold(args[1], "as"):
+		name := strings.ToLower(args[2])
+		if !validStageName.MatchString(name) {
+			c.report(n.StartLine, severityError, "invalid name for build stage: %q, name must start with a letter and contain only letters, digits, '_', '-' and '.'", args[2])
+		} else if c.stageNames[name] {
+			c.report(n.StartLine, severityError, "duplicate name for build stage: %q", args[2])
+		}
+		c.stageNames[name] = true
+		c.currentStageName = name
+	default:
+		c.report(n.StartLine, severityError, "FROM requires either one argument, or three: FROM <image> [AS <name>]")
+	}
+}
+
+// checkCopyFrom checks that COPY --from refers to an earlier build stage.
+func (c *dockerfileChecker) checkCopyFrom(n *parser.Node) {
+	for _, flag := range n.Flags {
+		if !strings.HasPrefix(flag, "--from=") {
+			continue
+		}
+		ref := strings.TrimPrefix(flag, "--from=")
+		if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < c.stages-1 {
+			continue
+		}
+		if name := strings.ToLower(ref); c.stageNames[name] && name != c.currentStageName {
+			continue
+		}
+		c.report(n.StartLine, severityError, "invalid from flag value %s: no such build stage, only earlier stages can be copied from", ref)
+	}
+}
+
+func (c *dockerfileChecker) checkOnbuild(n *parser.Node) {
+	if n.Next == nil || len(n.Next.Children) == 0 || n.Next.Children[0] == nil {
+		c.report(n.StartLine, severityError, "ONBUILD requires at least one argument")
+		return
+	}
+	trigger := n.Next.Children[0]
+	switch trigger.Value {
+	case command.Onbuild:
+		c.report(n.StartLine, severityError, "Chaining ONBUILD via `ONBUILD ONBUILD` isn't allowed")
+	case command.Maintainer, command.From:
+		c.report(n.StartLine, severityError, "%s isn't allowed as an ONBUILD trigger", strings.ToUpper(trigger.Value))
+	default:
+		c.checkInstruction(trigger, n.StartLine)
+	}
+}
+
+type byLine []types.DockerfileProblem
+
+func (p byLine) Len() int           { return len(p) }
+func (p byLine) Less(i, j int) bool { return p[i].Line < p[j].Line }
+func (p byLine) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
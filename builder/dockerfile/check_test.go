@@ -0,0 +1,56 @@
+package dockerfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+)
+
+func TestCheck(t *testing.T) {
+	dockerfile := `FROM busybox AS build
+MAINTAINER someone
+CMD ["make"]
+RUN ["make", 'all']
+CMD make all
+FOO bar
+ONBUILD FROM busybox
+ONBUILD BAR baz
+
+FROM busybox AS build
+COPY --from=build /out /out
+COPY --from=0 /out /out
+COPY --from=later /out /out
+FROM busybox AS later extra
+`
+	expected := []types.DockerfileProblem{
+		{Line: 2, Severity: "warning", Message: `MAINTAINER is deprecated, use a LABEL instead, such as LABEL maintainer="name"`},
+		{Line: 3, Severity: "warning", Message: "CMD has no effect, it is overridden by the CMD on line 5"},
+		{Line: 4, Severity: "warning", Message: "the arguments of RUN are not a valid JSON array, they are used in shell form"},
+		{Line: 6, Severity: "error", Message: "Unknown instruction: FOO"},
+		{Line: 7, Severity: "error", Message: "FROM isn't allowed as an ONBUILD trigger"},
+		{Line: 8, Severity: "error", Message: "Unknown instruction: BAR"},
+		{Line: 10, Severity: "error", Message: `duplicate name for build stage: "build"`},
+		{Line: 11, Severity: "error", Message: "invalid from flag value build: no such build stage, only earlier stages can be copied from"},
+		{Line: 13, Severity: "error", Message: "invalid from flag value later: no such build stage, only earlier stages can be copied from"},
+		{Line: 14, Severity: "error", Message: "FROM requires either one argument, or three: FROM <image> [AS <name>]"},
+	}
+	if problems := Check(strings.NewReader(dockerfile)); !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("expected problems:\n%v\ngot:\n%v", expected, problems)
+	}
+
+	problems := Check(strings.NewReader("FROM busybox\n\nENV PATH\n"))
+	if len(problems) != 1 || problems[0].Line != 3 || problems[0].Severity != "error" {
+		t.Fatalf("expected a syntax error on line 3, got %v", problems)
+	}
+
+	problems = Check(strings.NewReader("RUN make\nFROM busybox\n"))
+	if len(problems) != 1 || problems[0].Line != 1 || problems[0].Message != "RUN is not allowed before the first FROM instruction" {
+		t.Fatalf("expected an error for the instruction before FROM, got %v", problems)
+	}
+
+	if problems := Check(strings.NewReader("FROM busybox\nRUN make\n")); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+}
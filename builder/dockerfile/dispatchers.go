@@ -14,7 +14,9 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api"
@@ -27,6 +29,9 @@ import (
 	"github.com/docker/go-connections/nat"
 )
 
+// validStageName matches the names that can be given to build stages.
+var validStageName = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
 // ENV foo bar
 //
 // Sets the environment variable foo to bar, also makes interpolation
@@ -153,32 +158,65 @@ func add(b *Builder, args []string, attributes map[string]bool, original string)
 		return err
 	}
 
-	return b.runContextCommand(args, true, true, "ADD")
+	return b.runContextCommand(args, true, true, "ADD", b.context)
 }
 
 // COPY foo /path
+// COPY --from=stage foo /path
 //
-// Same as 'ADD' but without the tar and remote url handling.
+// Same as 'ADD' but without the tar and remote url handling. With --from,
+// the files are copied from the image of an earlier build stage instead of
+// the build context.
 //
 func dispatchCopy(b *Builder, args []string, attributes map[string]bool, original string) error {
 	if len(args) < 2 {
 		return errAtLeastOneArgument("COPY")
 	}
 
+	flFrom := b.flags.AddString("from", "")
+
 	if err := b.flags.Parse(); err != nil {
 		return err
 	}
 
-	return b.runContextCommand(args, false, false, "COPY")
+	if flFrom.Value == "" {
+		return b.runContextCommand(args, false, false, "COPY", b.context)
+	}
+
+	stageContext, err := b.stageContext(flFrom.Value)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stageContext.Close(); err != nil {
+			logrus.Errorf("[BUILDER] failed to release build stage %s: %v", flFrom.Value, err)
+		}
+	}()
+
+	return b.runContextCommand(args, false, false, "COPY", stageContext)
 }
 
 // FROM imagename
+// FROM imagename AS stagename
 //
-// This sets the image the dockerfile will build on top of.
+// This sets the image the dockerfile will build on top of. Every FROM starts
+// a new build stage, which can be named so that later stages can refer to
+// it, either in FROM or in COPY --from.
 //
 func from(b *Builder, args []string, attributes map[string]bool, original string) error {
-	if len(args) != 1 {
-		return errExactlyOneArgument("FROM")
+	var stageName string
+	switch {
+	case len(args) == 1:
+	case len(args) == 3 && strings.EqualFold(args[1], "as"):
+		stageName = strings.ToLower(args[2])
+		if !validStageName.MatchString(stageName) {
+			return fmt.Errorf("invalid name for build stage: %q, name must start with a letter and contain only letters, digits, '_', '-' and '.'", args[2])
+		}
+		if _, exists := b.stageNames[stageName]; exists {
+			return fmt.Errorf("duplicate name for build stage: %q", args[2])
+		}
+	default:
+		return fmt.Errorf("FROM requires either one argument, or three: FROM <image> [AS <name>]")
 	}
 
 	if err := b.flags.Parse(); err != nil {
@@ -186,6 +224,12 @@ func from(b *Builder, args []string, attributes map[string]bool, original string
 	}
 
 	name := args[0]
+	stageImage, isStage := b.stageImage(name)
+
+	b.inStage = true
+	if stageName != "" {
+		b.stageNames[stageName] = len(b.stages)
+	}
 
 	var (
 		image builder.Image
@@ -193,12 +237,17 @@ func from(b *Builder, args []string, attributes map[string]bool, original string
 	)
 
 	// Windows cannot support a container with no base image.
-	if name == api.NoBaseImageSpecifier {
+	if name == api.NoBaseImageSpecifier || (isStage && stageImage == "") {
 		if runtime.GOOS == "windows" {
 			return fmt.Errorf("Windows does not support FROM scratch")
 		}
 		b.image = ""
 		b.noBaseImage = true
+	} else if isStage {
+		image, err = b.docker.GetImageOnBuild(stageImage)
+		if err != nil {
+			return err
+		}
 	} else {
 		// TODO: don't use `name`, instead resolve it to a digest
 		if !b.options.PullParent {
@@ -213,7 +262,7 @@ func from(b *Builder, args []string, attributes map[string]bool, original string
 		}
 	}
 
-	return b.processImageFrom(image)
+	return b.processImageFrom(image, name)
 }
 
 // ONBUILD RUN echo yo
@@ -278,8 +327,9 @@ func workdir(b *Builder, args []string, attributes map[string]bool, original str
 // RUN some command yo
 //
 // run a command and commit the image. Args are automatically prepended with
-// 'sh -c' under linux or 'cmd /S /C' under Windows, in the event there is
-// only one argument. The difference in processing:
+// the shell set by SHELL, which defaults to 'sh -c' under linux or
+// 'cmd /S /C' under Windows, in the event there is only one argument. The
+// difference in processing:
 //
 // RUN echo hi          # sh -c echo hi       (Linux)
 // RUN echo hi          # cmd /S /C echo hi   (Windows)
@@ -290,18 +340,25 @@ func run(b *Builder, args []string, attributes map[string]bool, original string)
 		return fmt.Errorf("Please provide a source image with `from` prior to run")
 	}
 
+	flMount := b.flags.AddStrings("mount")
+
 	if err := b.flags.Parse(); err != nil {
 		return err
 	}
 
+	var secretMounts []*secretMount
+	for _, value := range flMount.StringValues {
+		m, err := parseSecretMount(value)
+		if err != nil {
+			return err
+		}
+		secretMounts = append(secretMounts, m)
+	}
+
 	args = handleJSONArgs(args, attributes)
 
 	if !attributes["json"] {
-		if runtime.GOOS != "windows" {
-			args = append([]string{"/bin/sh", "-c"}, args...)
-		} else {
-			args = append([]string{"cmd", "/S", "/C"}, args...)
-		}
+		args = append(getShell(b.runConfig), args...)
 	}
 
 	config := &container.Config{
@@ -336,15 +393,7 @@ func run(b *Builder, args []string, attributes map[string]bool, original string)
 	// lookup for same image built with same build time environment.
 	cmdBuildEnv := []string{}
 	configEnv := runconfigopts.ConvertKVStringsToMap(b.runConfig.Env)
-	for key, val := range b.options.BuildArgs {
-		if !b.isBuildArgAllowed(key) {
-			// skip build-args that are not in allowed list, meaning they have
-			// not been defined by an "ARG" Dockerfile command yet.
-			// This is an error condition but only if there is no "ARG" in the entire
-			// Dockerfile, so we'll generate any necessary errors after we parsed
-			// the entire file (see 'leftoverArgs' processing in evaluator.go )
-			continue
-		}
+	for key, val := range b.buildArgs() {
 		if _, ok := configEnv[key]; !ok {
 			cmdBuildEnv = append(cmdBuildEnv, fmt.Sprintf("%s=%s", key, val))
 		}
@@ -364,8 +413,24 @@ func run(b *Builder, args []string, attributes map[string]bool, original string)
 		saveCmd = strslice.StrSlice(append(tmpEnv, saveCmd...))
 	}
 
+	// the secret mounts are part of the command as well, in front of the
+	// build-time environment, so that the cache only matches a step with the
+	// same mounts. Only the IDs and targets are recorded, not the secrets.
+	if len(secretMounts) > 0 {
+		var mountArgs []string
+		for _, m := range secretMounts {
+			mountArgs = append(mountArgs, m.String())
+		}
+		saveCmd = strslice.StrSlice(append(mountArgs, saveCmd...))
+	}
+
+	binds, err := b.secretBinds(secretMounts)
+	if err != nil {
+		return err
+	}
+
 	b.runConfig.Cmd = saveCmd
-	hit, err := b.probeCache()
+	hit, err := b.probeCache(cmd)
 	if err != nil {
 		return err
 	}
@@ -382,7 +447,7 @@ func run(b *Builder, args []string, attributes map[string]bool, original string)
 
 	logrus.Debugf("[BUILDER] Command to be executed: %v", b.runConfig.Cmd)
 
-	cID, err := b.create()
+	cID, err := b.create(binds)
 	if err != nil {
 		return err
 	}
@@ -412,11 +477,7 @@ func cmd(b *Builder, args []string, attributes map[string]bool, original string)
 	cmdSlice := handleJSONArgs(args, attributes)
 
 	if !attributes["json"] {
-		if runtime.GOOS != "windows" {
-			cmdSlice = append([]string{"/bin/sh", "-c"}, cmdSlice...)
-		} else {
-			cmdSlice = append([]string{"cmd", "/S", "/C"}, cmdSlice...)
-		}
+		cmdSlice = append(getShell(b.runConfig), cmdSlice...)
 	}
 
 	b.runConfig.Cmd = strslice.StrSlice(cmdSlice)
@@ -456,11 +517,7 @@ func entrypoint(b *Builder, args []string, attributes map[string]bool, original
 		b.runConfig.Entrypoint = nil
 	default:
 		// ENTRYPOINT echo hi
-		if runtime.GOOS != "windows" {
-			b.runConfig.Entrypoint = strslice.StrSlice{"/bin/sh", "-c", parsed[0]}
-		} else {
-			b.runConfig.Entrypoint = strslice.StrSlice{"cmd", "/S", "/C", parsed[0]}
-		}
+		b.runConfig.Entrypoint = strslice.StrSlice(append(getShell(b.runConfig), parsed[0]))
 	}
 
 	// when setting the entrypoint if a CMD was not explicitly set then
@@ -582,6 +639,109 @@ func stopSignal(b *Builder, args []string, attributes map[string]bool, original
 	return b.commit("", b.runConfig.Cmd, fmt.Sprintf("STOPSIGNAL %v", args))
 }
 
+// HEALTHCHECK foo
+//
+// Set the default healthcheck command to run in the container (which may be empty).
+// Argument handling is the same as RUN.
+//
+func healthcheck(b *Builder, args []string, attributes map[string]bool, original string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("HEALTHCHECK requires an argument")
+	}
+	typ := strings.ToUpper(args[0])
+	args = args[1:]
+	if typ == "NONE" {
+		if len(args) != 0 {
+			return fmt.Errorf("HEALTHCHECK NONE takes no arguments")
+		}
+		b.runConfig.Healthcheck = &container.HealthConfig{
+			Test: []string{typ},
+		}
+	} else {
+		if b.runConfig.Healthcheck != nil {
+			oldCmd := b.runConfig.Healthcheck.Test
+			if len(oldCmd) > 0 && oldCmd[0] != "NONE" {
+				fmt.Fprintf(b.Stdout, "Note: overriding previous HEALTHCHECK: %v\n", oldCmd)
+			}
+		}
+
+		healthcheck := container.HealthConfig{}
+
+		flInterval := b.flags.AddString("interval", "")
+		flTimeout := b.flags.AddString("timeout", "")
+		flRetries := b.flags.AddString("retries", "")
+
+		if err := b.flags.Parse(); err != nil {
+			return err
+		}
+
+		switch typ {
+		case "CMD":
+			cmdSlice := handleJSONArgs(args, attributes)
+			if len(cmdSlice) == 0 {
+				return fmt.Errorf("Missing command after HEALTHCHECK CMD")
+			}
+
+			if !attributes["json"] {
+				typ = "CMD-SHELL"
+			}
+
+			healthcheck.Test = append([]string{typ}, cmdSlice...)
+		default:
+			return fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD)", typ)
+		}
+
+		interval, err := parseOptInterval(flInterval)
+		if err != nil {
+			return err
+		}
+		healthcheck.Interval = interval
+
+		timeout, err := parseOptInterval(flTimeout)
+		if err != nil {
+			return err
+		}
+		healthcheck.Timeout = timeout
+
+		if flRetries.Value != "" {
+			retries, err := strconv.ParseInt(flRetries.Value, 10, 32)
+			if err != nil {
+				return err
+			}
+			if retries < 1 {
+				return fmt.Errorf("--retries must be at least 1 (not %d)", retries)
+			}
+			healthcheck.Retries = int(retries)
+		}
+
+		b.runConfig.Healthcheck = &healthcheck
+	}
+
+	hc := b.runConfig.Healthcheck
+	commitStr := fmt.Sprintf("HEALTHCHECK %q", hc.Test)
+	if typ != "NONE" {
+		commitStr = fmt.Sprintf("HEALTHCHECK --interval=%v --timeout=%v --retries=%d %q", hc.Interval, hc.Timeout, hc.Retries, hc.Test)
+	}
+	return b.commit("", b.runConfig.Cmd, commitStr)
+}
+
+// parseOptInterval parses a duration flag of HEALTHCHECK, which must be
+// positive if set.
+func parseOptInterval(f *Flag) (time.Duration, error) {
+	s := f.Value
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("Interval %#v must be positive", f.name)
+	}
+	return d, nil
+}
+
 // ARG name[=value]
 //
 // Adds the variable foo to the trusted list of variables that can be passed
@@ -613,19 +773,60 @@ func arg(b *Builder, args []string, attributes map[string]bool, original string)
 		name = arg
 		hasDefault = false
 	}
-	// add the arg to allowed list of build-time args from this step on.
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("ARG names can not be blank or contain whitespace: %q", name)
+	}
+
+	// add the arg to allowed list of build-time args from this step on, until
+	// the end of the build stage.
 	b.allowedBuildArgs[name] = true
+	b.declaredArgs[name] = true
 
-	// If there is a default value associated with this arg then add it to the
-	// b.buildArgs if one is not already passed to the builder. The args passed
-	// to builder override the default value of 'arg'.
-	if _, ok := b.options.BuildArgs[name]; !ok && hasDefault {
-		b.options.BuildArgs[name] = value
+	// If there is a default value associated with this arg then remember it
+	// for the rest of the build stage. The args passed to builder override the
+	// default value of 'arg' (see buildArgs).
+	if hasDefault {
+		b.buildArgDefaults[name] = value
+	} else {
+		delete(b.buildArgDefaults, name)
 	}
 
 	return b.commit("", b.runConfig.Cmd, fmt.Sprintf("ARG %s", arg))
 }
 
+// SHELL ["powershell", "-command"]
+//
+// Set the shell the shell form of RUN, CMD and ENTRYPOINT runs the command
+// with, for the following instructions. It is recorded in the image.
+//
+func shell(b *Builder, args []string, attributes map[string]bool, original string) error {
+	if err := b.flags.Parse(); err != nil {
+		return err
+	}
+	shellSlice := handleJSONArgs(args, attributes)
+	switch {
+	case len(shellSlice) == 0:
+		// SHELL []
+		return errAtLeastOneArgument("SHELL")
+	case attributes["json"]:
+		// SHELL ["powershell", "-command"]
+		b.runConfig.Shell = strslice.StrSlice(shellSlice)
+	default:
+		// SHELL powershell -command - not JSON
+		return errNotJSON("SHELL", original)
+	}
+	return b.commit("", b.runConfig.Cmd, fmt.Sprintf("SHELL %v", shellSlice))
+}
+
+// getShell returns the shell the shell form of RUN, CMD and ENTRYPOINT runs
+// the command with, set by SHELL or the default one of the platform.
+func getShell(c *container.Config) []string {
+	if len(c.Shell) == 0 {
+		return append([]string{}, defaultShell...)
+	}
+	return append([]string{}, c.Shell...)
+}
+
 func errAtLeastOneArgument(command string) error {
 	return fmt.Errorf("%s requires at least one argument", command)
 }
@@ -637,3 +838,7 @@ func errExactlyOneArgument(command string) error {
 func errTooManyArguments(command string) error {
 	return fmt.Errorf("Bad input to %s, too many arguments", command)
 }
+
+func errNotJSON(command, original string) error {
+	return fmt.Errorf("%s requires the arguments to be in JSON form: %s", command, original)
+}
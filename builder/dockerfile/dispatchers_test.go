@@ -0,0 +1,178 @@
+package dockerfile
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/builder"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/container"
+)
+
+// stageBackend is a builder.Backend serving the images of build stages.
+type stageBackend struct {
+	builder.Backend
+	mounted []string
+}
+
+func (s *stageBackend) MountImage(name string) (string, func() error, error) {
+	s.mounted = append(s.mounted, name)
+	return "/var/lib/docker/mnt/" + name, func() error { return nil }, nil
+}
+
+func newStageBuilder() *Builder {
+	return &Builder{
+		flags:      NewBFlags(),
+		runConfig:  new(container.Config),
+		docker:     &stageBackend{},
+		stageNames: make(map[string]int),
+	}
+}
+
+func TestFromStageNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows does not support FROM scratch")
+	}
+
+	invalid := [][]string{
+		{},
+		{"scratch", "AS"},
+		{"scratch", "FOR", "build"},
+		{"scratch", "AS", "1build"},
+		{"scratch", "AS", "build/stage"},
+		{"scratch", "AS", "build", "extra"},
+	}
+	for _, args := range invalid {
+		if err := from(newStageBuilder(), args, nil, ""); err == nil {
+			t.Fatalf("expected FROM %q to fail", args)
+		}
+	}
+
+	b := newStageBuilder()
+	if err := from(b, []string{"scratch", "as", "Build"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if i, ok := b.stageNames["build"]; !ok || i != 0 {
+		t.Fatalf("expected stage build to have index 0, got %v", b.stageNames)
+	}
+	if _, ok := b.stageImage("build"); ok {
+		t.Fatal("the current build stage must not be usable as a completed stage")
+	}
+
+	b.image = "sha256:1234"
+	b.cmdSet = true
+	b.nextStage()
+	if b.image != "" || b.cmdSet || len(b.stages) != 1 || b.stages[0] != "sha256:1234" {
+		t.Fatalf("unexpected builder state after starting a new stage: %+v", b)
+	}
+	if err := from(b, []string{"scratch", "AS", "BUILD"}, nil, ""); err == nil {
+		t.Fatal("expected duplicate stage names to be rejected")
+	}
+	if id, ok := b.stageImage("BUILD"); !ok || id != "sha256:1234" {
+		t.Fatalf("expected stage BUILD to resolve to sha256:1234, got %q", id)
+	}
+}
+
+func TestStageContext(t *testing.T) {
+	b := newStageBuilder()
+	b.stages = []string{"sha256:1234", ""}
+	b.stageNames["build"] = 0
+	b.stageNames["empty"] = 1
+	b.stageNames["current"] = 2
+
+	for _, name := range []string{"build", "BUILD", "0"} {
+		ctx, err := b.stageContext(name)
+		if err != nil {
+			t.Fatalf("stage %s: %v", name, err)
+		}
+		if err := ctx.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mounted := b.docker.(*stageBackend).mounted; len(mounted) != 3 || mounted[2] != "sha256:1234" {
+		t.Fatalf("unexpected mounted images %v", mounted)
+	}
+
+	for _, name := range []string{"current", "2", "-1", "unknown", "empty", "1"} {
+		if _, err := b.stageContext(name); err == nil {
+			t.Fatalf("expected copying from stage %s to fail", name)
+		}
+	}
+}
+
+func TestArgScope(t *testing.T) {
+	b := newStageBuilder()
+	b.disableCommit = true
+	b.options = &types.ImageBuildOptions{BuildArgs: map[string]string{"FOO": "cli", "http_proxy": "proxy"}}
+	b.allowedBuildArgs = make(map[string]bool)
+	b.buildArgDefaults = make(map[string]string)
+	b.declaredArgs = make(map[string]bool)
+
+	for _, args := range [][]string{{"=value"}, {"a b=value"}, {"FOO", "BAR"}} {
+		if err := arg(b, args, nil, ""); err == nil {
+			t.Fatalf("expected ARG %q to fail", args)
+		}
+	}
+
+	if err := arg(b, []string{"FOO=default"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := arg(b, []string{"BAR=default"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := arg(b, []string{"BAZ"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"FOO": "cli", "BAR": "default", "http_proxy": "proxy"}
+	if args := b.buildArgs(); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected build args %v, got %v", expected, args)
+	}
+
+	// build args are scoped to the build stage they are defined in
+	b.nextStage()
+	expected = map[string]string{"http_proxy": "proxy"}
+	if args := b.buildArgs(); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected build args %v in the new stage, got %v", expected, args)
+	}
+	if err := arg(b, []string{"BAR"}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if args := b.buildArgs(); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("expected the default of BAR not to leak into the new stage, got %v", args)
+	}
+	if !b.isBuildArgDeclared("FOO") || b.isBuildArgAllowed("FOO") {
+		t.Fatal("expected FOO to be declared in an earlier stage only")
+	}
+}
+
+func TestShell(t *testing.T) {
+	b := newStageBuilder()
+	b.disableCommit = true
+
+	if err := shell(b, []string{"powershell -command"}, nil, "SHELL powershell -command"); err == nil {
+		t.Fatal("expected SHELL not in JSON form to fail")
+	}
+	if err := shell(b, []string{}, map[string]bool{"json": true}, "SHELL []"); err == nil {
+		t.Fatal("expected SHELL without arguments to fail")
+	}
+
+	if shellCmd := getShell(b.runConfig); !reflect.DeepEqual(shellCmd, defaultShell) {
+		t.Fatalf("expected the default shell %v, got %v", defaultShell, shellCmd)
+	}
+	expected := []string{"/bin/bash", "-c"}
+	if err := shell(b, expected, map[string]bool{"json": true}, `SHELL ["/bin/bash", "-c"]`); err != nil {
+		t.Fatal(err)
+	}
+	if shellCmd := getShell(b.runConfig); !reflect.DeepEqual(shellCmd, expected) {
+		t.Fatalf("expected the shell %v, got %v", expected, shellCmd)
+	}
+
+	if err := entrypoint(b, []string{"server --port 80"}, nil, "ENTRYPOINT server --port 80"); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, "server --port 80")
+	if !reflect.DeepEqual([]string(b.runConfig.Entrypoint), expected) {
+		t.Fatalf("expected the entrypoint %v, got %v", expected, b.runConfig.Entrypoint)
+	}
+}
@@ -2,6 +2,10 @@
 
 package dockerfile
 
+// defaultShell is the shell the shell form of RUN, CMD and ENTRYPOINT runs
+// the command with, unless a SHELL instruction sets another one.
+var defaultShell = []string{"/bin/sh", "-c"}
+
 // platformSupports is a short-term function to give users a quality error
 // message if a Dockerfile uses a command not supported on the platform.
 func platformSupports(command string) error {
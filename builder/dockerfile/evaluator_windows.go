@@ -2,6 +2,10 @@ package dockerfile
 
 import "fmt"
 
+// defaultShell is the shell the shell form of RUN, CMD and ENTRYPOINT runs
+// the command with, unless a SHELL instruction sets another one.
+var defaultShell = []string{"cmd", "/S", "/C"}
+
 // platformSupports is gives users a quality error message if a Dockerfile uses
 // a command not supported on the platform.
 func platformSupports(command string) error {
@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/docker/docker/pkg/httputils"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/metrics"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/stringid"
@@ -40,6 +42,8 @@ import (
 	"github.com/docker/engine-api/types/strslice"
 )
 
+var cacheProbes = metrics.NewCounter("docker_builder_cache_probes_total", "The number of build cache lookups, by result.", "result")
+
 func (b *Builder) addLabels() {
 	// merge labels
 	if len(b.options.Labels) > 0 {
@@ -71,13 +75,13 @@ func (b *Builder) commit(id string, autoCmd strslice.StrSlice, comment string) e
 		}
 		defer func(cmd strslice.StrSlice) { b.runConfig.Cmd = cmd }(cmd)
 
-		hit, err := b.probeCache()
+		hit, err := b.probeCache(autoCmd)
 		if err != nil {
 			return err
 		} else if hit {
 			return nil
 		}
-		id, err = b.create()
+		id, err = b.create(nil)
 		if err != nil {
 			return err
 		}
@@ -110,8 +114,8 @@ type copyInfo struct {
 	decompress bool
 }
 
-func (b *Builder) runContextCommand(args []string, allowRemote bool, allowLocalDecompression bool, cmdName string) error {
-	if b.context == nil {
+func (b *Builder) runContextCommand(args []string, allowRemote bool, allowLocalDecompression bool, cmdName string, buildContext builder.Context) error {
+	if buildContext == nil {
 		return fmt.Errorf("No context given. Impossible to use %s", cmdName)
 	}
 
@@ -147,7 +151,7 @@ func (b *Builder) runContextCommand(args []string, allowRemote bool, allowLocalD
 			continue
 		}
 		// not a URL
-		subInfos, err := b.calcCopyInfo(cmdName, orig, allowLocalDecompression, true)
+		subInfos, err := b.calcCopyInfo(buildContext, cmdName, orig, allowLocalDecompression, true)
 		if err != nil {
 			return err
 		}
@@ -197,7 +201,7 @@ func (b *Builder) runContextCommand(args []string, allowRemote bool, allowLocalD
 	}
 	defer func(cmd strslice.StrSlice) { b.runConfig.Cmd = cmd }(cmd)
 
-	if hit, err := b.probeCache(); err != nil {
+	if hit, err := b.probeCache(cmd); err != nil {
 		return err
 	} else if hit {
 		return nil
@@ -321,7 +325,7 @@ func (b *Builder) download(srcURL string) (fi builder.FileInfo, err error) {
 	return &builder.HashedFileInfo{FileInfo: builder.PathFileInfo{FileInfo: tmpFileSt, FilePath: tmpFileName}, FileHash: hash}, nil
 }
 
-func (b *Builder) calcCopyInfo(cmdName, origPath string, allowLocalDecompression, allowWildcards bool) ([]copyInfo, error) {
+func (b *Builder) calcCopyInfo(buildContext builder.Context, cmdName, origPath string, allowLocalDecompression, allowWildcards bool) ([]copyInfo, error) {
 
 	// Work in daemon-specific OS filepath semantics
 	origPath = filepath.FromSlash(origPath)
@@ -334,7 +338,7 @@ func (b *Builder) calcCopyInfo(cmdName, origPath string, allowLocalDecompression
 	// Deal with wildcards
 	if allowWildcards && containsWildcards(origPath) {
 		var copyInfos []copyInfo
-		if err := b.context.Walk("", func(path string, info builder.FileInfo, err error) error {
+		if err := buildContext.Walk("", func(path string, info builder.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -348,7 +352,7 @@ func (b *Builder) calcCopyInfo(cmdName, origPath string, allowLocalDecompression
 
 			// Note we set allowWildcards to false in case the name has
 			// a * in it
-			subInfos, err := b.calcCopyInfo(cmdName, path, allowLocalDecompression, false)
+			subInfos, err := b.calcCopyInfo(buildContext, cmdName, path, allowLocalDecompression, false)
 			if err != nil {
 				return err
 			}
@@ -362,7 +366,7 @@ func (b *Builder) calcCopyInfo(cmdName, origPath string, allowLocalDecompression
 
 	// Must be a dir or a file
 
-	statPath, fi, err := b.context.Stat(origPath)
+	statPath, fi, err := buildContext.Stat(origPath)
 	if err != nil {
 		return nil, err
 	}
@@ -381,7 +385,7 @@ func (b *Builder) calcCopyInfo(cmdName, origPath string, allowLocalDecompression
 	}
 	// Must be a dir
 	var subfiles []string
-	err = b.context.Walk(statPath, func(path string, info builder.FileInfo, err error) error {
+	err = buildContext.Walk(statPath, func(path string, info builder.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -413,9 +417,62 @@ func containsWildcards(name string) bool {
 	return false
 }
 
-func (b *Builder) processImageFrom(img builder.Image) error {
+// nextStage records the image of the current build stage, and resets the
+// state carried from one instruction to the next so that the following
+// FROM starts a new stage from a clean slate.
+func (b *Builder) nextStage() {
+	b.stages = append(b.stages, b.image)
+	b.image = ""
+	b.fromImage = ""
+	b.noBaseImage = false
+	b.runConfig = new(container.Config)
+	b.maintainer = ""
+	b.cmdSet = false
+	b.cacheBusted = false
+	b.allowedBuildArgs = make(map[string]bool)
+	b.buildArgDefaults = make(map[string]string)
+}
+
+// stageImage returns the image ID of the completed build stage named name
+// with FROM ... AS, and whether there is such a stage.
+func (b *Builder) stageImage(name string) (string, bool) {
+	i, ok := b.stageNames[strings.ToLower(name)]
+	if !ok || i >= len(b.stages) {
+		return "", false
+	}
+	return b.stages[i], true
+}
+
+// stageContext returns a build Context over the filesystem of the completed
+// build stage referred to by name, which is either the name of the stage or
+// its index, starting from 0.
+func (b *Builder) stageContext(name string) (builder.Context, error) {
+	imageID, ok := b.stageImage(name)
+	if !ok {
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(b.stages) {
+			imageID, ok = b.stages[i], true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid from flag value %s: no such build stage, only earlier stages can be copied from", name)
+	}
+	if imageID == "" {
+		return nil, fmt.Errorf("invalid from flag value %s: build stage did not produce an image", name)
+	}
+
+	root, release, err := b.docker.MountImage(imageID)
+	if err != nil {
+		return nil, err
+	}
+	return builder.NewImageContext(imageID, root, release), nil
+}
+
+// processImageFrom starts the build from img, the image named name in the
+// FROM instruction, and runs its ONBUILD triggers.
+func (b *Builder) processImageFrom(img builder.Image, name string) error {
 	if img != nil {
 		b.image = img.ImageID()
+		b.fromImage = b.image
 
 		if img.RunConfig() != nil {
 			imgConfig := *img.RunConfig()
@@ -453,21 +510,28 @@ func (b *Builder) processImageFrom(img builder.Image) error {
 		return nil
 	}
 
-	// Process ONBUILD triggers if they exist
-	if nTriggers := len(b.runConfig.OnBuild); nTriggers != 0 {
-		word := "trigger"
-		if nTriggers > 1 {
-			word = "triggers"
-		}
-		fmt.Fprintf(b.Stderr, "# Executing %d build %s...\n", nTriggers, word)
-	}
-
 	// Copy the ONBUILD triggers, and remove them from the config, since the config will be committed.
 	onBuildTriggers := b.runConfig.OnBuild
 	b.runConfig.OnBuild = []string{}
 
+	// Process ONBUILD triggers if they exist
+	nTriggers := len(onBuildTriggers)
+	if nTriggers == 0 {
+		return nil
+	}
+	word := "trigger"
+	if nTriggers > 1 {
+		word = "triggers"
+	}
+	if b.options.SkipOnBuild {
+		fmt.Fprintf(b.Stderr, "# Skipping %d build %s of %s\n", nTriggers, word, name)
+		return nil
+	}
+	fmt.Fprintf(b.Stderr, "# Executing %d build %s...\n", nTriggers, word)
+
 	// parse the ONBUILD triggers by invoking the parser
-	for _, step := range onBuildTriggers {
+	for t, step := range onBuildTriggers {
+		fmt.Fprintf(b.Stderr, "# Build trigger %d/%d of %s: %s\n", t+1, nTriggers, name, step)
 		ast, err := parser.Parse(strings.NewReader(step))
 		if err != nil {
 			return err
@@ -490,35 +554,41 @@ func (b *Builder) processImageFrom(img builder.Image) error {
 	return nil
 }
 
-// probeCache checks if `b.docker` implements builder.ImageCache and image-caching
+// probeCache checks if `b.docker` provides an image cache and image-caching
 // is enabled (`b.UseCache`).
-// If so attempts to look up the current `b.image` and `b.runConfig` pair with `b.docker`.
+// If so attempts to look up the current `b.image` and `b.runConfig` pair in `b.imageCache`.
+// `autoCmd` is the command the image would be committed with.
 // If an image is found, probeCache returns `(true, nil)`.
 // If no image is found, it returns `(false, nil)`.
 // If there is any error, it returns `(false, err)`.
-func (b *Builder) probeCache() (bool, error) {
-	c, ok := b.docker.(builder.ImageCache)
-	if !ok || b.options.NoCache || b.cacheBusted {
+func (b *Builder) probeCache(autoCmd strslice.StrSlice) (bool, error) {
+	if b.imageCache == nil || b.options.NoCache || b.cacheBusted {
 		return false, nil
 	}
-	cache, err := c.GetCachedImageOnBuild(b.image, b.runConfig)
+	imageCfg := *b.runConfig
+	imageCfg.Image = b.image
+	imageCfg.Cmd = autoCmd
+	cache, err := b.imageCache.GetCachedImageOnBuild(b.image, b.runConfig, &imageCfg)
 	if err != nil {
 		return false, err
 	}
 	if len(cache) == 0 {
 		logrus.Debugf("[BUILDER] Cache miss: %s", b.runConfig.Cmd)
+		cacheProbes.Inc("miss")
 		b.cacheBusted = true
 		return false, nil
 	}
+	cacheProbes.Inc("hit")
+	b.cacheHit = true
 
-	fmt.Fprintf(b.Stdout, " ---> Using cache\n")
+	b.status(" ---> Using cache\n")
 	logrus.Debugf("[BUILDER] Use cached version: %s", b.runConfig.Cmd)
 	b.image = string(cache)
 
 	return true, nil
 }
 
-func (b *Builder) create() (string, error) {
+func (b *Builder) create(binds []string) (string, error) {
 	if b.image == "" && !b.noBaseImage {
 		return "", fmt.Errorf("Please provide a source image with `from` prior to run")
 	}
@@ -538,9 +608,12 @@ func (b *Builder) create() (string, error) {
 
 	// TODO: why not embed a hostconfig in builder?
 	hostConfig := &container.HostConfig{
-		Isolation: b.options.Isolation,
-		ShmSize:   b.options.ShmSize,
-		Resources: resources,
+		Binds:       binds,
+		Isolation:   b.options.Isolation,
+		ShmSize:     b.options.ShmSize,
+		Resources:   resources,
+		NetworkMode: container.NetworkMode(b.options.NetworkMode),
+		ExtraHosts:  b.options.ExtraHosts,
 	}
 
 	config := *b.runConfig
@@ -558,7 +631,7 @@ func (b *Builder) create() (string, error) {
 	}
 
 	b.tmpContainers[c.ID] = struct{}{}
-	fmt.Fprintf(b.Stdout, " ---> Running in %s\n", stringid.TruncateID(c.ID))
+	b.status(" ---> Running in %s\n", stringid.TruncateID(c.ID))
 
 	// override the entry point that may have been picked up from the base image
 	if err := b.docker.ContainerUpdateCmdOnBuild(c.ID, config.Cmd); err != nil {
@@ -631,7 +704,7 @@ func (b *Builder) clearTmp() {
 			return
 		}
 		delete(b.tmpContainers, c)
-		fmt.Fprintf(b.Stdout, "Removing intermediate container %s\n", stringid.TruncateID(c))
+		b.status("Removing intermediate container %s\n", stringid.TruncateID(c))
 	}
 }
 
@@ -688,7 +761,7 @@ func (b *Builder) readDockerfile() error {
 }
 
 // determine if build arg is part of built-in args or user
-// defined args in Dockerfile at any point in time.
+// defined args in the current build stage of the Dockerfile.
 func (b *Builder) isBuildArgAllowed(arg string) bool {
 	if _, ok := BuiltinAllowedBuildArgs[arg]; ok {
 		return true
@@ -698,3 +771,74 @@ func (b *Builder) isBuildArgAllowed(arg string) bool {
 	}
 	return false
 }
+
+// determine if build arg is part of built-in args or user
+// defined args in any build stage of the Dockerfile.
+func (b *Builder) isBuildArgDeclared(arg string) bool {
+	if _, ok := BuiltinAllowedBuildArgs[arg]; ok {
+		return true
+	}
+	if _, ok := b.declaredArgs[arg]; ok {
+		return true
+	}
+	return false
+}
+
+// buildArgs returns the build-time args that are available to the current
+// instruction, meaning the ones allowed so far in the build stage, and their
+// values. A value passed to the builder overrides the default value of 'arg',
+// and an arg with neither is not set at all.
+// Build-args that are passed but not allowed yet are skipped. This is an
+// error condition but only if there is no "ARG" for them in the entire
+// Dockerfile, so we'll generate any necessary errors after we parsed the
+// entire file (see 'leftoverArgs' processing in builder.go).
+func (b *Builder) buildArgs() map[string]string {
+	args := make(map[string]string)
+	for key, val := range b.options.BuildArgs {
+		if b.isBuildArgAllowed(key) {
+			args[key] = val
+		}
+	}
+	for key, val := range b.buildArgDefaults {
+		if _, ok := args[key]; !ok {
+			args[key] = val
+		}
+	}
+	return args
+}
+
+// status writes a status line of the build, such as the step being run or
+// the image it resulted in. These lines are replaced by the BuildStep
+// records when the client asked for structured output.
+func (b *Builder) status(format string, a ...interface{}) {
+	if !b.options.StructuredOutput {
+		fmt.Fprintf(b.Stdout, format, a...)
+	}
+}
+
+// reportStep sends the BuildStep record of the step stepN, which started
+// from the image parentImage, when the client asked for structured output.
+func (b *Builder) reportStep(stepN int, ast *parser.Node, parentImage string, duration time.Duration) {
+	stdoutFormatter, ok := b.Stdout.(*streamformatter.StdoutFormatter)
+	if !b.options.StructuredOutput || !ok {
+		return
+	}
+	step := types.BuildStep{
+		Step:        stepN + 1,
+		Instruction: ast.Original,
+		Cached:      b.cacheHit,
+		ImageID:     b.image,
+		Duration:    duration,
+	}
+	// a FROM starts from another image, it does not add any layer of its own
+	if parentImage != "" && b.image != parentImage {
+		img, err := b.docker.GetImageOnBuild(b.image)
+		if err != nil {
+			logrus.Warnf("Could not look up the layer of build step %d: %v", step.Step, err)
+		} else {
+			step.Layer = string(img.LastLayer())
+		}
+	}
+	progressOutput := stdoutFormatter.StreamFormatter.NewProgressOutput(stdoutFormatter.Writer, false)
+	progress.Aux(progressOutput, step)
+}
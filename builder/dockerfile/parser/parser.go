@@ -49,25 +49,38 @@ func init() {
 	// functions. Errors are propagated up by Parse() and the resulting AST can
 	// be incorporated directly into the existing AST as a next.
 	dispatch = map[string]func(string) (*Node, map[string]bool, error){
-		command.User:       parseString,
-		command.Onbuild:    parseSubCommand,
-		command.Workdir:    parseString,
-		command.Env:        parseEnv,
-		command.Label:      parseLabel,
-		command.Maintainer: parseString,
-		command.From:       parseString,
-		command.Add:        parseMaybeJSONToList,
-		command.Copy:       parseMaybeJSONToList,
-		command.Run:        parseMaybeJSON,
-		command.Cmd:        parseMaybeJSON,
-		command.Entrypoint: parseMaybeJSON,
-		command.Expose:     parseStringsWhitespaceDelimited,
-		command.Volume:     parseMaybeJSONToList,
-		command.StopSignal: parseString,
-		command.Arg:        parseNameOrNameVal,
+		command.User:        parseString,
+		command.Onbuild:     parseSubCommand,
+		command.Workdir:     parseString,
+		command.Env:         parseEnv,
+		command.Label:       parseLabel,
+		command.Maintainer:  parseString,
+		command.From:        parseStringsWhitespaceDelimited,
+		command.Add:         parseMaybeJSONToList,
+		command.Copy:        parseMaybeJSONToList,
+		command.Run:         parseMaybeJSON,
+		command.Cmd:         parseMaybeJSON,
+		command.Entrypoint:  parseMaybeJSON,
+		command.Expose:      parseStringsWhitespaceDelimited,
+		command.Volume:      parseMaybeJSONToList,
+		command.StopSignal:  parseString,
+		command.Arg:         parseNameOrNameVal,
+		command.Healthcheck: parseHealthConfig,
+		command.Shell:       parseMaybeJSON,
 	}
 }
 
+// LineError is the error of an instruction of a Dockerfile that could not be
+// parsed, along with the line the instruction starts at.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return e.Err.Error()
+}
+
 // parse a line and return the remainder.
 func parseLine(line string) (string, *Node, error) {
 	if line = stripComments(line); line == "" {
@@ -111,11 +124,11 @@ func Parse(rwc io.Reader) (*Node, error) {
 	for scanner.Scan() {
 		scannedLine := strings.TrimLeftFunc(scanner.Text(), unicode.IsSpace)
 		currentLine++
+		startLine := currentLine
 		line, child, err := parseLine(scannedLine)
 		if err != nil {
-			return nil, err
+			return nil, &LineError{Line: startLine, Err: err}
 		}
-		startLine := currentLine
 
 		if line != "" && child == nil {
 			for scanner.Scan() {
@@ -128,7 +141,7 @@ func Parse(rwc io.Reader) (*Node, error) {
 
 				line, child, err = parseLine(line + newline)
 				if err != nil {
-					return nil, err
+					return nil, &LineError{Line: startLine, Err: err}
 				}
 
 				if child != nil {
@@ -138,7 +151,7 @@ func Parse(rwc io.Reader) (*Node, error) {
 			if child == nil && line != "" {
 				_, child, err = parseLine(line)
 				if err != nil {
-					return nil, err
+					return nil, &LineError{Line: startLine, Err: err}
 				}
 			}
 		}
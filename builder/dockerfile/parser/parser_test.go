@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -152,3 +153,14 @@ func TestLineInformation(t *testing.T) {
 		}
 	}
 }
+
+func TestLineErrorInformation(t *testing.T) {
+	_, err := Parse(strings.NewReader("FROM busybox\n\n# comment\nENV PATH\nRUN true\n"))
+	lineErr, ok := err.(*LineError)
+	if !ok {
+		t.Fatalf("expected a LineError, got %v", err)
+	}
+	if lineErr.Line != 4 {
+		t.Fatalf("expected the error to be on line 4, got %d", lineErr.Line)
+	}
+}
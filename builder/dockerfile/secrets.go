@@ -0,0 +1,104 @@
+package dockerfile
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultSecretsDir is where secrets are mounted when RUN does not give a
+// target for them.
+const defaultSecretsDir = "/run/secrets"
+
+var validSecretID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// secretMount is a secret of the build exposed to a single RUN instruction
+// with --mount=type=secret.
+type secretMount struct {
+	id       string
+	target   string
+	required bool
+}
+
+// parseSecretMount parses the value of a --mount flag of RUN, in the form
+// type=secret,id=<id>[,target=<path>][,required[=<bool>]].
+func parseSecretMount(value string) (*secretMount, error) {
+	var (
+		m         = &secretMount{}
+		mountType string
+		err       error
+	)
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		key := strings.ToLower(parts[0])
+		if len(parts) == 1 {
+			if key != "required" {
+				return nil, fmt.Errorf("invalid field %q in mount %q", field, value)
+			}
+			m.required = true
+			continue
+		}
+		switch key {
+		case "type":
+			mountType = parts[1]
+		case "id":
+			m.id = parts[1]
+		case "target", "dst", "destination":
+			m.target = parts[1]
+		case "required":
+			if m.required, err = strconv.ParseBool(parts[1]); err != nil {
+				return nil, fmt.Errorf("invalid value for required in mount %q", value)
+			}
+		default:
+			return nil, fmt.Errorf("invalid field %q in mount %q", field, value)
+		}
+	}
+
+	if mountType != "secret" {
+		return nil, fmt.Errorf("unsupported mount type %q, only secret mounts are supported", mountType)
+	}
+	if !validSecretID.MatchString(m.id) {
+		return nil, fmt.Errorf("invalid secret ID %q in mount %q", m.id, value)
+	}
+	if m.target == "" {
+		m.target = path.Join(defaultSecretsDir, m.id)
+	}
+	if !path.IsAbs(m.target) {
+		return nil, fmt.Errorf("the target of secret %s must be an absolute path", m.id)
+	}
+	m.target = path.Clean(m.target)
+	return m, nil
+}
+
+// String returns the mount as it is recorded in the command of the build
+// step, which never includes the secret itself.
+func (m *secretMount) String() string {
+	return fmt.Sprintf("--mount=type=secret,id=%s,target=%s", m.id, m.target)
+}
+
+// secretBinds returns the binds exposing the secrets in mounts to a build
+// container. The secrets of the build are only written, on tmpfs, when the
+// first of them is used, and are removed at the end of the build.
+func (b *Builder) secretBinds(mounts []*secretMount) ([]string, error) {
+	var binds []string
+	for _, m := range mounts {
+		if _, ok := b.options.Secrets[m.id]; !ok {
+			if m.required {
+				return nil, fmt.Errorf("secret %s is required but was not provided", m.id)
+			}
+			continue
+		}
+		if b.secretsDir == "" {
+			dir, release, err := b.docker.MountBuildSecrets(b.options.Secrets)
+			if err != nil {
+				return nil, err
+			}
+			b.secretsDir, b.releaseSecrets = dir, release
+		}
+		binds = append(binds, fmt.Sprintf("%s:%s:ro", filepath.Join(b.secretsDir, m.id), m.target))
+	}
+	return binds, nil
+}
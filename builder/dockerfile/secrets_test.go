@@ -0,0 +1,86 @@
+package dockerfile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/builder"
+	"github.com/docker/engine-api/types"
+)
+
+// secretsBackend is a builder.Backend recording the mounts of build secrets.
+type secretsBackend struct {
+	builder.Backend
+	mounts int
+}
+
+func (s *secretsBackend) MountBuildSecrets(secrets map[string][]byte) (string, func() error, error) {
+	s.mounts++
+	return "/var/lib/docker/build-secrets/1234", func() error { return nil }, nil
+}
+
+func TestParseSecretMount(t *testing.T) {
+	valid := map[string]secretMount{
+		"type=secret,id=npmrc":                         {id: "npmrc", target: "/run/secrets/npmrc"},
+		"type=secret,id=npmrc,target=/root/.npmrc":     {id: "npmrc", target: "/root/.npmrc"},
+		"type=secret,id=key.pem,dst=/etc/ssl//key.pem": {id: "key.pem", target: "/etc/ssl/key.pem"},
+		"type=secret,id=npmrc,required":                {id: "npmrc", target: "/run/secrets/npmrc", required: true},
+		"id=npmrc,type=secret,required=false":          {id: "npmrc", target: "/run/secrets/npmrc"},
+	}
+	for value, expected := range valid {
+		m, err := parseSecretMount(value)
+		if err != nil {
+			t.Fatalf("mount %q: %v", value, err)
+		}
+		if *m != expected {
+			t.Fatalf("mount %q: expected %+v, got %+v", value, expected, *m)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"id=npmrc",
+		"type=bind,id=npmrc",
+		"type=secret",
+		"type=secret,id=../npmrc",
+		"type=secret,id=npmrc,target=relative",
+		"type=secret,id=npmrc,required=maybe",
+		"type=secret,id=npmrc,mode=0400",
+	}
+	for _, value := range invalid {
+		if _, err := parseSecretMount(value); err == nil {
+			t.Fatalf("expected mount %q to fail", value)
+		}
+	}
+}
+
+func TestSecretBinds(t *testing.T) {
+	backend := &secretsBackend{}
+	b := &Builder{
+		docker:  backend,
+		options: &types.ImageBuildOptions{Secrets: map[string][]byte{"npmrc": []byte("token")}},
+	}
+
+	binds, err := b.secretBinds([]*secretMount{
+		{id: "npmrc", target: "/root/.npmrc"},
+		{id: "missing", target: "/run/secrets/missing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join("/var/lib/docker/build-secrets/1234", "npmrc") + ":/root/.npmrc:ro"
+	if len(binds) != 1 || binds[0] != expected {
+		t.Fatalf("unexpected binds %v", binds)
+	}
+
+	if _, err := b.secretBinds([]*secretMount{{id: "npmrc", target: "/run/secrets/npmrc"}}); err != nil {
+		t.Fatal(err)
+	}
+	if backend.mounts != 1 {
+		t.Fatalf("expected the secrets to be mounted once per build, got %d mounts", backend.mounts)
+	}
+
+	if _, err := b.secretBinds([]*secretMount{{id: "missing", required: true}}); err == nil {
+		t.Fatal("expected a missing required secret to fail")
+	}
+}
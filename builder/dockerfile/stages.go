@@ -0,0 +1,259 @@
+package dockerfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/builder/dockerfile/command"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/docker/engine-api/types/container"
+)
+
+// buildStage is a stage of a multi-stage build, the instructions from one
+// FROM up to the next.
+type buildStage struct {
+	start int // index of the first instruction of the stage in the Dockerfile
+	nodes []*parser.Node
+	name  string // lower-cased name given with FROM ... AS
+	deps  []int  // earlier stages the stage is based on or copies from
+}
+
+// splitStages splits the instructions of a Dockerfile into build stages, and
+// finds the stages each one depends on from its FROM and COPY --from
+// instructions. References that do not resolve to an earlier stage are left
+// to the instructions to report.
+func splitStages(nodes []*parser.Node) []*buildStage {
+	var (
+		stages   []*buildStage
+		names    = make(map[string]int)
+		seenFrom bool
+	)
+	for i, n := range nodes {
+		if len(stages) == 0 || n.Value == command.From && seenFrom {
+			stages = append(stages, &buildStage{start: i})
+		}
+		index := len(stages) - 1
+		s := stages[index]
+		s.nodes = append(s.nodes, n)
+
+		switch n.Value {
+		case command.From:
+			seenFrom = true
+			var args []string
+			for arg := n.Next; arg != nil; arg = arg.Next {
+				args = append(args, arg.Value)
+			}
+			if len(args) > 0 {
+				if dep, ok := names[strings.ToLower(args[0])]; ok {
+					s.addDep(dep)
+				}
+			}
+			if len(args) == 3 && strings.EqualFold(args[1], "as") {
+				s.name = strings.ToLower(args[2])
+				if _, exists := names[s.name]; !exists {
+					names[s.name] = index
+				}
+			}
+		case command.Copy:
+			for _, flag := range n.Flags {
+				if !strings.HasPrefix(flag, "--from=") {
+					continue
+				}
+				ref := strings.TrimPrefix(flag, "--from=")
+				if dep, ok := names[strings.ToLower(ref)]; ok {
+					s.addDep(dep)
+				} else if dep, err := strconv.Atoi(ref); err == nil && dep >= 0 && dep < index {
+					s.addDep(dep)
+				}
+			}
+		}
+	}
+	return stages
+}
+
+func (s *buildStage) addDep(dep int) {
+	for _, d := range s.deps {
+		if d == dep {
+			return
+		}
+	}
+	s.deps = append(s.deps, dep)
+}
+
+var errStageDependency = errors.New("a build stage this stage depends on failed")
+
+// buildStages builds the stages of a multi-stage build, running the stages
+// that do not depend on one another concurrently, at most
+// b.options.Parallelism at a time. The output of a stage is held back until
+// the earlier stages are done, so that it reads as a sequential build.
+func (b *Builder) buildStages(stages []*buildStage) error {
+	// the stages would race to mount the secrets on first use
+	if len(b.options.Secrets) > 0 && b.secretsDir == "" {
+		dir, release, err := b.docker.MountBuildSecrets(b.options.Secrets)
+		if err != nil {
+			return err
+		}
+		b.secretsDir, b.releaseSecrets = dir, release
+	}
+
+	out := b.Output
+	if out == nil {
+		out = b.Stdout
+	}
+
+	var (
+		limit    = make(chan struct{}, b.options.Parallelism)
+		done     = make([]chan struct{}, len(stages))
+		builders = make([]*Builder, len(stages))
+		errs     = make([]error, len(stages))
+		outputs  = make([]*stageOutput, len(stages))
+		names    = make(map[string]int)
+
+		failOnce sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			b.cancel()
+		})
+	}
+
+	for i, s := range stages {
+		done[i] = make(chan struct{})
+		outputs[i] = &stageOutput{out: out}
+
+		// a stage only knows about the names of the stages before it
+		stageNames := make(map[string]int, len(names))
+		for name, index := range names {
+			stageNames[name] = index
+		}
+		if _, exists := names[s.name]; s.name != "" && !exists {
+			names[s.name] = i
+		}
+
+		go func(i int, s *buildStage, stageNames map[string]int) {
+			defer close(done[i])
+
+			images := make([]string, i)
+			for _, dep := range s.deps {
+				<-done[dep]
+				if errs[dep] != nil {
+					errs[i] = errStageDependency
+					return
+				}
+				images[dep] = builders[dep].image
+			}
+
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			sb := b.forkStage(images, stageNames, outputs[i])
+			for j, n := range s.nodes {
+				if err := sb.step(s.start+j, n); err != nil {
+					errs[i] = err
+					fail(err)
+					return
+				}
+			}
+			builders[i] = sb
+		}(i, s, stageNames)
+	}
+
+	for i := range stages {
+		if err := outputs[i].attach(); err != nil {
+			fail(err)
+		}
+		<-done[i]
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	last := builders[len(builders)-1]
+	for _, sb := range builders[:len(builders)-1] {
+		b.stages = append(b.stages, sb.image)
+	}
+	for _, sb := range builders {
+		for arg := range sb.declaredArgs {
+			b.declaredArgs[arg] = true
+		}
+	}
+	b.image = last.image
+	b.fromImage = last.fromImage
+	b.runConfig = last.runConfig
+	b.inStage = true
+	return nil
+}
+
+// forkStage returns a Builder running a build stage alongside other stages.
+// It shares the build options, context and backend with b, but has a state
+// of its own. stages holds the images of the earlier stages it depends on.
+func (b *Builder) forkStage(stages []string, stageNames map[string]int, out io.Writer) *Builder {
+	return &Builder{
+		options:          b.options,
+		Stdout:           redirectOutput(b.Stdout, out),
+		Stderr:           redirectOutput(b.Stderr, out),
+		Output:           out,
+		docker:           b.docker,
+		imageCache:       b.imageCache,
+		context:          b.context,
+		clientCtx:        b.clientCtx,
+		cancel:           b.cancel,
+		dockerfile:       b.dockerfile,
+		runConfig:        new(container.Config),
+		tmpContainers:    map[string]struct{}{},
+		allowedBuildArgs: make(map[string]bool),
+		buildArgDefaults: make(map[string]string),
+		declaredArgs:     make(map[string]bool),
+		stages:           stages,
+		stageNames:       stageNames,
+		secretsDir:       b.secretsDir,
+		id:               b.id,
+	}
+}
+
+// redirectOutput returns a writer formatting its output like w, but writing
+// it to out.
+func redirectOutput(w io.Writer, out io.Writer) io.Writer {
+	switch f := w.(type) {
+	case *streamformatter.StdoutFormatter:
+		return &streamformatter.StdoutFormatter{Writer: out, StreamFormatter: f.StreamFormatter}
+	case *streamformatter.StderrFormatter:
+		return &streamformatter.StderrFormatter{Writer: out, StreamFormatter: f.StreamFormatter}
+	}
+	return out
+}
+
+// stageOutput buffers the output of a build stage until it is attached to
+// the output of the build, from which point it is written through.
+type stageOutput struct {
+	mu       sync.Mutex
+	out      io.Writer
+	buf      bytes.Buffer
+	attached bool
+}
+
+func (o *stageOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.attached {
+		return o.out.Write(p)
+	}
+	return o.buf.Write(p)
+}
+
+// attach writes out what was buffered so far, and the rest of the output
+// as it comes.
+func (o *stageOutput) attach() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attached = true
+	_, err := o.buf.WriteTo(o.out)
+	return err
+}
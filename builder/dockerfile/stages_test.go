@@ -0,0 +1,80 @@
+package dockerfile
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/builder/dockerfile/parser"
+)
+
+func TestSplitStages(t *testing.T) {
+	dockerfile := `FROM busybox AS base
+RUN make
+FROM busybox AS Tools
+RUN make tools
+FROM base
+COPY --from=tools /bin/tool /bin/
+COPY --from=0 /out /out
+COPY --from=unknown /out /out
+FROM scratch
+COPY --from=2 /out /
+COPY --from=3 /out /
+COPY --from=TOOLS /bin/tool /bin/`
+
+	ast, err := parser.Parse(strings.NewReader(dockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages := splitStages(ast.Children)
+
+	expected := []struct {
+		start int
+		name  string
+		deps  []int
+	}{
+		{0, "base", nil},
+		{2, "tools", nil},
+		{4, "", []int{0, 1}},
+		{8, "", []int{2, 1}},
+	}
+	if len(stages) != len(expected) {
+		t.Fatalf("expected %d stages, got %d", len(expected), len(stages))
+	}
+	for i, e := range expected {
+		s := stages[i]
+		if s.start != e.start || s.name != e.name || !reflect.DeepEqual(s.deps, e.deps) {
+			t.Fatalf("stage %d: expected start %d, name %q and deps %v, got %d, %q and %v", i, e.start, e.name, e.deps, s.start, s.name, s.deps)
+		}
+	}
+	if len(stages[3].nodes) != 4 {
+		t.Fatalf("expected the last stage to have 4 instructions, got %d", len(stages[3].nodes))
+	}
+}
+
+func TestStageOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	first := &stageOutput{out: out}
+	second := &stageOutput{out: out}
+
+	fmt.Fprint(second, "second ")
+	fmt.Fprint(first, "first ")
+	if out.Len() != 0 {
+		t.Fatalf("expected the output to be held back, got %q", out.String())
+	}
+
+	if err := first.attach(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(first, "done ")
+	if err := second.attach(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(second, "done")
+
+	if expected := "first done second done"; out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,51 @@
+package builder
+
+// imageContext is a Context over the mounted root filesystem of an image,
+// used to copy files out of an earlier stage of a multi-stage build.
+type imageContext struct {
+	tarSumContext
+	imageID string
+	release func() error
+}
+
+// NewImageContext returns a build Context reading from root, where the
+// filesystem of the image imageID is mounted.
+//
+// The content of an image never changes, so files are hashed with the image
+// ID and their path instead of their content. release is called when the
+// Context is closed, the mount itself is left alone.
+func NewImageContext(imageID, root string, release func() error) Context {
+	return &imageContext{
+		tarSumContext: tarSumContext{root: root},
+		imageID:       imageID,
+		release:       release,
+	}
+}
+
+func (c *imageContext) Close() error {
+	return c.release()
+}
+
+func (c *imageContext) Stat(path string) (string, FileInfo, error) {
+	rel, fi, err := c.tarSumContext.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	c.setHash(fi)
+	return rel, fi, nil
+}
+
+func (c *imageContext) Walk(root string, walkFn WalkFunc) error {
+	return c.tarSumContext.Walk(root, func(path string, fi FileInfo, err error) error {
+		if err == nil {
+			c.setHash(fi)
+		}
+		return walkFn(path, fi, err)
+	})
+}
+
+func (c *imageContext) setHash(fi FileInfo) {
+	if hfi, ok := fi.(Hashed); ok {
+		hfi.SetHash(c.imageID + ":" + hfi.Hash())
+	}
+}
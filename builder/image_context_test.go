@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageContext(t *testing.T) {
+	root, err := ioutil.TempDir("", "builder-image-context-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "app", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "app", "bin", "server"), []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	released := false
+	ctx := NewImageContext("sha256:abcd", root, func() error {
+		released = true
+		return nil
+	})
+
+	rel, fi, err := ctx.Stat("app/bin/server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != filepath.Join("app", "bin", "server") {
+		t.Fatalf("unexpected relative path %q", rel)
+	}
+	if h := fi.(Hashed).Hash(); h != "sha256:abcd:app/bin/server" {
+		t.Fatalf("unexpected hash %q", h)
+	}
+
+	var hashes []string
+	if err := ctx.Walk("app", func(path string, fi FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, fi.(Hashed).Hash())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"sha256:abcd:app", "sha256:abcd:app/bin", "sha256:abcd:app/bin/server"}
+	if len(hashes) != len(expected) {
+		t.Fatalf("expected hashes %v, got %v", expected, hashes)
+	}
+	for i := range expected {
+		if filepath.FromSlash(expected[i]) != hashes[i] {
+			t.Fatalf("expected hashes %v, got %v", expected, hashes)
+		}
+	}
+
+	if _, _, err := ctx.Stat("../../etc/passwd"); err == nil {
+		t.Fatal("expected an error for a path outside of the image")
+	}
+
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !released {
+		t.Fatal("expected the image to be released on Close")
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("expected the mount point to be left alone: %v", err)
+	}
+}
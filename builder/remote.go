@@ -33,7 +33,7 @@ var mimeRe = regexp.MustCompile(acceptableRemoteMIME)
 func MakeRemoteContext(remoteURL string, contentTypeHandlers map[string]func(io.ReadCloser) (io.ReadCloser, error)) (ModifiableContext, error) {
 	f, err := httputils.Download(remoteURL)
 	if err != nil {
-		return nil, fmt.Errorf("Error downloading remote context %s: %v", remoteURL, err)
+		return nil, fmt.Errorf("Error downloading remote context %s: %v", urlutil.Redact(remoteURL), err)
 	}
 	defer f.Body.Close()
 
@@ -44,7 +44,7 @@ func MakeRemoteContext(remoteURL string, contentTypeHandlers map[string]func(io.
 
 		contentType, contextReader, err = inspectResponse(contentType, f.Body, clen)
 		if err != nil {
-			return nil, fmt.Errorf("Error detecting content type for remote %s: %v", remoteURL, err)
+			return nil, fmt.Errorf("Error detecting content type for remote %s: %v", urlutil.Redact(remoteURL), err)
 		}
 		defer contextReader.Close()
 
@@ -97,7 +97,7 @@ func DetectContextFromRemoteURL(r io.ReadCloser, remoteURL string, createProgres
 			},
 		})
 	default:
-		err = fmt.Errorf("remoteURL (%s) could not be recognized as URL", remoteURL)
+		err = fmt.Errorf("remoteURL (%s) could not be recognized as URL", urlutil.Redact(remoteURL))
 	}
 	return
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/pkg/term"
 	"github.com/docker/docker/restartmanager"
 	"github.com/docker/docker/runconfig"
 	runconfigopts "github.com/docker/docker/runconfig/opts"
@@ -468,50 +469,16 @@ func AttachStreams(ctx context.Context, streamConfig *runconfig.StreamConfig, op
 	})
 }
 
-// Code c/c from io.Copy() modified to handle escape sequence
+// copyEscapable copies src to dst until the escape sequence in keys, or
+// ctrl-p ctrl-q if keys is empty, is read. src is closed when that happens.
 func copyEscapable(dst io.Writer, src io.ReadCloser, keys []byte) (written int64, err error) {
 	if len(keys) == 0 {
 		// Default keys : ctrl-p ctrl-q
 		keys = []byte{16, 17}
 	}
-	buf := make([]byte, 32*1024)
-	for {
-		nr, er := src.Read(buf)
-		if nr > 0 {
-			// ---- Docker addition
-			for i, key := range keys {
-				if nr != 1 || buf[0] != key {
-					break
-				}
-				if i == len(keys)-1 {
-					if err := src.Close(); err != nil {
-						return 0, err
-					}
-					return 0, nil
-				}
-				nr, er = src.Read(buf)
-			}
-			// ---- End of docker
-			nw, ew := dst.Write(buf[0:nr])
-			if nw > 0 {
-				written += int64(nw)
-			}
-			if ew != nil {
-				err = ew
-				break
-			}
-			if nr != nw {
-				err = io.ErrShortWrite
-				break
-			}
-		}
-		if er == io.EOF {
-			break
-		}
-		if er != nil {
-			err = er
-			break
-		}
+	written, err = io.Copy(dst, term.NewEscapeProxy(src, keys))
+	if _, ok := err.(term.EscapeError); ok {
+		return written, src.Close()
 	}
 	return written, err
 }
@@ -519,7 +486,7 @@ func copyEscapable(dst io.Writer, src io.ReadCloser, keys []byte) (written int64
 // ShouldRestart decides whether the daemon should restart the container or not.
 // This is based on the container's restart policy.
 func (container *Container) ShouldRestart() bool {
-	shouldRestart, _, _ := container.restartManager.ShouldRestart(uint32(container.ExitCode), container.HasBeenManuallyStopped)
+	shouldRestart, _, _ := container.restartManager.ShouldRestart(uint32(container.ExitCode), container.HasBeenManuallyStopped, container.FinishedAt.Sub(container.StartedAt))
 	return shouldRestart
 }
 
@@ -904,6 +871,27 @@ func (container *Container) FullHostname() string {
 	return fullHostname
 }
 
+// SetRestartResetWindow sets how long the container has to run before the
+// backoff between its restarts is reset.
+func (container *Container) SetRestartResetWindow(window time.Duration) {
+	type resetWindowSetter interface {
+		SetResetWindow(time.Duration)
+	}
+
+	if rm, ok := container.RestartManager(false).(resetWindowSetter); ok {
+		rm.SetResetWindow(window)
+	}
+}
+
+// NextRestart returns when the pending restart of the container is due, or
+// the zero time if no restart is pending.
+func (container *Container) NextRestart() time.Time {
+	if container.restartManager == nil {
+		return time.Time{}
+	}
+	return container.restartManager.NextRestart()
+}
+
 // RestartManager returns the current restartmanager instance connected to container.
 func (container *Container) RestartManager(reset bool) restartmanager.RestartManager {
 	if reset {
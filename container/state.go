@@ -26,6 +26,7 @@ type State struct {
 	Error             string // contains last known error when starting the container
 	StartedAt         time.Time
 	FinishedAt        time.Time
+	Health            *Health
 	waitChan          chan struct{}
 }
 
@@ -46,6 +47,10 @@ func (s *State) String() string {
 			return fmt.Sprintf("Restarting (%d) %s ago", s.ExitCode, units.HumanDuration(time.Now().UTC().Sub(s.FinishedAt)))
 		}
 
+		if h := s.Health; h != nil {
+			return fmt.Sprintf("Up %s (%s)", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)), h.String())
+		}
+
 		return fmt.Sprintf("Up %s", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
 	}
 
@@ -186,6 +191,7 @@ func (s *State) SetRunning(pid int, initial bool) {
 	s.ExitCode = 0
 	s.Pid = pid
 	if initial {
+		s.OOMKilled = false
 		s.StartedAt = time.Now().UTC()
 	}
 	close(s.waitChan) // fire waiters for start
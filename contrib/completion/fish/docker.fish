@@ -129,6 +129,11 @@ complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l entrypoint -
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l env-file -d 'Read in a line delimited file of environment variables'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l expose -d 'Expose a port or a range of ports (e.g. --expose=3300-3310) from the container without publishing it to your host'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l group-add -d 'Add additional groups to run as'
+complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l health-cmd -d 'Command to run to check health'
+complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l health-interval -d 'Time between running the check'
+complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l health-retries -d 'Consecutive failures needed to report unhealthy'
+complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l health-timeout -d 'Maximum time to allow one check to run'
+complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l no-healthcheck -d 'Disable any container-specified HEALTHCHECK'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -s h -l hostname -d 'Container host name'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l help -d 'Print usage'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -s i -l interactive -d 'Keep STDIN open even if not attached'
@@ -317,6 +322,11 @@ complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l entrypoint -d '
 complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l env-file -d 'Read in a line delimited file of environment variables'
 complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l expose -d 'Expose a port or a range of ports (e.g. --expose=3300-3310) from the container without publishing it to your host'
 complete -c docker -A -f -n '__fish_seen_subcommand_from create' -l group-add -d 'Add additional groups to run as'
+complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l health-cmd -d 'Command to run to check health'
+complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l health-interval -d 'Time between running the check'
+complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l health-retries -d 'Consecutive failures needed to report unhealthy'
+complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l health-timeout -d 'Maximum time to allow one check to run'
+complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l no-healthcheck -d 'Disable any container-specified HEALTHCHECK'
 complete -c docker -A -f -n '__fish_seen_subcommand_from run' -s h -l hostname -d 'Container host name'
 complete -c docker -A -f -n '__fish_seen_subcommand_from run' -l help -d 'Print usage'
 complete -c docker -A -f -n '__fish_seen_subcommand_from run' -s i -l interactive -d 'Keep STDIN open even if not attached'
@@ -0,0 +1,55 @@
+// +build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/docker/pkg/stringid"
+)
+
+// MountBuildSecrets writes the secrets of a build, by ID, into a new tmpfs
+// so that they never touch the disk, and returns its path. The returned
+// function unmounts and removes the tmpfs.
+func (daemon *Daemon) MountBuildSecrets(secrets map[string][]byte) (string, func() error, error) {
+	rootUID, rootGID := daemon.GetRemappedUIDGID()
+	dir := filepath.Join(daemon.root, "build-secrets", stringid.GenerateRandomID())
+	if err := idtools.MkdirAllAs(dir, 0700, rootUID, rootGID); err != nil {
+		return "", nil, err
+	}
+	release := func() error {
+		if err := mount.Unmount(dir); err != nil {
+			return err
+		}
+		return os.RemoveAll(dir)
+	}
+
+	options := fmt.Sprintf("nodev,nosuid,noexec,mode=0700,uid=%d,gid=%d", rootUID, rootGID)
+	if err := mount.Mount("tmpfs", dir, "tmpfs", options); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("mounting build secrets tmpfs: %v", err)
+	}
+
+	var err error
+	for id, data := range secrets {
+		path := filepath.Join(dir, id)
+		if filepath.Base(path) != id {
+			err = fmt.Errorf("invalid build secret ID %q", id)
+		} else if err = ioutil.WriteFile(path, data, 0400); err == nil {
+			err = os.Chown(path, rootUID, rootGID)
+		}
+		if err != nil {
+			if err := release(); err != nil {
+				logrus.Errorf("Error removing build secrets: %v", err)
+			}
+			return "", nil, err
+		}
+	}
+	return dir, release, nil
+}
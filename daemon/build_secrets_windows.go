@@ -0,0 +1,9 @@
+package daemon
+
+import "fmt"
+
+// MountBuildSecrets is not supported on Windows, which has no tmpfs to keep
+// the secrets of a build off the disk.
+func (daemon *Daemon) MountBuildSecrets(secrets map[string][]byte) (string, func() error, error) {
+	return "", nil, fmt.Errorf("build secrets are not supported on Windows")
+}
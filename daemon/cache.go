@@ -0,0 +1,224 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	containertypes "github.com/docker/engine-api/types/container"
+)
+
+// MakeImageCache creates the image cache of a build. Besides the local
+// images, it looks up the history of the images referenced by sourceRefs,
+// which do not need to have been built locally.
+func (daemon *Daemon) MakeImageCache(sourceRefs []string) builder.ImageCache {
+	if len(sourceRefs) == 0 {
+		return &localImageCache{daemon}
+	}
+
+	cache := &imageCache{daemon: daemon, localImageCache: &localImageCache{daemon}}
+	for _, ref := range sourceRefs {
+		img, err := daemon.GetImage(ref)
+		if err != nil {
+			logrus.Warnf("Could not look up %s for cache resolution, skipping: %v", ref, err)
+			continue
+		}
+		cache.sources = append(cache.sources, img)
+	}
+	return cache
+}
+
+// localImageCache matches the images that were built locally, through the
+// parent chain of the images.
+type localImageCache struct {
+	daemon *Daemon
+}
+
+func (lic *localImageCache) GetCachedImageOnBuild(parentID string, cfg, imageCfg *containertypes.Config) (string, error) {
+	return lic.daemon.GetCachedImageOnBuild(parentID, cfg)
+}
+
+// imageCache matches the history of source images, which is all there is to
+// go on for images that were pulled. On a match, the image of the matching
+// build step is recreated from the layers of the source image. Local images
+// only match if they are one of the source images or their parents, so that
+// the source images are the only ones the build is based on.
+type imageCache struct {
+	sources         []*image.Image
+	daemon          *Daemon
+	localImageCache *localImageCache
+}
+
+func (ic *imageCache) GetCachedImageOnBuild(parentID string, cfg, imageCfg *containertypes.Config) (string, error) {
+	imgID, err := ic.localImageCache.GetCachedImageOnBuild(parentID, cfg, imageCfg)
+	if err != nil {
+		return "", err
+	}
+	if imgID != "" {
+		for _, s := range ic.sources {
+			if isParent(ic.daemon.imageStore, s.ID(), image.ID(imgID)) {
+				return imgID, nil
+			}
+		}
+	}
+
+	var parent *image.Image
+	lenHistory := 0
+	if parentID != "" {
+		parent, err = ic.daemon.imageStore.Get(image.ID(parentID))
+		if err != nil {
+			return "", fmt.Errorf("unable to find image %v", parentID)
+		}
+		lenHistory = len(parent.History)
+	}
+
+	for _, target := range ic.sources {
+		if !hasParentHistory(target, parent) || !isValidConfig(cfg, target.History[lenHistory]) {
+			continue
+		}
+
+		if len(target.History)-1 == lenHistory { // last
+			if parent != nil {
+				if err := ic.daemon.imageStore.SetParent(target.ID(), parent.ID()); err != nil {
+					return "", fmt.Errorf("failed to set parent for %v to %v: %v", target.ID(), parent.ID(), err)
+				}
+			}
+			return target.ID().String(), nil
+		}
+
+		imgID, err := ic.restoreCachedImage(parent, target, cfg, imageCfg)
+		if err != nil {
+			return "", fmt.Errorf("failed to restore cached image from %q to %v: %v", parentID, target.ID(), err)
+		}
+		return imgID.String(), nil
+	}
+
+	return "", nil
+}
+
+// restoreCachedImage creates the image of the build step following parent
+// in the history of target.
+func (ic *imageCache) restoreCachedImage(parent, target *image.Image, cfg, imageCfg *containertypes.Config) (image.ID, error) {
+	var history []image.History
+	rootFS := image.NewRootFS()
+	lenHistory := 0
+	if parent != nil {
+		// copy what is appended to, parent must not be modified
+		history = append(history, parent.History...)
+		parentRootFS := *parent.RootFS
+		parentRootFS.DiffIDs = append([]layer.DiffID(nil), parent.RootFS.DiffIDs...)
+		rootFS = &parentRootFS
+		lenHistory = len(parent.History)
+	}
+	history = append(history, target.History[lenHistory])
+	if diffID := getLayerForHistoryIndex(target, lenHistory); diffID != "" {
+		rootFS.Append(diffID)
+	}
+
+	config, err := json.Marshal(&image.Image{
+		V1Image: image.V1Image{
+			DockerVersion:   dockerversion.Version,
+			Config:          imageCfg,
+			Architecture:    target.Architecture,
+			OS:              target.OS,
+			ContainerConfig: *cfg,
+			Author:          target.Author,
+			Created:         history[len(history)-1].Created,
+		},
+		RootFS:     rootFS,
+		History:    history,
+		OSFeatures: target.OSFeatures,
+		OSVersion:  target.OSVersion,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	imgID, err := ic.daemon.imageStore.Create(config)
+	if err != nil {
+		return "", err
+	}
+
+	if parent != nil {
+		if err := ic.daemon.imageStore.SetParent(imgID, parent.ID()); err != nil {
+			return "", err
+		}
+	}
+	return imgID, nil
+}
+
+// isParent returns whether parentID is imgID, or one of its parents.
+func isParent(is image.Store, imgID, parentID image.ID) bool {
+	for imgID != "" {
+		if imgID == parentID {
+			return true
+		}
+		parent, err := is.GetParent(imgID)
+		if err != nil {
+			return false
+		}
+		imgID = parent
+	}
+	return false
+}
+
+// hasParentHistory returns whether the history and the layers of parent are
+// the beginning of the ones of img, with at least one more history entry.
+func hasParentHistory(img, parent *image.Image) bool {
+	if len(img.History) == 0 {
+		return false
+	}
+	if parent == nil || len(parent.History) == 0 && len(parent.RootFS.DiffIDs) == 0 {
+		return true
+	}
+	if len(parent.History) >= len(img.History) {
+		return false
+	}
+	if len(parent.RootFS.DiffIDs) > len(img.RootFS.DiffIDs) {
+		return false
+	}
+
+	for i, h := range parent.History {
+		if !reflect.DeepEqual(h, img.History[i]) {
+			return false
+		}
+	}
+	for i, d := range parent.RootFS.DiffIDs {
+		if d != img.RootFS.DiffIDs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// getLayerForHistoryIndex returns the layer created by the build step at
+// index in the history of img, or an empty DiffID if the step did not create
+// a layer.
+func getLayerForHistoryIndex(img *image.Image, index int) layer.DiffID {
+	layerIndex := 0
+	for i, h := range img.History {
+		if i == index {
+			if h.EmptyLayer || layerIndex >= len(img.RootFS.DiffIDs) {
+				return ""
+			}
+			break
+		}
+		if !h.EmptyLayer {
+			layerIndex++
+		}
+	}
+	return img.RootFS.DiffIDs[layerIndex]
+}
+
+// isValidConfig returns whether the build step with config cfg is the one
+// recorded in the history entry h. The history only keeps the command of
+// the step, so this is all that can be compared.
+func isValidConfig(cfg *containertypes.Config, h image.History) bool {
+	return strings.Join(cfg.Cmd, " ") == h.CreatedBy
+}
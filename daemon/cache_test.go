@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/strslice"
+)
+
+func newHistoryImage(diffIDs []layer.DiffID, history ...image.History) *image.Image {
+	rootFS := image.NewRootFS()
+	rootFS.DiffIDs = diffIDs
+	return &image.Image{RootFS: rootFS, History: history}
+}
+
+func TestImageCacheHistoryMatching(t *testing.T) {
+	var _ builder.ImageCacheBuilder = &Daemon{}
+
+	from := image.History{CreatedBy: "/bin/sh -c #(nop) ADD file:1234 in /"}
+	env := image.History{CreatedBy: "/bin/sh -c #(nop) ENV FOO=bar", EmptyLayer: true}
+	run := image.History{CreatedBy: "/bin/sh -c make"}
+	target := newHistoryImage([]layer.DiffID{"sha256:base", "sha256:make"}, from, env, run)
+
+	if !hasParentHistory(target, nil) {
+		t.Fatal("every image with a history should match a build from scratch")
+	}
+	if !hasParentHistory(target, newHistoryImage([]layer.DiffID{"sha256:base"}, from)) {
+		t.Fatal("expected the history of the base image to match")
+	}
+	if !hasParentHistory(target, newHistoryImage([]layer.DiffID{"sha256:base"}, from, env)) {
+		t.Fatal("expected the history up to ENV to match")
+	}
+	if hasParentHistory(target, newHistoryImage([]layer.DiffID{"sha256:other"}, from)) {
+		t.Fatal("expected different layers not to match")
+	}
+	if hasParentHistory(target, newHistoryImage([]layer.DiffID{"sha256:base"}, from, run)) {
+		t.Fatal("expected a different history not to match")
+	}
+	if hasParentHistory(target, target) {
+		t.Fatal("expected the image not to match itself, there is no next step")
+	}
+	if hasParentHistory(newHistoryImage(nil), nil) {
+		t.Fatal("expected an image without history not to match")
+	}
+
+	for index, expected := range []layer.DiffID{"sha256:base", "", "sha256:make"} {
+		if diffID := getLayerForHistoryIndex(target, index); diffID != expected {
+			t.Fatalf("expected layer %q for history entry %d, got %q", expected, index, diffID)
+		}
+	}
+
+	cfg := &containertypes.Config{Cmd: strslice.StrSlice{"/bin/sh", "-c", "make"}}
+	if !isValidConfig(cfg, run) {
+		t.Fatal("expected the command to match its history entry")
+	}
+	if isValidConfig(cfg, env) {
+		t.Fatal("expected the command not to match another history entry")
+	}
+}
@@ -94,6 +94,29 @@ func merge(userConf, imageConf *containertypes.Config) error {
 	if userConf.StopSignal == "" {
 		userConf.StopSignal = imageConf.StopSignal
 	}
+
+	if len(userConf.Shell) == 0 {
+		userConf.Shell = imageConf.Shell
+	}
+
+	if imageConf.Healthcheck != nil {
+		if userConf.Healthcheck == nil {
+			userConf.Healthcheck = imageConf.Healthcheck
+		} else {
+			if len(userConf.Healthcheck.Test) == 0 {
+				userConf.Healthcheck.Test = imageConf.Healthcheck.Test
+			}
+			if userConf.Healthcheck.Interval == 0 {
+				userConf.Healthcheck.Interval = imageConf.Healthcheck.Interval
+			}
+			if userConf.Healthcheck.Timeout == 0 {
+				userConf.Healthcheck.Timeout = imageConf.Healthcheck.Timeout
+			}
+			if userConf.Healthcheck.Retries == 0 {
+				userConf.Healthcheck.Retries = imageConf.Healthcheck.Retries
+			}
+		}
+	}
 	return nil
 }
 
@@ -243,3 +266,89 @@ func (daemon *Daemon) exportContainerRw(container *container.Container) (archive
 		}),
 		nil
 }
+
+// SquashImage creates a new image with the diff of the image id since its
+// parent image as its only layer on top of the layers of the parent. An
+// empty parent squashes all the layers of the image into one. The history of
+// the image is kept, with the squashed entries marked as not creating a layer.
+func (daemon *Daemon) SquashImage(id, parent string) (string, error) {
+	img, err := daemon.imageStore.Get(image.ID(id))
+	if err != nil {
+		return "", err
+	}
+
+	var parentImg *image.Image
+	var parentChainID layer.ChainID
+	if parent != "" {
+		parentImg, err = daemon.imageStore.Get(image.ID(parent))
+		if err != nil {
+			return "", fmt.Errorf("error getting specified parent layer: %v", err)
+		}
+		parentChainID = parentImg.RootFS.ChainID()
+	} else {
+		parentImg = &image.Image{RootFS: image.NewRootFS()}
+	}
+
+	l, err := daemon.layerStore.Get(img.RootFS.ChainID())
+	if err != nil {
+		return "", fmt.Errorf("error getting image layer: %v", err)
+	}
+	defer layer.ReleaseAndLog(daemon.layerStore, l)
+
+	ts, err := l.TarStreamFrom(parentChainID)
+	if err != nil {
+		return "", fmt.Errorf("error getting tar stream to parent: %v", err)
+	}
+	defer ts.Close()
+
+	newL, err := daemon.layerStore.Register(ts, parentChainID)
+	if err != nil {
+		return "", fmt.Errorf("error registering layer: %v", err)
+	}
+	defer layer.ReleaseAndLog(daemon.layerStore, newL)
+
+	newImage := *img
+	newImage.Parent = ""
+
+	rootFS := *parentImg.RootFS
+	rootFS.DiffIDs = append(append([]layer.DiffID(nil), parentImg.RootFS.DiffIDs...), newL.DiffID())
+	newImage.RootFS = &rootFS
+
+	newImage.History = make([]image.History, len(img.History))
+	for i, h := range img.History {
+		if i >= len(parentImg.History) {
+			h.EmptyLayer = true
+		}
+		newImage.History[i] = h
+	}
+
+	now := time.Now().UTC()
+	var historyComment string
+	if parent != "" {
+		historyComment = fmt.Sprintf("merge %s to %s", id, parent)
+	} else {
+		historyComment = fmt.Sprintf("create new from %s", id)
+	}
+	newImage.History = append(newImage.History, image.History{
+		Created: now,
+		Comment: historyComment,
+	})
+	newImage.Created = now
+
+	config, err := json.Marshal(&newImage)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling image config: %v", err)
+	}
+
+	newImgID, err := daemon.imageStore.Create(config)
+	if err != nil {
+		return "", fmt.Errorf("error creating new image after squash: %v", err)
+	}
+
+	if parent != "" {
+		if err := daemon.imageStore.SetParent(newImgID, image.ID(parent)); err != nil {
+			return "", err
+		}
+	}
+	return newImgID.String(), nil
+}
@@ -8,18 +8,27 @@ import (
 	"io/ioutil"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/discovery"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/registry"
+	"github.com/docker/docker/restartmanager"
 	"github.com/imdario/mergo"
 )
 
 const (
 	defaultNetworkMtu    = 1500
 	disableNetworkBridge = "none"
+
+	// defaultMaxConcurrentDownloads is the default number of layers that
+	// may be downloaded at a time for each pull.
+	defaultMaxConcurrentDownloads = 3
+	// defaultMaxConcurrentUploads is the default number of layers that
+	// may be uploaded at a time for each push.
+	defaultMaxConcurrentUploads = 5
 )
 
 // flatOptions contains configuration keys
@@ -28,7 +37,24 @@ const (
 // with others like the ones in CommonTLSOptions.
 var flatOptions = map[string]bool{
 	"cluster-store-opts": true,
+	"default-ulimits":    true,
 	"log-opts":           true,
+	"runtimes":           true,
+}
+
+// reloadableOptions contains the configuration keys
+// that can be changed without restarting the daemon.
+var reloadableOptions = map[string]bool{
+	"cluster-advertise":        true,
+	"cluster-store":            true,
+	"cluster-store-opts":       true,
+	"debug":                    true,
+	"insecure-registries":      true,
+	"labels":                   true,
+	"log-level":                true,
+	"max-concurrent-downloads": true,
+	"max-concurrent-uploads":   true,
+	"storage-gc-threshold":     true,
 }
 
 // LogConfig represents the default log configuration.
@@ -63,6 +89,9 @@ type CommonConfig struct {
 	AuthorizationPlugins []string            `json:"authorization-plugins,omitempty"` // AuthorizationPlugins holds list of authorization plugins
 	AutoRestart          bool                `json:"-"`
 	Context              map[string][]string `json:"-"`
+	ContentTrust         bool                `json:"content-trust,omitempty"`
+	ContentTrustServer   string              `json:"content-trust-server,omitempty"`
+	DeltaServer          string              `json:"delta-server,omitempty"`
 	DisableBridge        bool                `json:"-"`
 	DNS                  []string            `json:"dns,omitempty"`
 	DNSOptions           []string            `json:"dns-opts,omitempty"`
@@ -71,9 +100,12 @@ type CommonConfig struct {
 	GraphDriver          string              `json:"storage-driver,omitempty"`
 	GraphOptions         []string            `json:"storage-opts,omitempty"`
 	Labels               []string            `json:"labels,omitempty"`
+	MetricsAddress       string              `json:"metrics-addr,omitempty"`
+	RegistryCacheAddress string              `json:"serve-registry-cache,omitempty"`
 	Mtu                  int                 `json:"mtu,omitempty"`
 	Pidfile              string              `json:"pidfile,omitempty"`
 	RawLogs              bool                `json:"raw-logs,omitempty"`
+	RestartResetWindow   int                 `json:"restart-reset-window,omitempty"`
 	Root                 string              `json:"graph,omitempty"`
 	SocketGroup          string              `json:"group,omitempty"`
 	TrustKeyPath         string              `json:"-"`
@@ -92,6 +124,19 @@ type CommonConfig struct {
 	// reachable by other hosts.
 	ClusterAdvertise string `json:"cluster-advertise,omitempty"`
 
+	// MaxConcurrentDownloads is the maximum number of layers that may be
+	// downloaded at a time for each pull.
+	MaxConcurrentDownloads int `json:"max-concurrent-downloads,omitempty"`
+
+	// MaxConcurrentUploads is the maximum number of layers that may be
+	// uploaded at a time for each push.
+	MaxConcurrentUploads int `json:"max-concurrent-uploads,omitempty"`
+
+	// StorageGCThreshold is the disk usage of the daemon root, in percent,
+	// above which the unreferenced layers and stale temporary files are
+	// removed. A threshold of 0 disables the collection.
+	StorageGCThreshold int `json:"storage-gc-threshold,omitempty"`
+
 	Debug     bool     `json:"debug,omitempty"`
 	Hosts     []string `json:"hosts,omitempty"`
 	LogLevel  string   `json:"log-level,omitempty"`
@@ -135,6 +180,15 @@ func (config *Config) InstallCommonFlags(cmd *flag.FlagSet, usageFn func(string)
 	cmd.StringVar(&config.ClusterAdvertise, []string{"-cluster-advertise"}, "", usageFn("Address or interface name to advertise"))
 	cmd.StringVar(&config.ClusterStore, []string{"-cluster-store"}, "", usageFn("Set the cluster store"))
 	cmd.Var(opts.NewNamedMapOpts("cluster-store-opts", config.ClusterOpts, nil), []string{"-cluster-store-opt"}, usageFn("Set cluster store options"))
+	cmd.StringVar(&config.MetricsAddress, []string{"-metrics-addr"}, "", usageFn("Set address and port to serve the metrics api"))
+	cmd.StringVar(&config.RegistryCacheAddress, []string{"-serve-registry-cache"}, "", usageFn("Set address and port to serve the local images over the registry v2 protocol"))
+	cmd.IntVar(&config.MaxConcurrentDownloads, []string{"-max-concurrent-downloads"}, defaultMaxConcurrentDownloads, usageFn("Set the max concurrent layer downloads of all pulls"))
+	cmd.IntVar(&config.MaxConcurrentUploads, []string{"-max-concurrent-uploads"}, defaultMaxConcurrentUploads, usageFn("Set the max concurrent layer uploads of all pushes"))
+	cmd.BoolVar(&config.ContentTrust, []string{"-content-trust"}, false, usageFn("Only pull images whose signature is verified"))
+	cmd.StringVar(&config.ContentTrustServer, []string{"-content-trust-server"}, "", usageFn("Notary server to verify the signature of images with"))
+	cmd.StringVar(&config.DeltaServer, []string{"-delta-server"}, "", usageFn("Server to download layers from as binary diffs of the layers pulled before"))
+	cmd.IntVar(&config.StorageGCThreshold, []string{"-storage-gc-threshold"}, defaultStorageGCThreshold, usageFn("Disk usage percentage above which unreferenced layers are removed (0 to disable)"))
+	cmd.IntVar(&config.RestartResetWindow, []string{"-restart-reset-window"}, int(restartmanager.DefaultResetWindow/time.Second), usageFn("Number of seconds a container must run for its restart backoff to be reset"))
 }
 
 // IsValueSet returns true if a configuration value
@@ -375,5 +429,46 @@ func validateConfiguration(config *Config) error {
 		}
 	}
 
+	// validate InsecureRegistries
+	for _, r := range config.InsecureRegistries {
+		if _, err := registry.ValidateIndexName(r); err != nil {
+			return err
+		}
+	}
+
+	// validate LogLevel
+	if config.LogLevel != "" {
+		if _, err := logrus.ParseLevel(config.LogLevel); err != nil {
+			return fmt.Errorf("invalid log level: %s", config.LogLevel)
+		}
+	}
+
+	// validate MaxConcurrentDownloads and MaxConcurrentUploads
+	if config.MaxConcurrentDownloads < 0 {
+		return fmt.Errorf("invalid max concurrent downloads: %d", config.MaxConcurrentDownloads)
+	}
+	if config.MaxConcurrentUploads < 0 {
+		return fmt.Errorf("invalid max concurrent uploads: %d", config.MaxConcurrentUploads)
+	}
+
+	// validate StorageGCThreshold
+	if config.StorageGCThreshold < 0 || config.StorageGCThreshold > 100 {
+		return fmt.Errorf("invalid storage GC threshold: %d, must be between 0 and 100", config.StorageGCThreshold)
+	}
+
 	return nil
 }
+
+// configToMap returns the configuration keyed by the names
+// used in the configuration file.
+func configToMap(config *Config) (map[string]interface{}, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -275,4 +276,79 @@ func TestValidateConfiguration(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
+
+	c7 := &Config{
+		CommonConfig: CommonConfig{
+			LogLevel: "verbose",
+		},
+	}
+
+	err = validateConfiguration(c7)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	c8 := &Config{
+		CommonConfig: CommonConfig{
+			MaxConcurrentDownloads: -1,
+		},
+	}
+
+	err = validateConfiguration(c8)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	c9 := &Config{
+		CommonConfig: CommonConfig{
+			StorageGCThreshold: 101,
+		},
+	}
+
+	err = validateConfiguration(c9)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestDaemonConfigurationCoversFlags(t *testing.T) {
+	config := &Config{}
+	flags := mflag.NewFlagSet("test", mflag.ContinueOnError)
+	config.InstallFlags(flags, func(s string) string { return s })
+
+	keys := make(map[string]bool)
+	collectConfigKeys(reflect.TypeOf(config).Elem(), keys)
+
+	flags.VisitAll(func(f *mflag.Flag) {
+		var key string
+		if namedOption, ok := f.Value.(opts.NamedOption); ok {
+			key = namedOption.Name()
+		} else {
+			// deprecated flags only have names starting with "#"
+			for _, name := range f.Names {
+				if strings.HasPrefix(name, "-") {
+					key = strings.TrimLeft(name, "-")
+				}
+			}
+		}
+		if key != "" && !keys[key] {
+			t.Errorf("flag %v cannot be set in the configuration file, no option named %q", f.Names, key)
+		}
+	})
+}
+
+// collectConfigKeys collects the json names of the fields of a configuration
+// struct, including the ones of its embedded structs.
+func collectConfigKeys(typ reflect.Type, keys map[string]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			collectConfigKeys(field.Type, keys)
+			continue
+		}
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
 }
@@ -13,7 +13,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -73,15 +75,6 @@ import (
 	"golang.org/x/net/context"
 )
 
-const (
-	// maxDownloadConcurrency is the maximum number of downloads that
-	// may take place at a time for each pull.
-	maxDownloadConcurrency = 3
-	// maxUploadConcurrency is the maximum number of uploads that
-	// may take place at a time for each push.
-	maxUploadConcurrency = 5
-)
-
 var (
 	validContainerNameChars   = utils.RestrictedNameChars
 	validContainerNamePattern = utils.RestrictedNamePattern
@@ -107,6 +100,7 @@ type Daemon struct {
 	referenceStore            reference.Store
 	downloadManager           *xfer.LayerDownloadManager
 	uploadManager             *xfer.LayerUploadManager
+	storageGC                 *storageGC
 	distributionMetadataStore dmetadata.Store
 	trustKey                  libtrust.PrivateKey
 	idIndex                   *truncindex.TruncIndex
@@ -120,6 +114,8 @@ type Daemon struct {
 	discoveryWatcher          discoveryReloader
 	root                      string
 	seccompEnabled            bool
+	seccompProfile            []byte
+	seccompProfilePath        string
 	shutdown                  bool
 	uidMaps                   []idtools.IDMap
 	gidMaps                   []idtools.IDMap
@@ -128,7 +124,11 @@ type Daemon struct {
 	nameIndex                 *registrar.Registrar
 	linkIndex                 *linkIndex
 	containerd                libcontainerd.Client
+	containerdRemote          libcontainerd.Remote
 	defaultIsolation          containertypes.Isolation // Default isolation mode on Windows
+	diskUsageRunning          int32
+	initialConfig             map[string]interface{} // configuration the daemon was started with, used to detect changes on reload
+	pruneRunning              int32
 }
 
 // GetContainer looks for a container using the provided information, which could be
@@ -292,13 +292,14 @@ func (daemon *Daemon) restore() error {
 		go func(c *container.Container) {
 			defer wg.Done()
 			rm := c.RestartManager(false)
+			c.SetRestartResetWindow(time.Duration(daemon.configStore.RestartResetWindow) * time.Second)
 			if c.IsRunning() || c.IsPaused() {
 				// Fix activityCount such that graph mounts can be unmounted later
 				if err := daemon.layerStore.ReinitRWLayer(c.RWLayer); err != nil {
 					logrus.Errorf("Failed to ReinitRWLayer for %s due to %s", c.ID, err)
 					return
 				}
-				if err := daemon.containerd.Restore(c.ID, libcontainerd.WithRestartManager(rm)); err != nil {
+				if err := daemon.containerd.Restore(context.Background(), c.ID, libcontainerd.WithRestartManager(rm)); err != nil {
 					logrus.Errorf("Failed to restore with containerd: %q", err)
 					return
 				}
@@ -601,11 +602,22 @@ func (daemon *Daemon) registerLink(parent, child *container.Container, alias str
 // requests from the webserver.
 func NewDaemon(config *Config, registryService *registry.Service, containerdRemote libcontainerd.Remote) (daemon *Daemon, err error) {
 	setDefaultMtu(config)
+	setDefaultMaxConcurrency(config)
+
+	// Keep the configuration as given, before it is modified below,
+	// to tell which options change when the configuration is reloaded
+	initialConfig, err := configToMap(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// Ensure we have compatible and valid configuration options
 	if err := verifyDaemonSettings(config); err != nil {
 		return nil, err
 	}
+	if config.StorageGCThreshold < 0 || config.StorageGCThreshold > 100 {
+		return nil, fmt.Errorf("invalid storage GC threshold: %d, must be between 0 and 100", config.StorageGCThreshold)
+	}
 
 	// Do we have a disabled network?
 	config.DisableBridge = isBridgeNetworkDisabled(config)
@@ -675,6 +687,10 @@ func NewDaemon(config *Config, registryService *registry.Service, containerdRemo
 		return nil, fmt.Errorf("error setting default isolation mode: %v", err)
 	}
 
+	if err := d.setupSeccompProfile(); err != nil {
+		return nil, err
+	}
+
 	// Verify logging driver type
 	if config.LogConfig.Type != "none" {
 		if _, err := logger.GetLogDriver(config.LogConfig.Type); err != nil {
@@ -717,8 +733,8 @@ func NewDaemon(config *Config, registryService *registry.Service, containerdRemo
 		return nil, err
 	}
 
-	d.downloadManager = xfer.NewLayerDownloadManager(d.layerStore, maxDownloadConcurrency)
-	d.uploadManager = xfer.NewLayerUploadManager(maxUploadConcurrency)
+	d.downloadManager = xfer.NewLayerDownloadManager(d.layerStore, config.MaxConcurrentDownloads)
+	d.uploadManager = xfer.NewLayerUploadManager(config.MaxConcurrentUploads)
 
 	ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
 	if err != nil {
@@ -801,6 +817,7 @@ func NewDaemon(config *Config, registryService *registry.Service, containerdRemo
 		Config: config.LogConfig.Config,
 	}
 	d.RegistryService = registryService
+	d.initialConfig = initialConfig
 	d.EventsService = eventsService
 	d.volumes = volStore
 	d.root = config.Root
@@ -813,6 +830,10 @@ func NewDaemon(config *Config, registryService *registry.Service, containerdRemo
 
 	go d.execCommandGC()
 
+	d.storageGC = newStorageGC(config.Root, []string{realTmp, filepath.Join(config.Root, "image", graphDriver, "layerdb", "tmp")}, d.layerStore, config.StorageGCThreshold)
+	go d.storageGC.run()
+
+	d.containerdRemote = containerdRemote
 	d.containerd, err = containerdRemote.Client(d)
 	if err != nil {
 		return nil, err
@@ -864,9 +885,34 @@ func (daemon *Daemon) shutdownContainer(c *container.Container) error {
 	return nil
 }
 
+// containerdDrainTimeout is how long Shutdown waits for libcontainerd to
+// finish in-flight container creations and state notifications.
+const containerdDrainTimeout = 10 * time.Second
+
 // Shutdown stops the daemon.
 func (daemon *Daemon) Shutdown() error {
 	daemon.shutdown = true
+	// Make sure no container is left half-created. Running containers are
+	// stopped below or kept for live restore, so they are not signalled here.
+	if daemon.containerd != nil {
+		if err := daemon.containerd.Drain(context.Background(), containerdDrainTimeout, false); err != nil {
+			logrus.Warnf("Failed to drain containerd client: %v", err)
+		}
+	}
+	// Keep running containers, their mounts and networking untouched so
+	// that the next daemon can restore them.
+	if daemon.liveRestoreEnabled() && daemon.containers != nil {
+		running := false
+		daemon.containers.ApplyAll(func(c *container.Container) {
+			if c.IsRunning() {
+				running = true
+			}
+		})
+		if running {
+			return nil
+		}
+	}
+
 	if daemon.containers != nil {
 		logrus.Debug("starting clean shutdown of all containers...")
 		daemon.containers.ApplyAll(func(c *container.Container) {
@@ -936,7 +982,7 @@ func (daemon *Daemon) Unmount(container *container.Container) error {
 }
 
 func (daemon *Daemon) kill(c *container.Container, sig int) error {
-	return daemon.containerd.Signal(c.ID, sig)
+	return daemon.containerd.Signal(context.Background(), c.ID, sig)
 }
 
 func (daemon *Daemon) subscribeToContainerStats(c *container.Container) chan interface{} {
@@ -984,11 +1030,20 @@ func isBrokenPipe(e error) bool {
 // ExportImage exports a list of images to the given output stream. The
 // exported images are archived into a tar when written to the output
 // stream. All images with the given tag and all versions containing
-// the same tag are exported. names is the set of tags to export, and
-// outStream is the writer which the images are written to.
-func (daemon *Daemon) ExportImage(names []string, outStream io.Writer) error {
+// the same tag are exported. names is the set of tags to export,
+// compression is the compression of the archive, and outStream is the
+// writer which the images are written to.
+func (daemon *Daemon) ExportImage(names []string, compression archive.Compression, outStream io.Writer) error {
+	compressed, err := archive.CompressStream(outStream, compression)
+	if err != nil {
+		return err
+	}
 	imageExporter := tarexport.NewTarExporter(daemon.imageStore, daemon.layerStore, daemon.referenceStore)
-	return imageExporter.Save(names, outStream)
+	if err := imageExporter.Save(names, compressed); err != nil {
+		compressed.Close()
+		return err
+	}
+	return compressed.Close()
 }
 
 // LookupImage looks up an image by name and returns it as an ImageInspect
@@ -1055,13 +1110,75 @@ func (daemon *Daemon) LookupImage(name string) (*types.ImageInspect, error) {
 		RootFS:          rootFSToAPIType(img.RootFS),
 	}
 
+	imageInspect.Layers, err = daemon.imageLayers(img)
+	if err != nil {
+		return nil, err
+	}
+
 	imageInspect.GraphDriver.Name = daemon.GraphDriverName()
 
 	imageInspect.GraphDriver.Data = layerMetadata
 
+	if trust, err := dmetadata.NewTrustService(daemon.distributionMetadataStore).GetMetadata(img.ID()); err == nil {
+		for _, t := range trust {
+			imageInspect.ContentTrust = append(imageInspect.ContentTrust, types.ImageTrust{
+				Repository: t.Repository,
+				RootKeyIDs: t.RootKeyIDs,
+			})
+		}
+	}
+
 	return imageInspect, nil
 }
 
+// imageLayers describes the layers of img, with the compressed digest they
+// were last pulled or pushed with and the history entry that created them.
+func (daemon *Daemon) imageLayers(img *image.Image) ([]types.ImageLayer, error) {
+	if img.RootFS == nil {
+		return nil, nil
+	}
+
+	// the history has an entry for each layer, in order, along with the
+	// entries of the instructions that did not create a layer
+	var createdBy []string
+	for _, h := range img.History {
+		if !h.EmptyLayer {
+			createdBy = append(createdBy, h.CreatedBy)
+		}
+	}
+
+	v2MetadataService := dmetadata.NewV2MetadataService(daemon.distributionMetadataStore)
+	layers := make([]types.ImageLayer, 0, len(img.RootFS.DiffIDs))
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for i, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		l, err := daemon.layerStore.Get(rootFS.ChainID())
+		if err != nil {
+			return nil, err
+		}
+		size, err := l.DiffSize()
+		layer.ReleaseAndLog(daemon.layerStore, l)
+		if err != nil {
+			return nil, err
+		}
+
+		il := types.ImageLayer{
+			DiffID: diffID.String(),
+			Size:   size,
+		}
+		// the most recent metadata comes last
+		if metadata, err := v2MetadataService.GetMetadata(diffID); err == nil && len(metadata) > 0 {
+			il.Digest = metadata[len(metadata)-1].Digest.String()
+		}
+		if len(createdBy) == len(img.RootFS.DiffIDs) {
+			il.CreatedBy = createdBy[i]
+		}
+		layers = append(layers, il)
+	}
+	return layers, nil
+}
+
 // LoadImage uploads a set of images into the repository. This is the
 // complement of ImageExport.  The input stream is an uncompressed tar
 // ball containing images and metadata.
@@ -1196,6 +1313,36 @@ func (daemon *Daemon) GetImageOnBuild(name string) (builder.Image, error) {
 	return img, nil
 }
 
+// MountImage mounts the root filesystem of the image referenced by `name`
+// on a new read-write layer, so that the image itself is never modified.
+// The returned function unmounts and removes that layer.
+func (daemon *Daemon) MountImage(name string) (string, func() error, error) {
+	img, err := daemon.GetImage(name)
+	if err != nil {
+		return "", nil, err
+	}
+	rwLayer, err := daemon.layerStore.CreateRWLayer(stringid.GenerateRandomID(), img.RootFS.ChainID(), "", nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	root, err := rwLayer.Mount("")
+	if err != nil {
+		if _, err := daemon.layerStore.ReleaseRWLayer(rwLayer); err != nil {
+			logrus.Errorf("Error releasing layer of image %s: %v", img.ID(), err)
+		}
+		return "", nil, err
+	}
+	release := func() error {
+		if err := rwLayer.Unmount(); err != nil {
+			return err
+		}
+		metadata, err := daemon.layerStore.ReleaseRWLayer(rwLayer)
+		layer.LogReleaseMetadata(metadata)
+		return err
+	}
+	return root, release, nil
+}
+
 // GraphDriverName returns the name of the graph driver used by the layer.Store
 func (daemon *Daemon) GraphDriverName() string {
 	return daemon.layerStore.DriverName()
@@ -1320,6 +1467,15 @@ func setDefaultMtu(config *Config) {
 	config.Mtu = defaultNetworkMtu
 }
 
+func setDefaultMaxConcurrency(config *Config) {
+	if config.MaxConcurrentDownloads == 0 {
+		config.MaxConcurrentDownloads = defaultMaxConcurrentDownloads
+	}
+	if config.MaxConcurrentUploads == 0 {
+		config.MaxConcurrentUploads = defaultMaxConcurrentUploads
+	}
+}
+
 // verifyContainerSettings performs validation of the hostconfig and config
 // structures.
 func (daemon *Daemon) verifyContainerSettings(hostConfig *containertypes.HostConfig, config *containertypes.Config, update bool) ([]string, error) {
@@ -1339,6 +1495,18 @@ func (daemon *Daemon) verifyContainerSettings(hostConfig *containertypes.HostCon
 				return nil, err
 			}
 		}
+
+		if config.Healthcheck != nil {
+			if config.Healthcheck.Interval < 0 {
+				return nil, fmt.Errorf("Interval in Healthcheck cannot be negative")
+			}
+			if config.Healthcheck.Timeout < 0 {
+				return nil, fmt.Errorf("Timeout in Healthcheck cannot be negative")
+			}
+			if config.Healthcheck.Retries < 0 {
+				return nil, fmt.Errorf("Retries in Healthcheck cannot be negative")
+			}
+		}
 	}
 
 	if hostConfig == nil {
@@ -1501,17 +1669,100 @@ func (daemon *Daemon) initDiscovery(config *Config) error {
 // These are the settings that Reload changes:
 // - Daemon labels.
 // - Daemon debug log level.
+// - Daemon log level.
+// - Insecure registries.
+// - Maximum concurrent downloads and uploads.
+// - Storage garbage collection threshold.
 // - Cluster discovery (reconfigure and restart).
+// The configuration is rejected as a whole if it changes any other setting.
 func (daemon *Daemon) Reload(config *Config) error {
 	daemon.configStore.reloadLock.Lock()
 	defer daemon.configStore.reloadLock.Unlock()
-	if config.IsValueSet("label") {
+
+	if err := daemon.checkReloadable(config); err != nil {
+		return err
+	}
+
+	var reloaded []string
+	if config.IsValueSet("insecure-registries") {
+		if err := daemon.RegistryService.LoadInsecureRegistries(config.InsecureRegistries); err != nil {
+			return err
+		}
+		daemon.configStore.InsecureRegistries = config.InsecureRegistries
+		reloaded = append(reloaded, "insecure-registries")
+	}
+	if config.IsValueSet("labels") {
 		daemon.configStore.Labels = config.Labels
+		reloaded = append(reloaded, "labels")
 	}
 	if config.IsValueSet("debug") {
 		daemon.configStore.Debug = config.Debug
+		reloaded = append(reloaded, "debug")
+	}
+	if config.IsValueSet("log-level") {
+		daemon.configStore.LogLevel = config.LogLevel
+		reloaded = append(reloaded, "log-level")
+	}
+	if config.IsValueSet("max-concurrent-downloads") {
+		daemon.configStore.MaxConcurrentDownloads = config.MaxConcurrentDownloads
+		setDefaultMaxConcurrency(daemon.configStore)
+		if daemon.downloadManager != nil {
+			daemon.downloadManager.SetConcurrency(daemon.configStore.MaxConcurrentDownloads)
+		}
+		reloaded = append(reloaded, "max-concurrent-downloads")
+	}
+	if config.IsValueSet("max-concurrent-uploads") {
+		daemon.configStore.MaxConcurrentUploads = config.MaxConcurrentUploads
+		setDefaultMaxConcurrency(daemon.configStore)
+		if daemon.uploadManager != nil {
+			daemon.uploadManager.SetConcurrency(daemon.configStore.MaxConcurrentUploads)
+		}
+		reloaded = append(reloaded, "max-concurrent-uploads")
+	}
+	if config.IsValueSet("storage-gc-threshold") {
+		daemon.configStore.StorageGCThreshold = config.StorageGCThreshold
+		if daemon.storageGC != nil {
+			daemon.storageGC.SetThreshold(daemon.configStore.StorageGCThreshold)
+		}
+		reloaded = append(reloaded, "storage-gc-threshold")
+	}
+
+	if err := daemon.reloadClusterDiscovery(config); err != nil {
+		return err
+	}
+	for _, key := range []string{"cluster-store", "cluster-store-opts", "cluster-advertise"} {
+		if config.IsValueSet(key) {
+			reloaded = append(reloaded, key)
+		}
 	}
-	return daemon.reloadClusterDiscovery(config)
+
+	logrus.Infof("Reloaded daemon configuration: %s", strings.Join(reloaded, ", "))
+	return nil
+}
+
+// checkReloadable returns an error listing the settings of the new
+// configuration that differ from the ones the daemon was started with,
+// but which cannot be changed without restarting the daemon.
+func (daemon *Daemon) checkReloadable(config *Config) error {
+	newConfig, err := configToMap(config)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for key := range config.valuesSet {
+		if reloadableOptions[key] {
+			continue
+		}
+		if !reflect.DeepEqual(newConfig[key], daemon.initialConfig[key]) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return fmt.Errorf("the following options cannot be changed without restarting the daemon: %s", strings.Join(changed, ", "))
+	}
+	return nil
 }
 
 func (daemon *Daemon) reloadClusterDiscovery(config *Config) error {
@@ -5,14 +5,17 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/pkg/discovery"
 	_ "github.com/docker/docker/pkg/discovery/memory"
 	"github.com/docker/docker/pkg/registrar"
 	"github.com/docker/docker/pkg/truncindex"
+	"github.com/docker/docker/registry"
 	"github.com/docker/docker/volume"
 	volumedrivers "github.com/docker/docker/volume/drivers"
 	"github.com/docker/docker/volume/local"
@@ -320,7 +323,7 @@ func TestDaemonReloadLabels(t *testing.T) {
 	}
 
 	valuesSets := make(map[string]interface{})
-	valuesSets["label"] = "foo:baz"
+	valuesSets["labels"] = "foo:baz"
 	newConfig := &Config{
 		CommonConfig: CommonConfig{
 			Labels:    []string{"foo:baz"},
@@ -345,7 +348,7 @@ func TestDaemonReloadNotAffectOthers(t *testing.T) {
 	}
 
 	valuesSets := make(map[string]interface{})
-	valuesSets["label"] = "foo:baz"
+	valuesSets["labels"] = "foo:baz"
 	newConfig := &Config{
 		CommonConfig: CommonConfig{
 			Labels:    []string{"foo:baz"},
@@ -364,6 +367,92 @@ func TestDaemonReloadNotAffectOthers(t *testing.T) {
 	}
 }
 
+func TestDaemonReloadRejectsNonReloadable(t *testing.T) {
+	daemon := &Daemon{}
+	daemon.configStore = &Config{
+		CommonConfig: CommonConfig{
+			Labels: []string{"foo:bar"},
+			Root:   "/var/lib/docker",
+		},
+	}
+	initialConfig, err := configToMap(daemon.configStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon.initialConfig = initialConfig
+
+	valuesSets := make(map[string]interface{})
+	valuesSets["labels"] = "foo:baz"
+	valuesSets["graph"] = "/var/lib/docker"
+	newConfig := &Config{
+		CommonConfig: CommonConfig{
+			Labels:    []string{"foo:baz"},
+			Root:      "/var/lib/docker",
+			valuesSet: valuesSets,
+		},
+	}
+	if err := daemon.Reload(newConfig); err != nil {
+		t.Fatalf("Expected unchanged graph option to be accepted, got %v", err)
+	}
+
+	valuesSets["labels"] = "foo:qux"
+	valuesSets["graph"] = "/srv/docker"
+	newConfig = &Config{
+		CommonConfig: CommonConfig{
+			Labels:    []string{"foo:qux"},
+			Root:      "/srv/docker",
+			valuesSet: valuesSets,
+		},
+	}
+	err = daemon.Reload(newConfig)
+	if err == nil || !strings.Contains(err.Error(), "graph") {
+		t.Fatalf("Expected reload of graph option to be rejected, got %v", err)
+	}
+	if label := daemon.configStore.Labels[0]; label != "foo:baz" {
+		t.Fatalf("Expected daemon label `foo:baz` to be kept, got %s", label)
+	}
+}
+
+func TestDaemonReloadRegistryAndConcurrency(t *testing.T) {
+	daemon := &Daemon{
+		RegistryService: registry.NewService(registry.ServiceOptions{}),
+		downloadManager: xfer.NewLayerDownloadManager(nil, defaultMaxConcurrentDownloads),
+		uploadManager:   xfer.NewLayerUploadManager(defaultMaxConcurrentUploads),
+	}
+	daemon.configStore = &Config{}
+
+	valuesSets := make(map[string]interface{})
+	valuesSets["insecure-registries"] = []interface{}{"example.com:5000"}
+	valuesSets["max-concurrent-downloads"] = 10
+	valuesSets["max-concurrent-uploads"] = 0
+	newConfig := &Config{
+		CommonConfig: CommonConfig{
+			MaxConcurrentDownloads: 10,
+			ServiceOptions: registry.ServiceOptions{
+				InsecureRegistries: []string{"example.com:5000"},
+			},
+			valuesSet: valuesSets,
+		},
+	}
+	if err := daemon.Reload(newConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := daemon.RegistryService.ResolveIndex("example.com:5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Secure {
+		t.Fatal("Expected example.com:5000 to be an insecure registry")
+	}
+	if daemon.configStore.MaxConcurrentDownloads != 10 {
+		t.Fatalf("Expected 10 concurrent downloads, got %d", daemon.configStore.MaxConcurrentDownloads)
+	}
+	if daemon.configStore.MaxConcurrentUploads != defaultMaxConcurrentUploads {
+		t.Fatalf("Expected the default of %d concurrent uploads, got %d", defaultMaxConcurrentUploads, daemon.configStore.MaxConcurrentUploads)
+	}
+}
+
 func TestDaemonDiscoveryReload(t *testing.T) {
 	daemon := &Daemon{}
 	daemon.configStore = &Config{
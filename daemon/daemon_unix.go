@@ -3,6 +3,7 @@
 package daemon
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -39,6 +40,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/label"
 	"github.com/opencontainers/runc/libcontainer/user"
 	"github.com/opencontainers/specs/specs-go"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -55,6 +57,13 @@ const (
 	// constant for cgroup drivers
 	cgroupFsDriver      = "cgroupfs"
 	cgroupSystemdDriver = "systemd"
+
+	// DefaultRuntimeBinary is the default runtime to be used by
+	// containerd if none is specified
+	DefaultRuntimeBinary = "docker-runc"
+
+	// stockRuntimeName is the name the default runtime is registered under
+	stockRuntimeName = "runc"
 )
 
 func getMemoryResources(config containertypes.Resources) *specs.Memory {
@@ -296,6 +305,9 @@ func (daemon *Daemon) adaptContainerSettings(hostConfig *containertypes.HostConf
 	if hostConfig.ShmSize == 0 {
 		hostConfig.ShmSize = container.DefaultSHMSize
 	}
+	if hostConfig.Runtime == "" {
+		hostConfig.Runtime = daemon.configStore.DefaultRuntime
+	}
 	var err error
 	if hostConfig.SecurityOpt == nil {
 		hostConfig.SecurityOpt, err = daemon.generateSecurityOpt(hostConfig.IpcMode, hostConfig.PidMode)
@@ -550,6 +562,9 @@ func verifyPlatformContainerSettings(daemon *Daemon, hostConfig *containertypes.
 			return warnings, fmt.Errorf("Cannot use the --read-only option when user namespaces are enabled")
 		}
 	}
+	if hostConfig.Runtime != "" && daemon.getRuntime(hostConfig.Runtime) == nil {
+		return warnings, fmt.Errorf("Unknown runtime specified %s", hostConfig.Runtime)
+	}
 	if hostConfig.CgroupParent != "" && UsingSystemd(daemon.configStore) {
 		// CgroupParent for systemd cgroup should be named as "xxx.slice"
 		if len(hostConfig.CgroupParent) <= 6 || !strings.HasSuffix(hostConfig.CgroupParent, ".slice") {
@@ -579,6 +594,17 @@ func verifyDaemonSettings(config *Config) error {
 			return fmt.Errorf("cgroup-parent for systemd cgroup should be a valid slice named as \"xxx.slice\"")
 		}
 	}
+
+	if config.DefaultRuntime == "" {
+		config.DefaultRuntime = stockRuntimeName
+	}
+	if config.Runtimes == nil {
+		config.Runtimes = make(map[string]types.Runtime)
+	}
+	config.Runtimes[stockRuntimeName] = types.Runtime{Path: DefaultRuntimeBinary}
+	if _, ok := config.Runtimes[config.DefaultRuntime]; !ok {
+		return fmt.Errorf("default runtime %s is not registered, use --add-runtime to register it", config.DefaultRuntime)
+	}
 	return nil
 }
 
@@ -925,7 +951,7 @@ func parseRemappedRoot(usergrp string) (string, string, error) {
 			groupID = int(gid)
 			lgrp, err := user.LookupGid(groupID)
 			if err != nil {
-				return "", "", fmt.Errorf("Gid %d has no entry in /etc/passwd: %v", groupID, err)
+				return "", "", fmt.Errorf("Gid %d has no entry in /etc/group: %v", groupID, err)
 			}
 			groupname = lgrp.Name
 		} else {
@@ -1064,7 +1090,7 @@ func (daemon *Daemon) stats(c *container.Container) (*types.StatsJSON, error) {
 	if !c.IsRunning() {
 		return nil, errNotRunning{c.ID}
 	}
-	stats, err := daemon.containerd.Stats(c.ID)
+	stats, err := daemon.containerd.Stats(context.Background(), c.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -1123,6 +1149,26 @@ func (daemon *Daemon) setDefaultIsolation() error {
 	return nil
 }
 
+// setupSeccompProfile loads the seccomp profile given with --seccomp-profile,
+// which replaces the built-in default profile for all containers.
+func (daemon *Daemon) setupSeccompProfile() error {
+	path := daemon.configStore.SeccompProfile
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("opening seccomp profile (%s) failed: %v", path, err)
+	}
+	var profile types.Seccomp
+	if err := json.Unmarshal(b, &profile); err != nil {
+		return fmt.Errorf("decoding seccomp profile (%s) failed: %v", path, err)
+	}
+	daemon.seccompProfile = b
+	daemon.seccompProfilePath = path
+	return nil
+}
+
 func rootFSToAPIType(rootfs *image.RootFS) types.RootFS {
 	var layers []string
 	for _, l := range rootfs.DiffIDs {
@@ -1133,3 +1179,22 @@ func rootFSToAPIType(rootfs *image.RootFS) types.RootFS {
 		Layers: layers,
 	}
 }
+
+// liveRestoreEnabled returns true if containers are kept running while the
+// daemon is down.
+func (daemon *Daemon) liveRestoreEnabled() bool {
+	return daemon.configStore.LiveRestore
+}
+
+// getRuntime returns the runtime registered under the given name, or the
+// default runtime if the name is empty.
+func (daemon *Daemon) getRuntime(name string) *types.Runtime {
+	if name == "" {
+		name = daemon.configStore.DefaultRuntime
+	}
+	rt, ok := daemon.configStore.Runtimes[name]
+	if !ok {
+		return nil
+	}
+	return &rt
+}
@@ -5,6 +5,7 @@ package daemon
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/docker/docker/container"
@@ -19,8 +20,9 @@ func TestAdjustCPUShares(t *testing.T) {
 	}
 	defer os.RemoveAll(tmp)
 	daemon := &Daemon{
-		repository: tmp,
-		root:       tmp,
+		repository:  tmp,
+		root:        tmp,
+		configStore: &Config{},
 	}
 
 	hostConfig := &containertypes.HostConfig{
@@ -58,8 +60,9 @@ func TestAdjustCPUSharesNoAdjustment(t *testing.T) {
 	}
 	defer os.RemoveAll(tmp)
 	daemon := &Daemon{
-		repository: tmp,
-		root:       tmp,
+		repository:  tmp,
+		root:        tmp,
+		configStore: &Config{},
 	}
 
 	hostConfig := &containertypes.HostConfig{
@@ -197,3 +200,69 @@ func TestNetworkOptions(t *testing.T) {
 		t.Fatalf("Expected networkOptions error, got nil")
 	}
 }
+
+func TestSetupSeccompProfile(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-daemon-seccomp-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	daemon := &Daemon{configStore: &Config{}}
+	if err := daemon.setupSeccompProfile(); err != nil {
+		t.Fatalf("Expected no error without a profile, got %v", err)
+	}
+	if daemon.seccompProfile != nil {
+		t.Fatal("Expected no seccomp profile to be loaded")
+	}
+
+	valid := filepath.Join(tmp, "valid.json")
+	if err := ioutil.WriteFile(valid, []byte(`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	daemon.configStore.SeccompProfile = valid
+	if err := daemon.setupSeccompProfile(); err != nil {
+		t.Fatalf("Expected valid profile to load, got %v", err)
+	}
+	if daemon.seccompProfilePath != valid || len(daemon.seccompProfile) == 0 {
+		t.Fatalf("Expected profile %s to be loaded", valid)
+	}
+
+	invalid := filepath.Join(tmp, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte(`{"defaultAction":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	daemon.configStore.SeccompProfile = invalid
+	if err := daemon.setupSeccompProfile(); err == nil {
+		t.Fatal("Expected error for an invalid profile, got nil")
+	}
+
+	daemon.configStore.SeccompProfile = filepath.Join(tmp, "missing.json")
+	if err := daemon.setupSeccompProfile(); err == nil {
+		t.Fatal("Expected error for a missing profile, got nil")
+	}
+}
+
+func TestParseRemappedRoot(t *testing.T) {
+	valid := map[string][2]string{
+		"root":   {"root", "root"},
+		"0":      {"root", "root"},
+		"0:0":    {"root", "root"},
+		"root:0": {"root", "root"},
+	}
+	for spec, expected := range valid {
+		username, groupname, err := parseRemappedRoot(spec)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", spec, err)
+		}
+		if username != expected[0] || groupname != expected[1] {
+			t.Fatalf("Expected %q to resolve to %s:%s, got %s:%s", spec, expected[0], expected[1], username, groupname)
+		}
+	}
+
+	for _, spec := range []string{"root:root:root", "no-such-user-for-docker-test", "root:no-such-group-for-docker-test"} {
+		if _, _, err := parseRemappedRoot(spec); err == nil {
+			t.Fatalf("Expected %q to fail to parse", spec)
+		}
+	}
+}
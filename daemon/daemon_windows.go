@@ -438,6 +438,11 @@ func (daemon *Daemon) setDefaultIsolation() error {
 	return nil
 }
 
+// setupSeccompProfile is a no-op on Windows, which has no seccomp.
+func (daemon *Daemon) setupSeccompProfile() error {
+	return nil
+}
+
 func rootFSToAPIType(rootfs *image.RootFS) types.RootFS {
 	var layers []string
 	for _, l := range rootfs.DiffIDs {
@@ -449,3 +454,9 @@ func rootFSToAPIType(rootfs *image.RootFS) types.RootFS {
 		BaseLayer: rootfs.BaseLayer,
 	}
 }
+
+// liveRestoreEnabled returns true if containers are kept running while the
+// daemon is down. This is not supported on Windows.
+func (daemon *Daemon) liveRestoreEnabled() bool {
+	return false
+}
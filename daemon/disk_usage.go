@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/errors"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/engine-api/types"
+)
+
+// SystemDiskUsage returns information about the disk space used by the
+// daemon's images, containers and volumes.
+func (daemon *Daemon) SystemDiskUsage() (*types.DiskUsage, error) {
+	if !atomic.CompareAndSwapInt32(&daemon.diskUsageRunning, 0, 1) {
+		return nil, errors.NewRequestConflictError(fmt.Errorf("a disk usage operation is already running"))
+	}
+	defer atomic.StoreInt32(&daemon.diskUsageRunning, 0)
+
+	// Retrieve container list
+	allContainers, err := daemon.Containers(&types.ContainerListOptions{
+		Size: true,
+		All:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve container list: %v", err)
+	}
+
+	// Get all top images
+	allImages, err := daemon.Images("", "", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve image list: %v", err)
+	}
+
+	imageContainers := make(map[string]int64)
+	for _, c := range allContainers {
+		imageContainers[c.ImageID]++
+	}
+
+	// Find the layer chain of every image and count how many of the images
+	// use each layer.
+	allLayers := daemon.layerStore.Map()
+	imageLayers := make(map[string][]layer.Layer, len(allImages))
+	layerRefs := make(map[layer.ChainID]int)
+	for _, i := range allImages {
+		img, err := daemon.imageStore.Get(image.ID(i.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve image %s: %v", i.ID, err)
+		}
+		for l := allLayers[img.RootFS.ChainID()]; l != nil; l = l.Parent() {
+			imageLayers[i.ID] = append(imageLayers[i.ID], l)
+			layerRefs[l.ChainID()]++
+		}
+	}
+
+	for _, i := range allImages {
+		var sharedSize int64
+		for _, l := range imageLayers[i.ID] {
+			if layerRefs[l.ChainID()] < 2 {
+				continue
+			}
+			size, err := l.DiffSize()
+			if err != nil {
+				return nil, err
+			}
+			sharedSize += size
+		}
+		i.SharedSize = sharedSize
+		i.Containers = imageContainers[i.ID]
+	}
+
+	var layersSize int64
+	for _, l := range allLayers {
+		size, err := l.DiffSize()
+		if err != nil {
+			return nil, err
+		}
+		layersSize += size
+	}
+
+	// Get all local volumes
+	allVolumes := []*types.Volume{}
+	volumes, warnings, err := daemon.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		logrus.Warnf("disk usage: %s", w)
+	}
+	for _, v := range volumes {
+		size, err := daemon.volumes.Size(v)
+		if err != nil {
+			logrus.Warnf("failed to determine size of volume %v: %v", v.Name(), err)
+		}
+		tv := volumeToAPIType(v)
+		tv.UsageData = &types.VolumeUsageData{
+			Size:     size,
+			RefCount: len(daemon.volumes.Refs(v)),
+		}
+		allVolumes = append(allVolumes, tv)
+	}
+
+	return &types.DiskUsage{
+		LayersSize: layersSize,
+		Images:     allImages,
+		Containers: allContainers,
+		Volumes:    allVolumes,
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/errors"
+	"github.com/docker/docker/libcontainerd"
 	"github.com/docker/docker/reference"
 )
 
@@ -55,3 +56,16 @@ func errExecPaused(id string) error {
 	err := fmt.Errorf("Container %s is paused, unpause the container before exec", id)
 	return errors.NewRequestConflictError(err)
 }
+
+// containerdErrToErrcode prefixes a libcontainerd error with the given
+// message and maps its type to an API error with the matching status code.
+func containerdErrToErrcode(err error, format string, args ...interface{}) error {
+	e := fmt.Errorf("%s: %s", fmt.Sprintf(format, args...), err)
+	switch {
+	case libcontainerd.IsNotFound(err):
+		return errors.NewRequestNotFoundError(e)
+	case libcontainerd.IsConflict(err):
+		return errors.NewRequestConflictError(e)
+	}
+	return e
+}
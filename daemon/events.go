@@ -77,6 +77,18 @@ func (daemon *Daemon) LogNetworkEventWithAttributes(nw libnetwork.Network, actio
 	daemon.EventsService.Log(action, events.NetworkEventType, actor)
 }
 
+// daemonEventType is the event type that the daemon itself generates.
+const daemonEventType = "daemon"
+
+// LogDaemonEvent generates an event related to the daemon itself.
+func (daemon *Daemon) LogDaemonEvent(action string) {
+	actor := events.Actor{
+		ID:         daemon.ID,
+		Attributes: map[string]string{},
+	}
+	daemon.EventsService.Log(action, daemonEventType, actor)
+}
+
 // copyAttributes guarantees that labels are not mutated by event triggers.
 func copyAttributes(attributes, labels map[string]string) {
 	if labels == nil {
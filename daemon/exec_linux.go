@@ -5,6 +5,7 @@ import (
 	"github.com/docker/docker/daemon/caps"
 	"github.com/docker/docker/daemon/exec"
 	"github.com/docker/docker/libcontainerd"
+	"github.com/opencontainers/runc/libcontainer/apparmor"
 )
 
 func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainerd.Process) error {
@@ -21,6 +22,12 @@ func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainer
 	}
 	if ec.Privileged {
 		p.Capabilities = caps.GetAllCapabilities()
+		// Like for privileged containers, only an explicitly requested
+		// AppArmor profile is kept.
+		if apparmor.IsEnabled() && len(c.AppArmorProfile) == 0 {
+			unconfined := "unconfined"
+			p.ApparmorProfile = &unconfined
+		}
 	}
 	return nil
 }
@@ -2,9 +2,12 @@ package daemon
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/container"
+	dmetadata "github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/errors"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
@@ -288,6 +291,9 @@ func (daemon *Daemon) imageDeleteHelper(imgID image.ID, records *[]types.ImageDe
 	if err != nil {
 		return err
 	}
+	if err := dmetadata.NewTrustService(daemon.distributionMetadataStore).Remove(imgID); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("Failed to remove the content trust data of %s: %v", imgID, err)
+	}
 
 	daemon.LogImageEvent(imgID.String(), imgID.String(), "delete")
 	*records = append(*records, types.ImageDelete{Deleted: imgID.String()})
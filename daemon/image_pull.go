@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"io"
+	"path/filepath"
 	"strings"
 
 	"github.com/docker/distribution/digest"
@@ -98,8 +99,21 @@ func (daemon *Daemon) pullImageWithReference(ctx context.Context, ref reference.
 		ReferenceStore:   daemon.referenceStore,
 		DownloadManager:  daemon.downloadManager,
 	}
+	if daemon.configStore.ContentTrust {
+		imagePullConfig.ContentTrust = &distribution.ContentTrustConfig{
+			Server:   daemon.configStore.ContentTrustServer,
+			TrustDir: filepath.Join(daemon.root, "trust"),
+		}
+	}
+	if daemon.configStore.DeltaServer != "" {
+		imagePullConfig.Delta = &distribution.DeltaConfig{
+			Server:     daemon.configStore.DeltaServer,
+			LayerStore: daemon.layerStore,
+		}
+	}
 
 	err := distribution.Pull(ctx, ref, imagePullConfig)
+	imagePulls.Inc(metricsResult(err))
 	close(progressChan)
 	<-writesDone
 	return err
@@ -56,3 +56,43 @@ func (daemon *Daemon) PushImage(ctx context.Context, image, tag string, metaHead
 	<-writesDone
 	return err
 }
+
+// PushManifestList creates a manifest list from images of the remote
+// repository named name, and pushes it to the repository with tag.
+func (daemon *Daemon) PushManifestList(ctx context.Context, name, tag string, images []types.ManifestListEntry, metaHeaders map[string][]string, authConfig *types.AuthConfig, outStream io.Writer) error {
+	ref, err := reference.ParseNamed(name)
+	if err != nil {
+		return err
+	}
+	if tag == "" {
+		tag = reference.DefaultTag
+	}
+	taggedRef, err := reference.WithTag(ref, tag)
+	if err != nil {
+		return err
+	}
+
+	progressChan := make(chan progress.Progress, 100)
+
+	writesDone := make(chan struct{})
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+
+	go func() {
+		writeDistributionProgress(cancelFunc, outStream, progressChan)
+		close(writesDone)
+	}()
+
+	manifestListPushConfig := &distribution.ManifestListPushConfig{
+		MetaHeaders:      metaHeaders,
+		AuthConfig:       authConfig,
+		ProgressOutput:   progress.ChanOutput(progressChan),
+		RegistryService:  daemon.RegistryService,
+		ImageEventLogger: daemon.LogImageEvent,
+	}
+
+	err = distribution.PushManifestList(ctx, taggedRef, images, manifestListPushConfig)
+	close(progressChan)
+	<-writesDone
+	return err
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"os"
 	"runtime"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -125,9 +126,34 @@ func (daemon *Daemon) SystemInfo() (*types.Info, error) {
 		v.Name = hostname
 	}
 
+	v.SystemStatus = append(v.SystemStatus, daemon.containerdStatus()...)
+
 	return v, nil
 }
 
+// containerdStatus returns the status of containerd in a form suitable for
+// the SystemStatus of docker info. Nothing is reported on platforms
+// without a remote containerd.
+func (daemon *Daemon) containerdStatus() [][2]string {
+	if daemon.containerdRemote == nil {
+		return nil
+	}
+	st := daemon.containerdRemote.Status()
+	if st.ConnectionState == "" {
+		return nil
+	}
+	version := st.Version
+	if version == "" {
+		version = "<unknown>"
+	}
+	return [][2]string{
+		{"Containerd", ""},
+		{" Version", version},
+		{" Connection State", st.ConnectionState},
+		{" Event Backlog", strconv.Itoa(st.EventBacklog)},
+	}
+}
+
 // SystemVersion returns version information about the daemon.
 func (daemon *Daemon) SystemVersion() types.Version {
 	v := types.Version{
@@ -132,6 +132,18 @@ func (daemon *Daemon) getInspectData(container *container.Container, size bool)
 		FinishedAt: container.State.FinishedAt.Format(time.RFC3339Nano),
 	}
 
+	if next := container.NextRestart(); container.State.Restarting && !next.IsZero() {
+		containerState.NextRestartAt = next.UTC().Format(time.RFC3339Nano)
+	}
+
+	if h := container.State.Health; h != nil {
+		containerState.Health = &types.Health{
+			Status:        h.Status,
+			FailingStreak: h.FailingStreak,
+			Log:           append([]*types.HealthcheckResult{}, h.Log...),
+		}
+	}
+
 	contJSONBase := &types.ContainerJSONBase{
 		ID:           container.ID,
 		Created:      container.Created.Format(time.RFC3339Nano),
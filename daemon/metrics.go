@@ -0,0 +1,17 @@
+package daemon
+
+import "github.com/docker/docker/pkg/metrics"
+
+var (
+	containerActions = metrics.NewCounter("docker_daemon_container_actions_total", "The number of container lifecycle changes, by action.", "action")
+	imagePulls       = metrics.NewCounter("docker_daemon_image_pulls_total", "The number of image pulls, by result.", "result")
+)
+
+// metricsResult returns the result label used for operations that either
+// succeed or fail.
+func metricsResult(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/libcontainerd"
@@ -25,6 +26,8 @@ func (daemon *Daemon) StateChanged(id string, e libcontainerd.StateInfo) error {
 		if runtime.GOOS == "windows" {
 			return errors.New("Received StateOOM from libcontainerd on Windows. This should never happen.")
 		}
+		// OOMKilled itself is set when the exit of the container is handled,
+		// libcontainerd reports it along with StateExit.
 		daemon.LogContainerEvent(c, "oom")
 	case libcontainerd.StateExit:
 		c.Lock()
@@ -32,10 +35,12 @@ func (daemon *Daemon) StateChanged(id string, e libcontainerd.StateInfo) error {
 		c.Wait()
 		c.Reset(false)
 		c.SetStopped(platformConstructExitStatus(e))
+		daemon.updateHealthMonitor(c)
 		attributes := map[string]string{
 			"exitCode": strconv.Itoa(int(e.ExitCode)),
 		}
 		daemon.LogContainerEventWithAttributes(c, "die", attributes)
+		containerActions.Inc("stop")
 		daemon.Cleanup(c)
 		// FIXME: here is race condition between two RUN instructions in Dockerfile
 		// because they share same runconfig and change image. Must be fixed
@@ -50,10 +55,12 @@ func (daemon *Daemon) StateChanged(id string, e libcontainerd.StateInfo) error {
 		c.Reset(false)
 		c.RestartCount++
 		c.SetRestarting(platformConstructExitStatus(e))
+		daemon.stopHealthchecks(c)
 		attributes := map[string]string{
 			"exitCode": strconv.Itoa(int(e.ExitCode)),
 		}
 		daemon.LogContainerEventWithAttributes(c, "die", attributes)
+		containerActions.Inc("restart")
 		if err := c.ToDisk(); err != nil {
 			return err
 		}
@@ -73,22 +80,38 @@ func (daemon *Daemon) StateChanged(id string, e libcontainerd.StateInfo) error {
 			// remove the exec command from the container's store only and not the
 			// daemon's store so that the exec command can be inspected.
 			c.ExecCommands.Delete(execConfig.ID)
+			execConfig.CanRemove = true
+			attributes := map[string]string{
+				"execID":   execConfig.ID,
+				"exitCode": strconv.Itoa(ec),
+			}
+			daemon.LogContainerEventWithAttributes(c, "exec_die", attributes)
 		} else {
 			logrus.Warnf("Ignoring StateExitProcess for %v but no exec command found", e)
 		}
+	case libcontainerd.StateStartProcess:
+		if execConfig := c.ExecCommands.Get(e.ProcessID); execConfig != nil {
+			daemon.LogContainerEvent(c, "exec_start: "+execConfig.Entrypoint+" "+strings.Join(execConfig.Args, " "))
+		} else {
+			logrus.Warnf("Ignoring StateStartProcess for %v but no exec command found", e)
+		}
 	case libcontainerd.StateStart, libcontainerd.StateRestore:
 		c.SetRunning(int(e.Pid), e.State == libcontainerd.StateStart)
 		c.HasBeenManuallyStopped = false
+		daemon.initHealthMonitor(c)
 		if err := c.ToDisk(); err != nil {
 			c.Reset(false)
 			return err
 		}
 		daemon.LogContainerEvent(c, "start")
+		containerActions.Inc("start")
 	case libcontainerd.StatePause:
 		c.Paused = true
+		daemon.updateHealthMonitor(c)
 		daemon.LogContainerEvent(c, "pause")
 	case libcontainerd.StateResume:
 		c.Paused = false
+		daemon.updateHealthMonitor(c)
 		daemon.LogContainerEvent(c, "unpause")
 	}
 
@@ -148,3 +171,9 @@ func (daemon *Daemon) AttachStreams(id string, iop libcontainerd.IOPipe) error {
 
 	return nil
 }
+
+// ConnectionChanged is called by libcontainerd when the connection to
+// containerd is lost or restored.
+func (daemon *Daemon) ConnectionChanged(state string) {
+	daemon.LogDaemonEvent(state)
+}
@@ -264,6 +264,9 @@ func setNamespaces(daemon *Daemon, s *specs.Spec, c *container.Container) error
 			ns.Path = fmt.Sprintf("/proc/%d/ns/net", nc.State.GetPID())
 			if userNS {
 				// to share a net namespace, they must also share a user namespace
+				if nc.HostConfig.UsernsMode.IsHost() {
+					return fmt.Errorf("Cannot join the network namespace of container %s, it uses the host's user namespace", nc.ID)
+				}
 				nsUser := specs.Namespace{Type: "user"}
 				nsUser.Path = fmt.Sprintf("/proc/%d/ns/user", nc.State.GetPID())
 				setNamespace(s, nsUser)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/docker/docker/container"
+	"golang.org/x/net/context"
 )
 
 // ContainerPause pauses a container
@@ -41,8 +42,8 @@ func (daemon *Daemon) containerPause(container *container.Container) error {
 		return errContainerIsRestarting(container.ID)
 	}
 
-	if err := daemon.containerd.Pause(container.ID); err != nil {
-		return fmt.Errorf("Cannot pause container %s: %s", container.ID, err)
+	if err := daemon.containerd.Pause(context.Background(), container.ID); err != nil {
+		return containerdErrToErrcode(err, "Cannot pause container %s", container.ID)
 	}
 
 	return nil
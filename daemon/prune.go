@@ -0,0 +1,306 @@
+package daemon
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/errors"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/runconfig"
+	volumestore "github.com/docker/docker/volume/store"
+	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/filters"
+	timetypes "github.com/docker/engine-api/types/time"
+	"github.com/docker/libnetwork"
+)
+
+var (
+	containersAcceptedFilters = map[string]bool{
+		"label": true,
+		"until": true,
+	}
+	imagesAcceptedFilters = map[string]bool{
+		"dangling": true,
+		"label":    true,
+		"until":    true,
+	}
+	volumesAcceptedFilters = map[string]bool{
+		"label": true,
+	}
+	networksAcceptedFilters = map[string]bool{
+		"label": true,
+	}
+)
+
+// startPrune makes sure only one prune operation runs at a time. The
+// returned function must be called once the operation is done.
+func (daemon *Daemon) startPrune() (func(), error) {
+	if !atomic.CompareAndSwapInt32(&daemon.pruneRunning, 0, 1) {
+		return nil, errors.NewRequestConflictError(fmt.Errorf("a prune operation is already running"))
+	}
+	return func() { atomic.StoreInt32(&daemon.pruneRunning, 0) }, nil
+}
+
+// ContainersPrune removes the stopped containers matching the given filters.
+func (daemon *Daemon) ContainersPrune(pruneFilters filters.Args) (*types.ContainersPruneReport, error) {
+	if err := pruneFilters.Validate(containersAcceptedFilters); err != nil {
+		return nil, err
+	}
+	until, err := getUntilFromPruneFilters(pruneFilters)
+	if err != nil {
+		return nil, err
+	}
+	done, err := daemon.startPrune()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rep := &types.ContainersPruneReport{}
+	for _, c := range daemon.List() {
+		if c.IsRunning() {
+			continue
+		}
+		if !until.IsZero() && c.Created.After(until) {
+			continue
+		}
+		if !pruneFilters.MatchKVList("label", c.Config.Labels) {
+			continue
+		}
+		cSize, _ := daemon.getSize(c)
+		// ContainerRm refuses to remove a container that was started
+		// in the meantime, since ForceRemove is not set.
+		if err := daemon.ContainerRm(c.ID, &types.ContainerRmConfig{}); err != nil {
+			logrus.Warnf("failed to prune container %s: %v", c.ID, err)
+			continue
+		}
+		if cSize > 0 {
+			rep.SpaceReclaimed += uint64(cSize)
+		}
+		rep.ContainersDeleted = append(rep.ContainersDeleted, c.ID)
+	}
+
+	return rep, nil
+}
+
+// VolumesPrune removes the volumes matching the given filters which are not
+// referenced by any container.
+func (daemon *Daemon) VolumesPrune(pruneFilters filters.Args) (*types.VolumesPruneReport, error) {
+	if err := pruneFilters.Validate(volumesAcceptedFilters); err != nil {
+		return nil, err
+	}
+	done, err := daemon.startPrune()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	vols, warnings, err := daemon.volumes.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		logrus.Warnf("volume prune: %s", w)
+	}
+
+	rep := &types.VolumesPruneReport{}
+	for _, v := range daemon.volumes.FilterByUsed(vols, false) {
+		if !pruneFilters.MatchKVList("label", volumeToAPIType(v).Labels) {
+			continue
+		}
+		vSize, err := daemon.volumes.Size(v)
+		if err != nil {
+			logrus.Warnf("could not determine size of volume %s: %v", v.Name(), err)
+		}
+		// The volume store refuses to remove a volume that got a
+		// reference in the meantime.
+		if err := daemon.volumes.Remove(v); err != nil {
+			if !volumestore.IsInUse(err) {
+				logrus.Warnf("failed to prune volume %s: %v", v.Name(), err)
+			}
+			continue
+		}
+		daemon.LogVolumeEvent(v.Name(), "destroy", map[string]string{"driver": v.DriverName()})
+		if vSize > 0 {
+			rep.SpaceReclaimed += uint64(vSize)
+		}
+		rep.VolumesDeleted = append(rep.VolumesDeleted, v.Name())
+	}
+
+	return rep, nil
+}
+
+// ImagesPrune removes the images matching the given filters which are not
+// used by any container. Unless the "dangling" filter is set to false, only
+// untagged images without children are removed.
+func (daemon *Daemon) ImagesPrune(pruneFilters filters.Args) (*types.ImagesPruneReport, error) {
+	if err := pruneFilters.Validate(imagesAcceptedFilters); err != nil {
+		return nil, err
+	}
+	danglingOnly := true
+	if pruneFilters.Include("dangling") {
+		if pruneFilters.ExactMatch("dangling", "false") || pruneFilters.ExactMatch("dangling", "0") {
+			danglingOnly = false
+		} else if !pruneFilters.ExactMatch("dangling", "true") && !pruneFilters.ExactMatch("dangling", "1") {
+			return nil, fmt.Errorf("Invalid filter 'dangling=%s'", pruneFilters.Get("dangling"))
+		}
+	}
+	until, err := getUntilFromPruneFilters(pruneFilters)
+	if err != nil {
+		return nil, err
+	}
+	done, err := daemon.startPrune()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var allImages map[image.ID]*image.Image
+	if danglingOnly {
+		allImages = daemon.imageStore.Heads()
+	} else {
+		allImages = daemon.imageStore.Map()
+	}
+
+	usedImages := make(map[image.ID]bool)
+	for _, c := range daemon.List() {
+		usedImages[c.ImageID] = true
+	}
+
+	// Remember the size of every layer, so that the space reclaimed can be
+	// computed from the layers which are gone afterwards.
+	layerSizes := make(map[layer.ChainID]int64)
+	for chainID, l := range daemon.layerStore.Map() {
+		size, err := l.DiffSize()
+		if err != nil {
+			return nil, err
+		}
+		layerSizes[chainID] = size
+	}
+
+	rep := &types.ImagesPruneReport{}
+	for id, img := range allImages {
+		refs := daemon.referenceStore.References(id)
+		if danglingOnly && len(refs) > 0 {
+			continue
+		}
+		// Intermediate images are removed along with their children.
+		if len(refs) == 0 && len(daemon.imageStore.Children(id)) > 0 {
+			continue
+		}
+		if usedImages[id] {
+			continue
+		}
+		if !until.IsZero() && img.Created.After(until) {
+			continue
+		}
+		if !pruneFilters.MatchKVList("label", imageLabels(img)) {
+			continue
+		}
+
+		// ImageDelete refuses to remove an image which got used by a
+		// container in the meantime, since force is not set.
+		if len(refs) == 0 {
+			records, err := daemon.ImageDelete(id.String(), false, true)
+			if err != nil {
+				logrus.Warnf("failed to prune image %s: %v", id, err)
+				continue
+			}
+			rep.ImagesDeleted = append(rep.ImagesDeleted, records...)
+			continue
+		}
+		for _, ref := range refs {
+			records, err := daemon.ImageDelete(ref.String(), false, true)
+			if err != nil {
+				logrus.Warnf("failed to prune image %s: %v", ref.String(), err)
+				break
+			}
+			rep.ImagesDeleted = append(rep.ImagesDeleted, records...)
+		}
+	}
+
+	remaining := daemon.layerStore.Map()
+	for chainID, size := range layerSizes {
+		if _, ok := remaining[chainID]; !ok && size > 0 {
+			rep.SpaceReclaimed += uint64(size)
+		}
+	}
+
+	return rep, nil
+}
+
+// NetworksPrune removes the networks matching the given filters which have
+// no endpoints. Pre-defined networks are never removed.
+func (daemon *Daemon) NetworksPrune(pruneFilters filters.Args) (*types.NetworksPruneReport, error) {
+	if err := pruneFilters.Validate(networksAcceptedFilters); err != nil {
+		return nil, err
+	}
+	done, err := daemon.startPrune()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rep := &types.NetworksPruneReport{}
+	if !daemon.NetworkControllerEnabled() {
+		return rep, nil
+	}
+
+	// Collect the candidates first, as DeleteNetwork must not be called
+	// while walking the networks.
+	var candidates []libnetwork.Network
+	daemon.netController.WalkNetworks(func(nw libnetwork.Network) bool {
+		if runconfig.IsPreDefinedNetwork(nw.Name()) || len(nw.Endpoints()) > 0 {
+			return false
+		}
+		if !pruneFilters.MatchKVList("label", nw.Info().Labels()) {
+			return false
+		}
+		candidates = append(candidates, nw)
+		return false
+	})
+
+	for _, nw := range candidates {
+		// The network driver refuses to remove a network which got an
+		// endpoint in the meantime.
+		if err := daemon.DeleteNetwork(nw.ID()); err != nil {
+			logrus.Warnf("failed to prune network %s: %v", nw.Name(), err)
+			continue
+		}
+		rep.NetworksDeleted = append(rep.NetworksDeleted, nw.Name())
+	}
+
+	return rep, nil
+}
+
+// getUntilFromPruneFilters returns the time given by the "until" filter, or
+// the zero time if the filter is not set.
+func getUntilFromPruneFilters(pruneFilters filters.Args) (time.Time, error) {
+	until := time.Time{}
+	if !pruneFilters.Include("until") {
+		return until, nil
+	}
+	untilFilters := pruneFilters.Get("until")
+	if len(untilFilters) > 1 {
+		return until, fmt.Errorf("more than one until filter specified")
+	}
+	ts, err := timetypes.GetTimestamp(untilFilters[0], time.Now())
+	if err != nil {
+		return until, err
+	}
+	seconds, nanoseconds, err := timetypes.ParseTimestamps(ts, 0)
+	if err != nil {
+		return until, err
+	}
+	return time.Unix(seconds, nanoseconds), nil
+}
+
+func imageLabels(img *image.Image) map[string]string {
+	if img.Config == nil {
+		return nil
+	}
+	return img.Config.Labels
+}
@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types/filters"
+)
+
+func TestGetUntilFromPruneFilters(t *testing.T) {
+	args := filters.NewArgs()
+	until, err := getUntilFromPruneFilters(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !until.IsZero() {
+		t.Fatalf("expected zero time without until filter, got %v", until)
+	}
+
+	args.Add("until", "2016-05-01T10:00:00Z")
+	until, err = getUntilFromPruneFilters(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	if !until.Equal(expected) {
+		t.Fatalf("expected %v, got %v", expected, until)
+	}
+
+	args.Add("until", "10m")
+	if _, err := getUntilFromPruneFilters(args); err == nil {
+		t.Fatal("expected an error for multiple until filters")
+	}
+}
+
+func TestGetUntilFromPruneFiltersDuration(t *testing.T) {
+	args := filters.NewArgs()
+	args.Add("until", "1h")
+	until, err := getUntilFromPruneFilters(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(until); d < time.Hour || d > time.Hour+time.Minute {
+		t.Fatalf("expected until to be about one hour ago, got %v", until)
+	}
+}
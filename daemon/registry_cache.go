@@ -0,0 +1,288 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/registry/api/errcode"
+	"github.com/docker/distribution/registry/api/v2"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/reference"
+	"github.com/docker/engine-api/types"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+)
+
+// registryCache serves the images of the daemon over the registry v2
+// protocol, read-only, so that other daemons can use it as a registry or a
+// registry mirror. The tags it does not have are pulled first.
+//
+// The layers are served uncompressed, with their diffID as digest, as the
+// layer store can reproduce their tar but not the compressed blobs they were
+// pulled as.
+type registryCache struct {
+	daemon *Daemon
+
+	mu sync.Mutex
+	// tarSizes are the sizes of the tars of the layers, by diffID
+	tarSizes map[layer.DiffID]int64
+}
+
+// RegistryCacheHandler returns the handler serving the images of the daemon
+// over the registry v2 protocol.
+func (daemon *Daemon) RegistryCacheHandler() http.Handler {
+	rc := &registryCache{
+		daemon:   daemon,
+		tarSizes: make(map[layer.DiffID]int64),
+	}
+	r := mux.NewRouter()
+	r.Path("/v2/").Methods("GET", "HEAD").HandlerFunc(rc.serveBase)
+	r.Path("/v2/{name:.+}/manifests/{reference}").Methods("GET", "HEAD").HandlerFunc(rc.serveManifest)
+	r.Path("/v2/{name:.+}/blobs/{digest}").Methods("GET", "HEAD").HandlerFunc(rc.serveBlob)
+	// the cache is read-only
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errcode.ServeJSON(w, errcode.ErrorCodeUnsupported)
+	})
+	return r
+}
+
+func (rc *registryCache) serveBase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{}")
+}
+
+func (rc *registryCache) serveManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	named, err := reference.ParseNamed(vars["name"])
+	if err != nil {
+		errcode.ServeJSON(w, v2.ErrorCodeNameInvalid.WithDetail(err.Error()))
+		return
+	}
+
+	var manifest *schema2.DeserializedManifest
+	if dgst, err := digest.ParseDigest(vars["reference"]); err == nil {
+		manifest, err = rc.manifestByDigest(named, dgst)
+	} else {
+		manifest, err = rc.manifestByTag(named, vars["reference"])
+	}
+	if err != nil {
+		logrus.Debugf("Registry cache: no manifest for %s:%s: %v", named.Name(), vars["reference"], err)
+		errcode.ServeJSON(w, v2.ErrorCodeManifestUnknown.WithDetail(err.Error()))
+		return
+	}
+
+	mediaType, payload, err := manifest.Payload()
+	if err != nil {
+		errcode.ServeJSON(w, errcode.ErrorCodeUnknown.WithDetail(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.Header().Set("Docker-Content-Digest", digest.FromBytes(payload).String())
+	if r.Method == "HEAD" {
+		return
+	}
+	w.Write(payload)
+}
+
+// manifestByTag returns the manifest of the image tagged tag in the
+// repository named, which is pulled if the daemon does not have it.
+func (rc *registryCache) manifestByTag(named reference.Named, tag string) (*schema2.DeserializedManifest, error) {
+	tagged, err := reference.WithTag(named, tag)
+	if err != nil {
+		return nil, err
+	}
+	id, err := rc.daemon.referenceStore.Get(tagged)
+	if err == reference.ErrDoesNotExist {
+		logrus.Infof("Registry cache: pulling %s", tagged.String())
+		if err := rc.daemon.pullImageWithReference(context.Background(), tagged, nil, &types.AuthConfig{}, ioutil.Discard); err != nil {
+			return nil, err
+		}
+		id, err = rc.daemon.referenceStore.Get(tagged)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img, err := rc.daemon.imageStore.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return rc.manifest(img)
+}
+
+// manifestByDigest returns the manifest of digest dgst among the manifests
+// of the images of the repository named.
+func (rc *registryCache) manifestByDigest(named reference.Named, dgst digest.Digest) (*schema2.DeserializedManifest, error) {
+	for _, img := range rc.images(named) {
+		manifest, err := rc.manifest(img)
+		if err != nil {
+			continue
+		}
+		_, payload, err := manifest.Payload()
+		if err == nil && digest.FromBytes(payload) == dgst {
+			return manifest, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown manifest %s", dgst)
+}
+
+// manifest returns the schema2 manifest of img.
+func (rc *registryCache) manifest(img *image.Image) (*schema2.DeserializedManifest, error) {
+	if img.RootFS == nil || img.RootFS.Type != image.TypeLayers {
+		return nil, fmt.Errorf("image %s cannot be served", img.ID())
+	}
+	m := schema2.Manifest{
+		Versioned: schema2.SchemaVersion,
+		Config: distribution.Descriptor{
+			MediaType: schema2.MediaTypeConfig,
+			Size:      int64(len(img.RawJSON())),
+			Digest:    digest.Digest(img.ID()),
+		},
+	}
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		size, err := rc.tarSize(rootFS.ChainID(), diffID)
+		if err != nil {
+			return nil, err
+		}
+		m.Layers = append(m.Layers, distribution.Descriptor{
+			MediaType: schema2.MediaTypeLayer,
+			Size:      size,
+			Digest:    digest.Digest(diffID),
+		})
+	}
+	return schema2.FromStruct(m)
+}
+
+func (rc *registryCache) serveBlob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	named, err := reference.ParseNamed(vars["name"])
+	if err != nil {
+		errcode.ServeJSON(w, v2.ErrorCodeNameInvalid.WithDetail(err.Error()))
+		return
+	}
+	dgst, err := digest.ParseDigest(vars["digest"])
+	if err != nil {
+		errcode.ServeJSON(w, v2.ErrorCodeDigestInvalid.WithDetail(err.Error()))
+		return
+	}
+
+	for _, img := range rc.images(named) {
+		if digest.Digest(img.ID()) == dgst {
+			config := img.RawJSON()
+			rc.writeBlobHeaders(w, dgst, int64(len(config)))
+			if r.Method != "HEAD" {
+				w.Write(config)
+			}
+			return
+		}
+		if img.RootFS == nil {
+			continue
+		}
+		rootFS := *img.RootFS
+		rootFS.DiffIDs = nil
+		for _, diffID := range img.RootFS.DiffIDs {
+			rootFS.Append(diffID)
+			if digest.Digest(diffID) == dgst {
+				if err := rc.serveLayer(w, r, rootFS.ChainID(), diffID); err != nil {
+					logrus.Errorf("Registry cache: error serving layer %s: %v", diffID, err)
+				}
+				return
+			}
+		}
+	}
+	errcode.ServeJSON(w, v2.ErrorCodeBlobUnknown.WithDetail(dgst))
+}
+
+func (rc *registryCache) serveLayer(w http.ResponseWriter, r *http.Request, chainID layer.ChainID, diffID layer.DiffID) error {
+	size, err := rc.tarSize(chainID, diffID)
+	if err != nil {
+		errcode.ServeJSON(w, errcode.ErrorCodeUnknown.WithDetail(err.Error()))
+		return err
+	}
+	rc.writeBlobHeaders(w, digest.Digest(diffID), size)
+	if r.Method == "HEAD" {
+		return nil
+	}
+
+	l, err := rc.daemon.layerStore.Get(chainID)
+	if err != nil {
+		return err
+	}
+	defer layer.ReleaseAndLog(rc.daemon.layerStore, l)
+	ts, err := l.TarStream()
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	_, err = io.Copy(w, ts)
+	return err
+}
+
+func (rc *registryCache) writeBlobHeaders(w http.ResponseWriter, dgst digest.Digest, size int64) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	w.Header().Set("Etag", `"`+dgst.String()+`"`)
+}
+
+// images returns the images of the repository named.
+func (rc *registryCache) images(named reference.Named) []*image.Image {
+	var images []*image.Image
+	seen := make(map[image.ID]struct{})
+	for _, assoc := range rc.daemon.referenceStore.ReferencesByName(named) {
+		if _, ok := seen[assoc.ImageID]; ok {
+			continue
+		}
+		seen[assoc.ImageID] = struct{}{}
+		img, err := rc.daemon.imageStore.Get(assoc.ImageID)
+		if err != nil {
+			continue
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// tarSize returns the size of the tar of a layer, which is only known by
+// reading it.
+func (rc *registryCache) tarSize(chainID layer.ChainID, diffID layer.DiffID) (int64, error) {
+	rc.mu.Lock()
+	size, ok := rc.tarSizes[diffID]
+	rc.mu.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	l, err := rc.daemon.layerStore.Get(chainID)
+	if err != nil {
+		return 0, err
+	}
+	defer layer.ReleaseAndLog(rc.daemon.layerStore, l)
+	ts, err := l.TarStream()
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	size, err = io.Copy(ioutil.Discard, ts)
+	if err != nil {
+		return 0, err
+	}
+
+	rc.mu.Lock()
+	rc.tarSizes[diffID] = size
+	rc.mu.Unlock()
+	return size, nil
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/docker/daemon/graphdriver"
+	_ "github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/reference"
+)
+
+func newRegistryCacheTestDaemon(t *testing.T, root string) *Daemon {
+	driver, err := graphdriver.GetDriver("vfs", filepath.Join(root, "vfs"), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fms, err := layer.NewFSMetadataStore(filepath.Join(root, "layerdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := layer.NewStoreFromGraphDriver(fms, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifs, err := image.NewFSStoreBackend(filepath.Join(root, "imagedb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := image.NewImageStore(ifs, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := reference.NewReferenceStore(filepath.Join(root, "repositories.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Daemon{layerStore: ls, imageStore: is, referenceStore: rs}
+}
+
+func get(t *testing.T, method, url string) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b
+}
+
+func TestRegistryCache(t *testing.T) {
+	root, err := ioutil.TempDir("", "registry-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	d := newRegistryCacheTestDaemon(t, root)
+
+	var layerTar bytes.Buffer
+	tw := tar.NewWriter(&layerTar)
+	content := []byte("cached\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "file", Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	l, err := d.layerStore.Register(bytes.NewReader(layerTar.Bytes()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layer.ReleaseAndLog(d.layerStore, l)
+
+	config := []byte(`{"architecture":"amd64","os":"linux","rootfs":{"type":"layers","diff_ids":["` + l.DiffID().String() + `"]}}`)
+	id, err := d.imageStore.Create(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := reference.ParseNamed("busybox:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.referenceStore.AddTag(ref, id, false); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(d.RegistryCacheHandler())
+	defer server.Close()
+
+	resp, _ := get(t, "GET", server.URL+"/v2/")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Fatalf("unexpected response to the base endpoint: %v", resp.Status)
+	}
+
+	// the images are served under their Docker Hub names
+	resp, payload := get(t, "GET", server.URL+"/v2/library/busybox/manifests/latest")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != schema2.MediaTypeManifest {
+		t.Fatalf("unexpected response to the manifest request: %v %s", resp.Status, payload)
+	}
+	var m schema2.Manifest
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Config.Digest != digest.Digest(id) || len(m.Layers) != 1 || m.Layers[0].Digest != digest.Digest(l.DiffID()) || m.Layers[0].Size != int64(layerTar.Len()) {
+		t.Fatalf("unexpected manifest: %s", payload)
+	}
+	manifestDigest := resp.Header.Get("Docker-Content-Digest")
+	if manifestDigest != digest.FromBytes(payload).String() {
+		t.Fatalf("unexpected manifest digest %s", manifestDigest)
+	}
+
+	resp, b := get(t, "GET", server.URL+"/v2/busybox/manifests/"+manifestDigest)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(b, payload) {
+		t.Fatalf("unexpected response to the manifest request by digest: %v %s", resp.Status, b)
+	}
+
+	resp, b = get(t, "GET", server.URL+"/v2/busybox/blobs/"+m.Config.Digest.String())
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(b, config) {
+		t.Fatalf("unexpected config blob: %v %s", resp.Status, b)
+	}
+	resp, b = get(t, "GET", server.URL+"/v2/busybox/blobs/"+m.Layers[0].Digest.String())
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(b, layerTar.Bytes()) {
+		t.Fatalf("unexpected layer blob: %v", resp.Status)
+	}
+
+	// the blobs are only served in the repositories of their images
+	resp, _ = get(t, "HEAD", server.URL+"/v2/other/blobs/"+m.Layers[0].Digest.String())
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected %d for a blob of another repository, got %v", http.StatusNotFound, resp.Status)
+	}
+
+	// the cache is read-only
+	resp, _ = get(t, "PUT", server.URL+"/v2/busybox/manifests/latest")
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected %d for a push, got %v", http.StatusMethodNotAllowed, resp.Status)
+	}
+}
@@ -34,6 +34,11 @@ func setSeccomp(daemon *Daemon, rs *specs.Spec, c *container.Container) error {
 		if err != nil {
 			return err
 		}
+	} else if daemon.seccompProfile != nil {
+		profile, err = seccomp.LoadProfile(string(daemon.seccompProfile))
+		if err != nil {
+			return fmt.Errorf("loading seccomp profile %s failed: %v", daemon.seccompProfilePath, err)
+		}
 	} else {
 		profile, err = seccomp.GetDefaultProfile()
 		if err != nil {
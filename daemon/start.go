@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/container"
@@ -13,6 +14,7 @@ import (
 	"github.com/docker/docker/libcontainerd"
 	"github.com/docker/docker/runconfig"
 	containertypes "github.com/docker/engine-api/types/container"
+	"golang.org/x/net/context"
 )
 
 // ContainerStart starts a container.
@@ -111,6 +113,7 @@ func (daemon *Daemon) containerStart(container *container.Container) (err error)
 				"exitCode": fmt.Sprintf("%d", container.ExitCode),
 			}
 			daemon.LogContainerEventWithAttributes(container, "die", attributes)
+			containerActions.Inc("start_failure")
 		}
 	}()
 
@@ -131,7 +134,15 @@ func (daemon *Daemon) containerStart(container *container.Container) (err error)
 		return err
 	}
 
-	if err := daemon.containerd.Create(container.ID, *spec, libcontainerd.WithRestartManager(container.RestartManager(true))); err != nil {
+	createOptions, err := daemon.getLibcontainerdCreateOptions(container)
+	if err != nil {
+		return err
+	}
+	rm := container.RestartManager(true)
+	container.SetRestartResetWindow(time.Duration(daemon.configStore.RestartResetWindow) * time.Second)
+	createOptions = append(createOptions, libcontainerd.WithRestartManager(rm))
+
+	if err := daemon.containerd.Create(context.Background(), container.ID, *spec, createOptions...); err != nil {
 		// if we receive an internal error from the initial start of a container then lets
 		// return it instead of entering the restart loop
 		// set to 127 for container cmd not found/does not exist)
@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/libcontainerd"
+)
+
+// fifoDirname is the directory under the exec root holding the stdio fifos
+// of the containers.
+const fifoDirname = "fifo"
+
+func (daemon *Daemon) getLibcontainerdCreateOptions(container *container.Container) ([]libcontainerd.CreateOption, error) {
+	createOptions := []libcontainerd.CreateOption{}
+
+	rt := daemon.getRuntime(container.HostConfig.Runtime)
+	if rt == nil {
+		return nil, fmt.Errorf("No such runtime '%s'", container.HostConfig.Runtime)
+	}
+	createOptions = append(createOptions, libcontainerd.WithRuntime(rt.Path, rt.Args))
+	createOptions = append(createOptions, libcontainerd.WithFifoDir(filepath.Join(daemon.configStore.ExecRoot, fifoDirname)))
+
+	return createOptions, nil
+}
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/layer"
+	"github.com/docker/go-units"
+)
+
+const (
+	// defaultStorageGCThreshold is the default disk usage of the daemon
+	// root, in percent, above which the storage garbage is collected.
+	defaultStorageGCThreshold = 90
+	// storageGCInterval is how often the disk usage is checked.
+	storageGCInterval = time.Minute
+	// storageGCTempMinAge is how old a temporary file must be to be
+	// removed. Newer ones may belong to a pull or a load in progress.
+	storageGCTempMinAge = time.Hour
+)
+
+// storageGCTempPrefixes are the prefixes of the temporary files and
+// directories of layer downloads, image loads and layer registrations.
+var storageGCTempPrefixes = []string{"GetImageBlob", "docker-import-", "layer-"}
+
+// storageGC removes the layers nothing references anymore and the temporary
+// files left behind by interrupted pulls and loads, in the background, once
+// the disk usage of the daemon root crosses a threshold.
+type storageGC struct {
+	mu        sync.Mutex
+	threshold int
+
+	root       string
+	tempDirs   []string
+	layerStore layer.Store
+	diskUsage  func(path string) (int, error)
+	now        func() time.Time
+}
+
+func newStorageGC(root string, tempDirs []string, ls layer.Store, threshold int) *storageGC {
+	return &storageGC{
+		threshold:  threshold,
+		root:       root,
+		tempDirs:   tempDirs,
+		layerStore: ls,
+		diskUsage:  diskUsage,
+		now:        time.Now,
+	}
+}
+
+// SetThreshold sets the disk usage, in percent, above which the garbage is
+// collected. A threshold of 0 disables the collection.
+func (gc *storageGC) SetThreshold(threshold int) {
+	gc.mu.Lock()
+	gc.threshold = threshold
+	gc.mu.Unlock()
+}
+
+func (gc *storageGC) run() {
+	for range time.Tick(storageGCInterval) {
+		gc.collectIfNeeded()
+	}
+}
+
+// collectIfNeeded collects the garbage if the disk usage of the daemon root
+// is above the threshold. It returns whether the garbage was collected.
+func (gc *storageGC) collectIfNeeded() bool {
+	gc.mu.Lock()
+	threshold := gc.threshold
+	gc.mu.Unlock()
+	if threshold <= 0 {
+		return false
+	}
+
+	usage, err := gc.diskUsage(gc.root)
+	if err != nil {
+		logrus.Debugf("Could not get the disk usage of %s: %v", gc.root, err)
+		return false
+	}
+	if usage < threshold {
+		return false
+	}
+	gc.collect()
+	return true
+}
+
+func (gc *storageGC) collect() {
+	var (
+		removed   []layer.Metadata
+		reclaimed int64
+	)
+	if c, ok := gc.layerStore.(layer.GarbageCollector); ok {
+		var err error
+		if removed, err = c.CollectGarbage(); err != nil {
+			logrus.Errorf("Error removing unreferenced layers: %v", err)
+		}
+	}
+	for _, m := range removed {
+		reclaimed += m.DiffSize
+	}
+
+	var tempFiles int
+	for _, dir := range gc.tempDirs {
+		tempFiles += gc.removeTempFiles(dir)
+	}
+
+	if len(removed) > 0 || tempFiles > 0 {
+		logrus.Infof("Storage garbage collection removed %d unreferenced layers (%s) and %d temporary files", len(removed), units.HumanSize(float64(reclaimed)), tempFiles)
+	}
+}
+
+// removeTempFiles removes the stale temporary files and directories in dir,
+// and returns how many were removed.
+func (gc *storageGC) removeTempFiles(dir string) int {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Debugf("Could not list temporary files in %s: %v", dir, err)
+		}
+		return 0
+	}
+
+	var removed int
+	for _, fi := range entries {
+		if !hasStorageGCTempPrefix(fi.Name()) || gc.now().Sub(fi.ModTime()) < storageGCTempMinAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, fi.Name())); err != nil {
+			logrus.Errorf("Error removing temporary file %s: %v", filepath.Join(dir, fi.Name()), err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+func hasStorageGCTempPrefix(name string) bool {
+	for _, prefix := range storageGCTempPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
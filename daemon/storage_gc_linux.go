@@ -0,0 +1,17 @@
+package daemon
+
+import "syscall"
+
+// diskUsage returns the usage of the filesystem of path, in percent of the
+// space available to unprivileged users, as df reports it.
+func diskUsage(path string) (int, error) {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(path, &buf); err != nil {
+		return 0, err
+	}
+	used := buf.Blocks - buf.Bfree
+	if used+buf.Bavail == 0 {
+		return 0, nil
+	}
+	return int(used * 100 / (used + buf.Bavail)), nil
+}
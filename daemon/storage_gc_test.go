@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStorageGCTempFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "storage-gc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	now := time.Now()
+	for _, name := range []string{"GetImageBlob123", "docker-import-456", "layer-789", "GetImageBlobNew", "other"} {
+		if err := ioutil.WriteFile(filepath.Join(tmpDir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if name == "GetImageBlobNew" {
+			continue
+		}
+		old := now.Add(-2 * storageGCTempMinAge)
+		if err := os.Chtimes(filepath.Join(tmpDir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := 50
+	gc := newStorageGC(tmpDir, []string{tmpDir}, nil, 80)
+	gc.diskUsage = func(string) (int, error) { return usage, nil }
+	gc.now = func() time.Time { return now }
+
+	if gc.collectIfNeeded() {
+		t.Fatal("expected no collection below the threshold")
+	}
+	usage = 80
+	if !gc.collectIfNeeded() {
+		t.Fatal("expected a collection at the threshold")
+	}
+
+	entries, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, fi := range entries {
+		left = append(left, fi.Name())
+	}
+	sort.Strings(left)
+	if len(left) != 2 || left[0] != "GetImageBlobNew" || left[1] != "other" {
+		t.Fatalf("expected the recent and unknown files to be kept, got %v", left)
+	}
+
+	gc.SetThreshold(0)
+	if gc.collectIfNeeded() {
+		t.Fatal("expected no collection when disabled")
+	}
+}
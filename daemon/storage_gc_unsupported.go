@@ -0,0 +1,11 @@
+// +build !linux
+
+package daemon
+
+import "errors"
+
+// diskUsage is not supported on platforms other than linux, so the
+// storage garbage is never collected there.
+func diskUsage(path string) (int, error) {
+	return 0, errors.New("disk usage is not supported on this platform")
+}
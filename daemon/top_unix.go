@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
 )
 
 // ContainerTop lists the processes running inside of the given
@@ -34,7 +35,7 @@ func (daemon *Daemon) ContainerTop(name string, psArgs string) (*types.Container
 		return nil, errContainerIsRestarting(container.ID)
 	}
 
-	pids, err := daemon.containerd.GetPidsForContainer(container.ID)
+	pids, err := daemon.containerd.GetPidsForContainer(context.Background(), container.ID)
 	if err != nil {
 		return nil, err
 	}
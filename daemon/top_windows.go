@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
 )
 
 // ContainerTop is a minimal implementation on Windows currently.
@@ -18,15 +19,17 @@ func (daemon *Daemon) ContainerTop(containerID string, psArgs string) (*types.Co
 		return nil, errors.New("Windows does not support arguments to top")
 	}
 
-	s, err := daemon.containerd.Summary(containerID)
+	s, err := daemon.containerd.Summary(context.Background(), containerID)
 	if err != nil {
 		return nil, err
 	}
 
-	procList := &types.ContainerProcessList{}
+	procList := &types.ContainerProcessList{
+		Titles: []string{"PID", "CMD"},
+	}
 
 	for _, v := range s {
-		procList.Titles = append(procList.Titles, strconv.Itoa(int(v.Pid))+" "+v.Command)
+		procList.Processes = append(procList.Processes, []string{strconv.Itoa(int(v.Pid)), v.Command})
 	}
 	return procList, nil
 }
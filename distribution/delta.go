@@ -0,0 +1,259 @@
+package distribution
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/registry/client/transport"
+	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/bspatch"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// deltaBaseHeader is the header of the response of the delta server naming
+// the layer the binary diff applies to.
+const deltaBaseHeader = "Docker-Delta-Base"
+
+// errNoDelta is returned when the delta server has no binary diff of a
+// layer against the layers advertised.
+var errNoDelta = errors.New("no delta available")
+
+// DeltaConfig configures the download of the layers of an image as binary
+// diffs against the layers of the image previously pulled for the same tag.
+type DeltaConfig struct {
+	// Server is the URL of the service serving the binary diffs of the
+	// layers, which may be the registry itself.
+	Server string
+	// LayerStore is the store the layers the diffs apply to are read from.
+	LayerStore layer.Store
+}
+
+// layerDeltas downloads layers as binary diffs from a delta server. The
+// daemon advertises the layers it already has, and the server answers with
+// a bsdiff patch from the uncompressed tar of one of them to the
+// uncompressed tar of the new layer.
+type layerDeltas struct {
+	server     string
+	client     *http.Client
+	repoName   string
+	layerStore layer.Store
+	// bases are the layers the diffs may apply to, by digest
+	bases map[digest.Digest]layer.ChainID
+}
+
+// layerDeltas returns the layers binary diffs can be downloaded against for
+// ref: the layers of the image pulled before for the tag, that were pulled
+// from this repository. It returns nil if there are none.
+func (p *v2Puller) layerDeltas(ctx context.Context, ref reference.Named) *layerDeltas {
+	if _, isTagged := ref.(reference.NamedTagged); !isTagged {
+		return nil
+	}
+	id, err := p.config.ReferenceStore.Get(ref)
+	if err != nil {
+		return nil
+	}
+	img, err := p.config.ImageStore.Get(id)
+	if err != nil || img.RootFS == nil {
+		return nil
+	}
+
+	bases := make(map[digest.Digest]layer.ChainID)
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		metadata, err := p.V2MetadataService.GetMetadata(diffID)
+		if err != nil {
+			continue
+		}
+		for _, m := range metadata {
+			if m.SourceRepository == p.repoInfo.FullName() {
+				bases[m.Digest] = rootFS.ChainID()
+			}
+		}
+	}
+	if len(bases) == 0 {
+		return nil
+	}
+
+	client, err := newDeltaClient(ctx, p.config)
+	if err != nil {
+		logrus.Warnf("Not downloading deltas from %s: %v", p.config.Delta.Server, err)
+		return nil
+	}
+	return &layerDeltas{
+		server:     strings.TrimSuffix(p.config.Delta.Server, "/"),
+		client:     client,
+		repoName:   p.repo.Named().Name(),
+		layerStore: p.config.Delta.LayerStore,
+		bases:      bases,
+	}
+}
+
+// newDeltaClient returns a HTTP client for the delta server, with the TLS
+// configuration of the registries.
+func newDeltaClient(ctx context.Context, config *ImagePullConfig) (*http.Client, error) {
+	serverURL, err := url.Parse(config.Delta.Server)
+	if err != nil {
+		return nil, err
+	}
+	if serverURL.Scheme != "http" && serverURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid delta server URL %s", config.Delta.Server)
+	}
+	tlsConfig, err := config.RegistryService.TLSConfig(serverURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   true,
+	}
+	modifiers := registry.DockerHeaders(dockerversion.DockerUserAgent(ctx), config.MetaHeaders)
+	return &http.Client{Transport: transport.NewTransport(base, modifiers...)}, nil
+}
+
+// download downloads the layer dgst as a binary diff, and applies it. The
+// result is the uncompressed tar of the layer, verified against diffID.
+func (ld *layerDeltas) download(ctx context.Context, dgst digest.Digest, diffID layer.DiffID, id string, progressOutput progress.Output) (io.ReadCloser, int64, error) {
+	var from []string
+	for d := range ld.bases {
+		if d != dgst {
+			from = append(from, d.String())
+		}
+	}
+	if len(from) == 0 {
+		return nil, 0, errNoDelta
+	}
+	sort.Strings(from)
+
+	u := fmt.Sprintf("%s/v2/%s/deltas/%s?%s", ld.server, ld.repoName, dgst, url.Values{"from": from}.Encode())
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := ctxhttp.Do(ctx, ld.client, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, 0, errNoDelta
+	case resp.StatusCode != http.StatusOK:
+		return nil, 0, fmt.Errorf("unexpected status from delta server: %s", resp.Status)
+	}
+
+	base, err := digest.ParseDigest(resp.Header.Get(deltaBaseHeader))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s header: %v", deltaBaseHeader, err)
+	}
+	chainID, ok := ld.bases[base]
+	if !ok {
+		return nil, 0, fmt.Errorf("delta against unknown layer %s", base)
+	}
+
+	patchFile, err := createDownloadFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer removeDownloadFile(patchFile)
+	reader := progress.NewProgressReader(ioutils.NewCancelReadCloser(ctx, resp.Body), progressOutput, resp.ContentLength, id, "Downloading delta")
+	defer reader.Close()
+	patchSize, err := io.Copy(patchFile, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	progress.Update(progressOutput, id, "Applying delta")
+
+	oldFile, err := createDownloadFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer removeDownloadFile(oldFile)
+	oldSize, err := ld.writeLayerTar(chainID, oldFile)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	newFile, err := createDownloadFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	verifier, err := digest.NewDigestVerifier(digest.Digest(diffID))
+	if err != nil {
+		removeDownloadFile(newFile)
+		return nil, 0, err
+	}
+	if err := bspatch.Patch(oldFile, oldSize, patchFile, patchSize, io.MultiWriter(newFile, verifier)); err != nil {
+		removeDownloadFile(newFile)
+		return nil, 0, err
+	}
+	if !verifier.Verified() {
+		removeDownloadFile(newFile)
+		return nil, 0, fmt.Errorf("delta of %s against %s does not match %s", dgst, base, diffID)
+	}
+	size, err := newFile.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		removeDownloadFile(newFile)
+		return nil, 0, err
+	}
+	if _, err := newFile.Seek(0, os.SEEK_SET); err != nil {
+		removeDownloadFile(newFile)
+		return nil, 0, err
+	}
+
+	progress.Update(progressOutput, id, "Download complete")
+
+	return ioutils.NewReadCloserWrapper(newFile, func() error {
+		return removeDownloadFile(newFile)
+	}), size, nil
+}
+
+// writeLayerTar writes the uncompressed tar of the layer chainID to f.
+func (ld *layerDeltas) writeLayerTar(chainID layer.ChainID, f *os.File) (int64, error) {
+	l, err := ld.layerStore.Get(chainID)
+	if err != nil {
+		return 0, err
+	}
+	defer layer.ReleaseAndLog(ld.layerStore, l)
+	ts, err := l.TarStream()
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	return io.Copy(f, ts)
+}
+
+func removeDownloadFile(f *os.File) error {
+	f.Close()
+	err := os.RemoveAll(f.Name())
+	if err != nil {
+		logrus.Errorf("Failed to remove temp file: %s", f.Name())
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package distribution
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/progress"
+	"golang.org/x/net/context"
+)
+
+type deltaBaseStore struct {
+	layer.Store
+	layers map[layer.ChainID][]byte
+}
+
+func (ls deltaBaseStore) Get(chainID layer.ChainID) (layer.Layer, error) {
+	tar, ok := ls.layers[chainID]
+	if !ok {
+		return nil, layer.ErrLayerDoesNotExist
+	}
+	return deltaBaseLayer{tar: tar}, nil
+}
+
+func (ls deltaBaseStore) Release(l layer.Layer) ([]layer.Metadata, error) {
+	return nil, nil
+}
+
+type deltaBaseLayer struct {
+	layer.Layer
+	tar []byte
+}
+
+func (l deltaBaseLayer) TarStream() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.tar)), nil
+}
+
+func TestLayerDeltaDownload(t *testing.T) {
+	old, err := ioutil.ReadFile("../pkg/bspatch/testdata/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("../pkg/bspatch/testdata/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := ioutil.ReadFile("../pkg/bspatch/testdata/patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := digest.Digest("sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4")
+	target := digest.Digest("sha256:1834950e52ce4d5a88a1bbd131c537f4d0e56d10ff0dd69e66be3b7dfa9df7e6")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/team/app/deltas/"+target.String() {
+			http.NotFound(w, r)
+			return
+		}
+		if from := r.URL.Query()["from"]; len(from) != 1 || from[0] != base.String() {
+			t.Errorf("unexpected layers advertised: %v", from)
+		}
+		w.Header().Set(deltaBaseHeader, base.String())
+		w.Write(patch)
+	}))
+	defer server.Close()
+
+	deltas := &layerDeltas{
+		server:     server.URL,
+		client:     http.DefaultClient,
+		repoName:   "team/app",
+		layerStore: deltaBaseStore{layers: map[layer.ChainID][]byte{"sha256:base": old}},
+		bases:      map[digest.Digest]layer.ChainID{base: "sha256:base"},
+	}
+	progressOutput := progress.ChanOutput(make(chan progress.Progress, 100))
+
+	rc, size, err := deltas.download(context.Background(), target, layer.DiffID(digest.FromBytes(expected)), "target", progressOutput)
+	if err != nil {
+		t.Fatalf("error downloading delta: %v", err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(expected)) || !bytes.Equal(b, expected) {
+		t.Fatalf("unexpected layer of %d bytes:\n%s", size, b)
+	}
+
+	// the result is verified against the diffID of the layer
+	if _, _, err := deltas.download(context.Background(), target, layer.DiffID(digest.FromBytes(old)), "target", progressOutput); err == nil || err == errNoDelta {
+		t.Fatalf("expected a verification error, got %v", err)
+	}
+
+	if _, _, err := deltas.download(context.Background(), base, layer.DiffID(digest.FromBytes(old)), "base", progressOutput); err != errNoDelta {
+		t.Fatalf("expected %v for a layer without delta, got %v", errNoDelta, err)
+	}
+}
@@ -79,6 +79,23 @@ func continueOnError(err error) bool {
 	return true
 }
 
+// isMirrorFailure returns whether a pull from a mirror fell back to the next
+// endpoint because of the mirror itself: it could not be reached, or it did
+// not answer as a registry. Registry errors, such as an unknown manifest or
+// a denied access, are answers of a working mirror.
+func isMirrorFailure(err fallbackError) bool {
+	if !err.transportOK {
+		return true
+	}
+	switch v := err.err.(type) {
+	case errcode.Errors, errcode.Error:
+		return false
+	case ErrNoSupport:
+		return v.Err != nil && isMirrorFailure(fallbackError{err: v.Err, transportOK: true})
+	}
+	return true
+}
+
 // retryOnError wraps the error in xfer.DoNotRetry if we should not retry the
 // operation after this error.
 func retryOnError(err error) error {
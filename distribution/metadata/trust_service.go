@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"encoding/json"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/docker/image"
+)
+
+// TrustService maps image IDs to the content trust data their signatures
+// were verified with when they were pulled.
+type TrustService struct {
+	store Store
+}
+
+// TrustMetadata records the verification of the signature of an image in
+// a repository.
+type TrustMetadata struct {
+	// Repository is the repository the image was pulled from
+	Repository string
+	// RootKeyIDs are the IDs of the root keys of the repository the
+	// signature was verified with
+	RootKeyIDs []string
+}
+
+// NewTrustService creates a new image ID to trust metadata mapping service.
+func NewTrustService(store Store) *TrustService {
+	return &TrustService{
+		store: store,
+	}
+}
+
+func (serv *TrustService) namespace() string {
+	return "trust-by-image-id"
+}
+
+func (serv *TrustService) key(id image.ID) string {
+	return string(digest.Digest(id).Algorithm()) + "/" + digest.Digest(id).Hex()
+}
+
+// GetMetadata finds the trust metadata associated with an image ID.
+func (serv *TrustService) GetMetadata(id image.ID) ([]TrustMetadata, error) {
+	jsonBytes, err := serv.store.Get(serv.namespace(), serv.key(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []TrustMetadata
+	if err := json.Unmarshal(jsonBytes, &metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// Add associates trust metadata with an image ID, replacing the metadata of
+// the same repository.
+func (serv *TrustService) Add(id image.ID, metadata TrustMetadata) error {
+	oldMetadata, err := serv.GetMetadata(id)
+	if err != nil {
+		oldMetadata = nil
+	}
+	newMetadata := make([]TrustMetadata, 0, len(oldMetadata)+1)
+
+	// Copy the metadata of the other repositories to new slice
+	for _, oldMeta := range oldMetadata {
+		if oldMeta.Repository != metadata.Repository {
+			newMetadata = append(newMetadata, oldMeta)
+		}
+	}
+
+	newMetadata = append(newMetadata, metadata)
+
+	jsonBytes, err := json.Marshal(newMetadata)
+	if err != nil {
+		return err
+	}
+
+	return serv.store.Set(serv.namespace(), serv.key(id), jsonBytes)
+}
+
+// Remove unassociates all trust metadata from an image ID.
+func (serv *TrustService) Remove(id image.ID) error {
+	return serv.store.Delete(serv.namespace(), serv.key(id))
+}
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/image"
+)
+
+func TestTrustService(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "trust-service-test")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	metadataStore, err := NewFSMetadataStore(tmpDir)
+	if err != nil {
+		t.Fatalf("could not create metadata store: %v", err)
+	}
+	trustService := NewTrustService(metadataStore)
+
+	id := image.ID("sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4")
+	if _, err := trustService.GetMetadata(id); err == nil {
+		t.Fatal("expected an error for an image without trust metadata")
+	}
+
+	busybox := TrustMetadata{Repository: "docker.io/library/busybox", RootKeyIDs: []string{"root1"}}
+	other := TrustMetadata{Repository: "example.com/busybox", RootKeyIDs: []string{"root2"}}
+	rotated := TrustMetadata{Repository: "docker.io/library/busybox", RootKeyIDs: []string{"root3"}}
+	for _, m := range []TrustMetadata{busybox, other, rotated} {
+		if err := trustService.Add(id, m); err != nil {
+			t.Fatalf("error adding trust metadata: %v", err)
+		}
+	}
+
+	metadata, err := trustService.GetMetadata(id)
+	if err != nil {
+		t.Fatalf("error getting trust metadata: %v", err)
+	}
+	if expected := []TrustMetadata{other, rotated}; !reflect.DeepEqual(metadata, expected) {
+		t.Fatalf("expected trust metadata %v, got %v", expected, metadata)
+	}
+
+	if err := trustService.Remove(id); err != nil {
+		t.Fatalf("error removing trust metadata: %v", err)
+	}
+	if _, err := trustService.GetMetadata(id); err == nil {
+		t.Fatal("expected an error after removing the trust metadata")
+	}
+}
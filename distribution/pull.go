@@ -39,6 +39,12 @@ type ImagePullConfig struct {
 	ReferenceStore reference.Store
 	// DownloadManager manages concurrent pulls.
 	DownloadManager *xfer.LayerDownloadManager
+	// ContentTrust enforces the verification of the signature of the
+	// images pulled if it is set.
+	ContentTrust *ContentTrustConfig
+	// Delta enables the download of the layers as binary diffs if it is
+	// set.
+	Delta *DeltaConfig
 }
 
 // Puller is an interface that abstracts pulling for different API versions.
@@ -93,6 +99,17 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 		return err
 	}
 
+	// With content trust, the signed digest of ref is pulled instead
+	var trusted *trustedPull
+	pullRef := ref
+	if imagePullConfig.ContentTrust != nil {
+		trusted, err = verifyTrust(ctx, repoInfo, ref, imagePullConfig)
+		if err != nil {
+			return err
+		}
+		pullRef = trusted.trustedRef
+	}
+
 	var (
 		lastErr error
 
@@ -135,7 +152,7 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 			lastErr = err
 			continue
 		}
-		if err := puller.Pull(ctx, ref); err != nil {
+		if err := puller.Pull(ctx, pullRef); err != nil {
 			// Was this pull cancelled? If so, don't try to fall
 			// back.
 			fallback := false
@@ -148,6 +165,9 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 					if fallbackErr.transportOK && endpoint.URL.Scheme == "https" {
 						confirmedTLSRegistries[endpoint.URL.Host] = struct{}{}
 					}
+					if isMirrorFailure(fallbackErr) {
+						imagePullConfig.RegistryService.MirrorFailed(endpoint, fallbackErr.err)
+					}
 					err = fallbackErr.err
 				}
 			}
@@ -170,6 +190,12 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 			return err
 		}
 
+		imagePullConfig.RegistryService.MirrorSucceeded(endpoint)
+		if trusted != nil {
+			if err := trusted.record(repoInfo, imagePullConfig); err != nil {
+				return err
+			}
+		}
 		imagePullConfig.ImageEventLogger(ref.String(), repoInfo.Name(), "pull")
 		return nil
 	}
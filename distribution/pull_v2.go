@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"runtime"
@@ -29,6 +30,7 @@ import (
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/reference"
 	"github.com/docker/docker/registry"
+	"github.com/docker/go-units"
 	"golang.org/x/net/context"
 )
 
@@ -133,6 +135,11 @@ type v2LayerDescriptor struct {
 	V2MetadataService *metadata.V2MetadataService
 	tmpFile           *os.File
 	verifier          digest.Verifier
+	src               distribution.Descriptor
+	// deltas downloads the layer as a binary diff, once its diffID is
+	// known from the image config.
+	deltas *layerDeltas
+	diffID layer.DiffID
 }
 
 func (ld *v2LayerDescriptor) Key() string {
@@ -147,9 +154,60 @@ func (ld *v2LayerDescriptor) DiffID() (layer.DiffID, error) {
 	return ld.V2MetadataService.GetDiffID(ld.digest)
 }
 
+// Descriptor returns the descriptor of the layer in the manifest, which has
+// the URLs of a foreign layer.
+func (ld *v2LayerDescriptor) Descriptor() distribution.Descriptor {
+	return ld.src
+}
+
+// open opens the blob of the layer. The blob of a foreign layer is
+// downloaded from the registry if it has it, and from the first of its URLs
+// that serves it otherwise.
+func (ld *v2LayerDescriptor) open(ctx context.Context) (distribution.ReadSeekCloser, error) {
+	blobs := ld.repo.Blobs(ctx)
+	rsc, err := blobs.Open(ctx, ld.digest)
+
+	if len(ld.src.URLs) == 0 {
+		return rsc, err
+	}
+
+	// The registry has the blob if seeking, which makes the request,
+	// succeeds.
+	if err == nil {
+		if _, err = rsc.Seek(0, os.SEEK_SET); err == nil {
+			return rsc, nil
+		}
+		rsc.Close()
+	}
+
+	for _, u := range ld.src.URLs {
+		logrus.Debugf("Pulling %v from foreign URL %v", ld.digest, u)
+		rsc = transport.NewHTTPReadSeeker(http.DefaultClient, u, nil)
+
+		if _, err = rsc.Seek(0, os.SEEK_SET); err == nil {
+			return rsc, nil
+		}
+		logrus.Debugf("Download of %v from %v failed: %v", ld.digest, u, err)
+		rsc.Close()
+	}
+	return nil, err
+}
+
 func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progress.Output) (io.ReadCloser, int64, error) {
 	logrus.Debugf("pulling blob %q", ld.digest)
 
+	if ld.deltas != nil && ld.diffID != "" && ld.tmpFile == nil {
+		rc, size, err := ld.deltas.download(ctx, ld.digest, ld.diffID, ld.ID(), progressOutput)
+		if err == nil {
+			return rc, size, nil
+		}
+		if err != errNoDelta {
+			logrus.Warnf("Error downloading delta of %s, downloading the full layer: %v", ld.digest, err)
+		}
+		// retries download the full layer
+		ld.deltas = nil
+	}
+
 	var (
 		err    error
 		offset int64
@@ -165,6 +223,8 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 		if err != nil {
 			logrus.Debugf("error seeking to end of download file: %v", err)
 			offset = 0
+			// the download starts over, so does the verification
+			ld.verifier = nil
 
 			ld.tmpFile.Close()
 			if err := os.Remove(ld.tmpFile.Name()); err != nil {
@@ -180,9 +240,8 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 	}
 
 	tmpFile := ld.tmpFile
-	blobs := ld.repo.Blobs(ctx)
 
-	layerDownload, err := blobs.Open(ctx, ld.digest)
+	layerDownload, err := ld.open(ctx)
 	if err != nil {
 		logrus.Errorf("Error initiating layer download: %v", err)
 		if err == distribution.ErrBlobUnknown {
@@ -224,7 +283,11 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 		}
 	}
 
-	reader := progress.NewProgressReader(ioutils.NewCancelReadCloser(ctx, layerDownload), progressOutput, size-offset, ld.ID(), "Downloading")
+	if offset != 0 {
+		progress.Updatef(progressOutput, ld.ID(), "Resuming download at %s", units.HumanSize(float64(offset)))
+	}
+
+	reader := progress.NewProgressReaderAt(ioutils.NewCancelReadCloser(ctx, layerDownload), progressOutput, size, offset, ld.ID(), "Downloading")
 	defer reader.Close()
 
 	if ld.verifier == nil {
@@ -517,7 +580,15 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 		configChan <- configJSON
 	}()
 
-	var descriptors []xfer.DownloadDescriptor
+	var deltas *layerDeltas
+	if p.config.Delta != nil {
+		deltas = p.layerDeltas(ctx, ref)
+	}
+
+	var (
+		descriptors      []xfer.DownloadDescriptor
+		layerDescriptors []*v2LayerDescriptor
+	)
 
 	// Note that the order of this loop is in the direction of bottom-most
 	// to top-most, so that the downloads slice gets ordered correctly.
@@ -527,9 +598,11 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 			repo:              p.repo,
 			repoInfo:          p.repoInfo,
 			V2MetadataService: p.V2MetadataService,
+			src:               d,
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
+		layerDescriptors = append(layerDescriptors, layerDescriptor)
 	}
 
 	var (
@@ -551,6 +624,23 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 		downloadRootFS = *image.NewRootFS()
 	}
 
+	// The diffIDs of the layers, which the binary diffs are verified
+	// against, are in the config.
+	if deltas != nil {
+		if configJSON == nil {
+			configJSON, unmarshalledConfig, err = receiveConfig(configChan, errChan)
+			if err != nil {
+				return "", "", err
+			}
+		}
+		if unmarshalledConfig.RootFS != nil && len(unmarshalledConfig.RootFS.DiffIDs) == len(layerDescriptors) {
+			for i, ld := range layerDescriptors {
+				ld.deltas = deltas
+				ld.diffID = unmarshalledConfig.RootFS.DiffIDs[i]
+			}
+		}
+	}
+
 	rootFS, release, err := p.config.DownloadManager.Download(ctx, downloadRootFS, descriptors, p.config.ProgressOutput)
 	if err != nil {
 		if configJSON != nil {
@@ -622,20 +712,12 @@ func (p *v2Puller) pullManifestList(ctx context.Context, ref reference.Named, mf
 		return "", "", err
 	}
 
-	var manifestDigest digest.Digest
-	for _, manifestDescriptor := range mfstList.Manifests {
-		// TODO(aaronl): The manifest list spec supports optional
-		// "features" and "variant" fields. These are not yet used.
-		// Once they are, their values should be interpreted here.
-		if manifestDescriptor.Platform.Architecture == runtime.GOARCH && manifestDescriptor.Platform.OS == runtime.GOOS {
-			manifestDigest = manifestDescriptor.Digest
-			break
-		}
-	}
-
-	if manifestDigest == "" {
-		return "", "", errors.New("no supported platform found in manifest list")
+	manifestDescriptor, ok := selectManifest(mfstList.Manifests, runtime.GOOS, runtime.GOARCH, platformVariant())
+	if !ok {
+		return "", "", fmt.Errorf("no matching manifest for %s/%s in the manifest list entries", runtime.GOOS, runtime.GOARCH)
 	}
+	manifestDigest := manifestDescriptor.Digest
+	logrus.Debugf("%s resolves to %s for platform %s/%s", ref.String(), manifestDigest, runtime.GOOS, runtime.GOARCH)
 
 	manSvc, err := p.repo.Manifests(ctx)
 	if err != nil {
@@ -670,6 +752,33 @@ func (p *v2Puller) pullManifestList(ctx context.Context, ref reference.Named, mf
 	return imageID, manifestListDigest, err
 }
 
+// selectManifest returns the entry of a manifest list for the platform
+// os/arch. An entry of the exact CPU variant is preferred, otherwise one
+// without a variant, otherwise the first one for the platform. The optional
+// CPU and OS features are not interpreted.
+func selectManifest(manifests []manifestlist.ManifestDescriptor, os, arch, variant string) (manifestlist.ManifestDescriptor, bool) {
+	var (
+		selected manifestlist.ManifestDescriptor
+		score    int
+	)
+	for _, m := range manifests {
+		if m.Platform.OS != os || m.Platform.Architecture != arch {
+			continue
+		}
+		s := 1
+		switch m.Platform.Variant {
+		case variant:
+			s = 3
+		case "":
+			s = 2
+		}
+		if s > score {
+			selected, score = m, s
+		}
+	}
+	return selected, score > 0
+}
+
 func (p *v2Puller) pullSchema2ImageConfig(ctx context.Context, dgst digest.Digest) (configJSON []byte, err error) {
 	blobs := p.repo.Blobs(ctx)
 	configJSON, err = blobs.Get(ctx, dgst)
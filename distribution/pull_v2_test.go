@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
 	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/docker/reference"
 )
@@ -181,3 +182,46 @@ func TestValidateManifest(t *testing.T) {
 		t.Fatal("expected validateManifest to fail with digest error")
 	}
 }
+
+func TestSelectManifest(t *testing.T) {
+	entry := func(dgst, os, arch, variant string) manifestlist.ManifestDescriptor {
+		m := manifestlist.ManifestDescriptor{
+			Platform: manifestlist.PlatformSpec{OS: os, Architecture: arch, Variant: variant},
+		}
+		m.Digest = digest.Digest(dgst)
+		return m
+	}
+	manifests := []manifestlist.ManifestDescriptor{
+		entry("sha256:amd64", "linux", "amd64", ""),
+		entry("sha256:armv6", "linux", "arm", "v6"),
+		entry("sha256:armv7", "linux", "arm", "v7"),
+		entry("sha256:arm64", "linux", "arm64", ""),
+		entry("sha256:arm64v8", "linux", "arm64", "v8"),
+		entry("sha256:windows", "windows", "amd64", ""),
+	}
+
+	tests := []struct {
+		os, arch, variant string
+		expected          string
+	}{
+		{"linux", "amd64", "", "sha256:amd64"},
+		{"windows", "amd64", "", "sha256:windows"},
+		{"linux", "arm", "v7", "sha256:armv7"},
+		{"linux", "arm", "v8", "sha256:armv6"},
+		{"linux", "arm64", "v8", "sha256:arm64v8"},
+		{"linux", "arm64", "v9", "sha256:arm64"},
+		{"linux", "ppc64le", "", ""},
+	}
+	for _, test := range tests {
+		m, ok := selectManifest(manifests, test.os, test.arch, test.variant)
+		if test.expected == "" {
+			if ok {
+				t.Fatalf("expected no manifest for %s/%s, got %s", test.os, test.arch, m.Digest)
+			}
+			continue
+		}
+		if !ok || m.Digest.String() != test.expected {
+			t.Fatalf("expected %s for %s/%s/%s, got %s", test.expected, test.os, test.arch, test.variant, m.Digest)
+		}
+	}
+}
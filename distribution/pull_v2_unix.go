@@ -3,6 +3,11 @@
 package distribution
 
 import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+
 	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/docker/image"
 )
@@ -10,3 +15,27 @@ import (
 func detectBaseLayer(is image.Store, m *schema1.Manifest, rootFS *image.RootFS) error {
 	return nil
 }
+
+// platformVariant returns the CPU variant of the host, as used in the
+// platform of manifest list entries, or "" if there is none.
+func platformVariant() string {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "v8"
+	case "arm":
+		// the variant of 32-bit ARM is the version of the CPU architecture
+		f, err := os.Open("/proc/cpuinfo")
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			parts := strings.SplitN(scanner.Text(), ":", 2)
+			if len(parts) == 2 && strings.TrimSpace(parts[0]) == "CPU architecture" {
+				return "v" + strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	return ""
+}
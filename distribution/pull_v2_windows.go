@@ -28,3 +28,9 @@ func detectBaseLayer(is image.Store, m *schema1.Manifest, rootFS *image.RootFS)
 	}
 	return fmt.Errorf("Invalid base layer %q", v1img.Parent)
 }
+
+// platformVariant returns the CPU variant of the host, there is none on
+// Windows.
+func platformVariant() string {
+	return ""
+}
@@ -0,0 +1,213 @@
+package distribution
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	"github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+// ManifestListPushConfig stores manifest list push configuration.
+type ManifestListPushConfig struct {
+	// MetaHeaders store HTTP headers with metadata about the manifest list
+	MetaHeaders map[string][]string
+	// AuthConfig holds authentication credentials for authenticating with
+	// the registry.
+	AuthConfig *types.AuthConfig
+	// ProgressOutput is the interface for showing the status of the push
+	// operation.
+	ProgressOutput progress.Output
+	// RegistryService is the registry service to use for TLS configuration
+	// and endpoint lookup.
+	RegistryService *registry.Service
+	// ImageEventLogger notifies events for a given image
+	ImageEventLogger func(id, name, action string)
+}
+
+// PushManifestList creates a manifest list referencing the manifests of
+// images, and pushes it to the registry as ref. The images are looked up in
+// the registry, not locally, and must be in the repository of ref: a
+// registry only accepts manifest lists referencing manifests of the same
+// repository.
+func PushManifestList(ctx context.Context, ref reference.NamedTagged, images []types.ManifestListEntry, config *ManifestListPushConfig) error {
+	if len(images) == 0 {
+		return fmt.Errorf("manifest list %s has no images", ref.String())
+	}
+
+	repoInfo, err := config.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return err
+	}
+
+	refs := make([]reference.Named, len(images))
+	for i, entry := range images {
+		imageRef, err := reference.ParseNamed(entry.Image)
+		if err != nil {
+			return err
+		}
+		if imageRef.Name() != ref.Name() {
+			return fmt.Errorf("%s is not in repository %s, the images of a manifest list must be pushed to its repository", entry.Image, ref.Name())
+		}
+		refs[i] = reference.WithDefaultTag(imageRef)
+	}
+
+	endpoints, err := config.RegistryService.LookupPushEndpoints(repoInfo.Hostname())
+	if err != nil {
+		return err
+	}
+
+	progress.Messagef(config.ProgressOutput, "", "The push refers to a repository [%s]", repoInfo.FullName())
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		if endpoint.Version == registry.APIVersion1 {
+			// manifest lists are a v2 feature
+			continue
+		}
+
+		repo, _, err := NewV2Repository(ctx, repoInfo, endpoint, config.MetaHeaders, config.AuthConfig, "push", "pull")
+		if err != nil {
+			if fallbackErr, ok := err.(fallbackError); ok {
+				lastErr = fallbackErr.err
+				logrus.Errorf("Attempting next endpoint for manifest list push after error: %v", lastErr)
+				continue
+			}
+			return err
+		}
+
+		if err := pushManifestList(ctx, repo, ref, refs, images, config.ProgressOutput); err != nil {
+			return err
+		}
+		config.ImageEventLogger(ref.String(), repoInfo.Name(), "push")
+		return nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no v2 endpoints found for %s", repoInfo.FullName())
+	}
+	return lastErr
+}
+
+func pushManifestList(ctx context.Context, repo distribution.Repository, ref reference.NamedTagged, refs []reference.Named, images []types.ManifestListEntry, progressOutput progress.Output) error {
+	manSvc, err := repo.Manifests(ctx)
+	if err != nil {
+		return err
+	}
+
+	descriptors := make([]manifestlist.ManifestDescriptor, len(images))
+	for i, imageRef := range refs {
+		descriptor, err := manifestListEntry(ctx, repo, manSvc, imageRef)
+		if err != nil {
+			return err
+		}
+		descriptor.Platform = mergePlatform(descriptor.Platform, images[i].Platform)
+		if descriptor.Platform.OS == "" || descriptor.Platform.Architecture == "" {
+			return fmt.Errorf("the platform of %s is unknown, set its OS and architecture", imageRef.String())
+		}
+		progress.Messagef(progressOutput, "", "%s: %s/%s %s", imageRef.String(), descriptor.Platform.OS, descriptor.Platform.Architecture, descriptor.Digest)
+		descriptors[i] = descriptor
+	}
+
+	list, err := manifestlist.FromDescriptors(descriptors)
+	if err != nil {
+		return err
+	}
+	if _, err := manSvc.Put(ctx, list, distribution.WithTag(ref.Tag())); err != nil {
+		return err
+	}
+
+	_, canonical, err := list.Payload()
+	if err != nil {
+		return err
+	}
+	listDigest := digest.FromBytes(canonical)
+	progress.Messagef(progressOutput, "", "%s: digest: %s size: %d", ref.Tag(), listDigest, len(canonical))
+	progress.Aux(progressOutput, PushResult{Tag: ref.Tag(), Digest: listDigest, Size: len(canonical)})
+	return nil
+}
+
+// manifestListEntry returns the manifest list entry of the image ref, with
+// the platform recorded in its configuration.
+func manifestListEntry(ctx context.Context, repo distribution.Repository, manSvc distribution.ManifestService, ref reference.Named) (manifestlist.ManifestDescriptor, error) {
+	var (
+		manifest distribution.Manifest
+		err      error
+	)
+	if digested, isDigested := ref.(reference.Canonical); isDigested {
+		manifest, err = manSvc.Get(ctx, digested.Digest())
+	} else if tagged, isTagged := ref.(reference.NamedTagged); isTagged {
+		manifest, err = manSvc.Get(ctx, "", distribution.WithTag(tagged.Tag()))
+	} else {
+		return manifestlist.ManifestDescriptor{}, fmt.Errorf("internal error: reference has neither a tag nor a digest: %s", ref.String())
+	}
+	if err != nil {
+		return manifestlist.ManifestDescriptor{}, fmt.Errorf("could not find %s in the registry: %v", ref.String(), err)
+	}
+
+	m, ok := manifest.(*schema2.DeserializedManifest)
+	if !ok {
+		// schema1 manifests are signed for their tag, and a manifest list
+		// cannot be nested
+		return manifestlist.ManifestDescriptor{}, fmt.Errorf("%s does not have a schema2 image manifest, push it again with a newer docker", ref.String())
+	}
+	mediaType, canonical, err := m.Payload()
+	if err != nil {
+		return manifestlist.ManifestDescriptor{}, err
+	}
+
+	configJSON, err := repo.Blobs(ctx).Get(ctx, m.Config.Digest)
+	if err != nil {
+		return manifestlist.ManifestDescriptor{}, fmt.Errorf("error getting the configuration of %s: %v", ref.String(), err)
+	}
+	var img image.Image
+	if err := json.Unmarshal(configJSON, &img); err != nil {
+		return manifestlist.ManifestDescriptor{}, err
+	}
+
+	descriptor := manifestlist.ManifestDescriptor{
+		Platform: manifestlist.PlatformSpec{
+			Architecture: img.Architecture,
+			OS:           img.OS,
+			OSVersion:    img.OSVersion,
+			OSFeatures:   img.OSFeatures,
+		},
+	}
+	descriptor.MediaType = mediaType
+	descriptor.Digest = digest.FromBytes(canonical)
+	descriptor.Size = int64(len(canonical))
+	return descriptor, nil
+}
+
+// mergePlatform returns the platform p with the fields set in override
+// replaced.
+func mergePlatform(p manifestlist.PlatformSpec, override types.ManifestPlatform) manifestlist.PlatformSpec {
+	if override.Architecture != "" {
+		p.Architecture = override.Architecture
+	}
+	if override.OS != "" {
+		p.OS = override.OS
+	}
+	if override.OSVersion != "" {
+		p.OSVersion = override.OSVersion
+	}
+	if len(override.OSFeatures) > 0 {
+		p.OSFeatures = override.OSFeatures
+	}
+	if override.Variant != "" {
+		p.Variant = override.Variant
+	}
+	if len(override.Features) > 0 {
+		p.Features = override.Features
+	}
+	return p
+}
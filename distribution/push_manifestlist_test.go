@@ -0,0 +1,38 @@
+package distribution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/engine-api/types"
+)
+
+func TestMergePlatform(t *testing.T) {
+	configured := manifestlist.PlatformSpec{
+		Architecture: "amd64",
+		OS:           "windows",
+		OSVersion:    "10.0.14393",
+	}
+
+	if p := mergePlatform(configured, types.ManifestPlatform{}); !reflect.DeepEqual(p, configured) {
+		t.Fatalf("expected the configured platform %+v, got %+v", configured, p)
+	}
+
+	p := mergePlatform(configured, types.ManifestPlatform{
+		Architecture: "arm",
+		OS:           "linux",
+		Variant:      "v7",
+		Features:     []string{"neon"},
+	})
+	expected := manifestlist.PlatformSpec{
+		Architecture: "arm",
+		OS:           "linux",
+		OSVersion:    "10.0.14393",
+		Variant:      "v7",
+		Features:     []string{"neon"},
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("expected the platform %+v, got %+v", expected, p)
+	}
+}
@@ -240,6 +240,15 @@ func (pd *v2PushDescriptor) DiffID() layer.DiffID {
 }
 
 func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.Output) (distribution.Descriptor, error) {
+	// Foreign layers are downloaded from their URLs, they are not pushed
+	// to the registry.
+	if fs, ok := pd.layer.(distribution.Describable); ok {
+		if d := fs.Descriptor(); len(d.URLs) > 0 {
+			progress.Update(progressOutput, pd.ID(), "Skipped foreign layer")
+			return d, nil
+		}
+	}
+
 	diffID := pd.DiffID()
 
 	pd.pushState.Lock()
@@ -276,24 +285,14 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 	bs := pd.repo.Blobs(ctx)
 
 	var layerUpload distribution.BlobWriter
-	mountAttemptsRemaining := 3
 
 	// Attempt to find another repository in the same registry to mount the layer
 	// from to avoid an unnecessary upload.
-	// Note: metadata is stored from oldest to newest, so we iterate through this
-	// slice in reverse to maximize our chances of the blob still existing in the
-	// remote repository.
-	for i := len(v2Metadata) - 1; i >= 0 && mountAttemptsRemaining > 0; i-- {
-		mountFrom := v2Metadata[i]
-
+	for _, mountFrom := range mountCandidates(pd.repoInfo, v2Metadata, maxMountAttempts) {
 		sourceRepo, err := reference.ParseNamed(mountFrom.SourceRepository)
 		if err != nil {
 			continue
 		}
-		if pd.repoInfo.Hostname() != sourceRepo.Hostname() {
-			// don't mount blobs from another registry
-			continue
-		}
 
 		namedRef, err := reference.WithName(mountFrom.SourceRepository)
 		if err != nil {
@@ -334,12 +333,13 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 			return err.Descriptor, nil
 		case nil:
 			// blob upload session created successfully, so begin the upload
-			mountAttemptsRemaining = 0
 		default:
 			// unable to mount layer from this repository, so this source mapping is no longer valid
 			logrus.Debugf("unassociating layer %s (%s) with %s", diffID, mountFrom.Digest, mountFrom.SourceRepository)
 			pd.v2MetadataService.Remove(mountFrom)
-			mountAttemptsRemaining--
+		}
+		if layerUpload != nil {
+			break
 		}
 	}
 
@@ -414,6 +414,41 @@ func (pd *v2PushDescriptor) Descriptor() distribution.Descriptor {
 	return pd.remoteDescriptor
 }
 
+// maxMountAttempts is the number of repositories a layer is tried to be
+// mounted from before it is uploaded.
+const maxMountAttempts = 3
+
+// mountCandidates returns the metadata of the layer for the other
+// repositories of the registry of repoInfo, which the layer may be mounted
+// from. The candidates are the most recent ones first, since they are the
+// most likely to still have the layer, and there is at most one for each
+// repository. The repository of repoInfo itself is left out, the layer was
+// already looked up in it.
+func mountCandidates(repoInfo reference.Named, v2Metadata []metadata.V2Metadata, max int) []metadata.V2Metadata {
+	var (
+		candidates []metadata.V2Metadata
+		seen       = make(map[string]bool)
+	)
+	// metadata is stored from oldest to newest
+	for i := len(v2Metadata) - 1; i >= 0 && len(candidates) < max; i-- {
+		meta := v2Metadata[i]
+		sourceRepo, err := reference.ParseNamed(meta.SourceRepository)
+		if err != nil {
+			continue
+		}
+		if sourceRepo.Hostname() != repoInfo.Hostname() {
+			// don't mount blobs from another registry
+			continue
+		}
+		if sourceRepo.FullName() == repoInfo.FullName() || seen[sourceRepo.FullName()] {
+			continue
+		}
+		seen[sourceRepo.FullName()] = true
+		candidates = append(candidates, meta)
+	}
+	return candidates
+}
+
 // layerAlreadyExists checks if the registry already know about any of the
 // metadata passed in the "metadata" slice. If it finds one that the registry
 // knows about, it returns the known digest and "true".
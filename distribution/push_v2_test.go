@@ -0,0 +1,84 @@
+package distribution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/reference"
+	"golang.org/x/net/context"
+)
+
+func TestMountCandidates(t *testing.T) {
+	repoInfo, err := reference.ParseNamed("registry.example.com/team/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := func(dgst, repo string) metadata.V2Metadata {
+		return metadata.V2Metadata{Digest: digest.Digest(dgst), SourceRepository: repo}
+	}
+
+	// from oldest to newest
+	v2Metadata := []metadata.V2Metadata{
+		meta("sha256:1", "registry.example.com/team/base"),
+		meta("sha256:2", "docker.io/library/busybox"),
+		meta("sha256:3", "registry.example.com/team/app"),
+		meta("sha256:4", "registry.example.com/team/base"),
+		meta("sha256:5", "registry.example.com/other/tool"),
+		meta("sha256:6", "invalid repository name"),
+		meta("sha256:7", "registry.example.com/team/lib"),
+	}
+
+	expected := []metadata.V2Metadata{
+		meta("sha256:7", "registry.example.com/team/lib"),
+		meta("sha256:5", "registry.example.com/other/tool"),
+		meta("sha256:4", "registry.example.com/team/base"),
+	}
+	if candidates := mountCandidates(repoInfo, v2Metadata, 5); !reflect.DeepEqual(candidates, expected) {
+		t.Fatalf("expected mount candidates %v, got %v", expected, candidates)
+	}
+	if candidates := mountCandidates(repoInfo, v2Metadata, 2); !reflect.DeepEqual(candidates, expected[:2]) {
+		t.Fatalf("expected mount candidates %v, got %v", expected[:2], candidates)
+	}
+	if candidates := mountCandidates(repoInfo, nil, 3); len(candidates) != 0 {
+		t.Fatalf("expected no mount candidates, got %v", candidates)
+	}
+}
+
+type foreignLayer struct {
+	layer.Layer
+	descriptor distribution.Descriptor
+}
+
+func (l foreignLayer) DiffID() layer.DiffID {
+	return layer.DiffID(l.descriptor.Digest)
+}
+
+func (l foreignLayer) Descriptor() distribution.Descriptor {
+	return l.descriptor
+}
+
+func TestPushSkipsForeignLayers(t *testing.T) {
+	descriptor := distribution.Descriptor{
+		MediaType: schema2.MediaTypeForeignLayer,
+		Size:      1024,
+		Digest:    digest.Digest("sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"),
+		URLs:      []string{"https://foreign.example.com/layer.tar"},
+	}
+	// the repository and the push state are not used for foreign layers
+	pd := &v2PushDescriptor{layer: foreignLayer{descriptor: descriptor}}
+
+	progressChan := make(chan progress.Progress, 10)
+	d, err := pd.Upload(context.Background(), progress.ChanOutput(progressChan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, descriptor) {
+		t.Fatalf("expected descriptor %#v, got %#v", descriptor, d)
+	}
+}
@@ -0,0 +1,226 @@
+package distribution
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/registry/client/auth"
+	"github.com/docker/distribution/registry/client/transport"
+	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+	"github.com/docker/notary/client"
+	"github.com/docker/notary/passphrase"
+	"github.com/docker/notary/tuf/data"
+	"golang.org/x/net/context"
+)
+
+var releasesRole = path.Join(data.CanonicalTargetsRole, "releases")
+
+// ContentTrustConfig configures the verification of the signatures of the
+// images pulled, against the trust data of their repository on a notary
+// server.
+type ContentTrustConfig struct {
+	// Server is the URL of the notary server. If empty, the notary server
+	// of Docker Hub is used for official images, and the registry of the
+	// image is used as notary server for the others.
+	Server string
+	// TrustDir is the directory the trust data is cached in. The root keys
+	// of the repositories are pinned there on first use.
+	TrustDir string
+}
+
+// trustedPull is a pull of an image whose signature was verified.
+type trustedPull struct {
+	// ref is the reference the image was asked for
+	ref reference.Named
+	// trustedRef is the signed digest of ref
+	trustedRef reference.Canonical
+	// rootKeyIDs are the IDs of the root keys of the repository
+	rootKeyIDs []string
+}
+
+// verifyTrust resolves ref to the digest signed for it in the trust data of
+// its repository. A digested reference is only accepted if its digest is
+// signed for one of the tags of the repository.
+func verifyTrust(ctx context.Context, repoInfo *registry.RepositoryInfo, ref reference.Named, config *ImagePullConfig) (*trustedPull, error) {
+	notaryRepo, err := newNotaryRepository(ctx, repoInfo, config)
+	if err != nil {
+		return nil, fmt.Errorf("error establishing connection to trust repository: %v", err)
+	}
+
+	var dgst digest.Digest
+	switch r := ref.(type) {
+	case reference.Canonical:
+		targets, err := notaryRepo.ListTargets(releasesRole, data.CanonicalTargetsRole)
+		if err != nil {
+			return nil, trustError(repoInfo.FullName(), err)
+		}
+		for _, t := range targets {
+			// only trust the top level targets role and the releases
+			// delegation role
+			if t.Role != releasesRole && t.Role != data.CanonicalTargetsRole {
+				continue
+			}
+			if d, err := targetDigest(t.Target); err == nil && d == r.Digest() {
+				dgst = d
+				break
+			}
+		}
+		if dgst == "" {
+			return nil, fmt.Errorf("%s is not signed in the trust data of %s", r.Digest(), repoInfo.FullName())
+		}
+	case reference.NamedTagged:
+		t, err := notaryRepo.GetTargetByName(r.Tag(), releasesRole, data.CanonicalTargetsRole)
+		if err != nil {
+			return nil, trustError(repoInfo.FullName(), err)
+		}
+		if t.Role != releasesRole && t.Role != data.CanonicalTargetsRole {
+			return nil, trustError(repoInfo.FullName(), fmt.Errorf("no trust data for %s", r.Tag()))
+		}
+		if dgst, err = targetDigest(t.Target); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("content trust requires a tag or a digest to pull %s", ref.String())
+	}
+
+	roles, err := notaryRepo.ListRoles()
+	if err != nil {
+		return nil, trustError(repoInfo.FullName(), err)
+	}
+	var rootKeyIDs []string
+	for _, role := range roles {
+		if role.Name == data.CanonicalRootRole {
+			rootKeyIDs = append(rootKeyIDs, role.KeyIDs...)
+		}
+	}
+	sort.Strings(rootKeyIDs)
+
+	trustedRef, err := reference.WithDigest(ref, dgst)
+	if err != nil {
+		return nil, err
+	}
+	return &trustedPull{ref: ref, trustedRef: trustedRef, rootKeyIDs: rootKeyIDs}, nil
+}
+
+// record tags the pulled image, and records the root keys its signature
+// was verified with.
+func (tp *trustedPull) record(repoInfo *registry.RepositoryInfo, config *ImagePullConfig) error {
+	imageID, err := config.ReferenceStore.Get(tp.trustedRef)
+	if err != nil {
+		return err
+	}
+	if tagged, isTagged := tp.ref.(reference.NamedTagged); isTagged {
+		progress.Messagef(config.ProgressOutput, "", "Tagging %s as %s", tp.trustedRef.String(), tagged.String())
+		if err := config.ReferenceStore.AddTag(tagged, imageID, true); err != nil {
+			return err
+		}
+	}
+	return metadata.NewTrustService(config.MetadataStore).Add(imageID, metadata.TrustMetadata{
+		Repository: repoInfo.FullName(),
+		RootKeyIDs: tp.rootKeyIDs,
+	})
+}
+
+// trustServer returns the URL of the notary server of a repository.
+func trustServer(repoInfo *registry.RepositoryInfo, config *ContentTrustConfig) (string, error) {
+	if config.Server != "" {
+		urlObj, err := url.Parse(config.Server)
+		if err != nil || urlObj.Scheme != "https" {
+			return "", fmt.Errorf("valid https URL required for trust server, got %s", config.Server)
+		}
+		return config.Server, nil
+	}
+	if repoInfo.Index.Official {
+		return registry.NotaryServer, nil
+	}
+	return "https://" + repoInfo.Index.Name, nil
+}
+
+// newNotaryRepository returns the trust data of a repository, with a HTTP
+// transport authenticating with the credentials of the pull.
+func newNotaryRepository(ctx context.Context, repoInfo *registry.RepositoryInfo, config *ImagePullConfig) (*client.NotaryRepository, error) {
+	server, err := trustServer(repoInfo, config.ContentTrust)
+	if err != nil {
+		return nil, err
+	}
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := config.RegistryService.TLSConfig(serverURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   true,
+	}
+
+	modifiers := registry.DockerHeaders(dockerversion.DockerUserAgent(ctx), config.MetaHeaders)
+	authTransport := transport.NewTransport(base, modifiers...)
+	pingClient := &http.Client{
+		Transport: authTransport,
+		Timeout:   5 * time.Second,
+	}
+	endpointStr := server + "/v2/"
+	req, err := http.NewRequest("GET", endpointStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	challengeManager := auth.NewSimpleChallengeManager()
+
+	resp, err := pingClient.Do(req)
+	if err != nil {
+		// the trust data cached from earlier pulls may be enough
+		logrus.Debugf("Error pinging notary server %q: %s", endpointStr, err)
+	} else {
+		defer resp.Body.Close()
+		if err := challengeManager.AddResponse(resp); err != nil {
+			return nil, err
+		}
+	}
+
+	creds := dumbCredentialStore{auth: config.AuthConfig}
+	tokenHandler := auth.NewTokenHandler(authTransport, creds, repoInfo.FullName(), "pull")
+	basicHandler := auth.NewBasicHandler(creds)
+	modifiers = append(modifiers, auth.NewAuthorizer(challengeManager, tokenHandler, basicHandler))
+	tr := transport.NewTransport(base, modifiers...)
+
+	// pulls never sign, there are no keys to unlock
+	return client.NewNotaryRepository(config.ContentTrust.TrustDir, repoInfo.FullName(), server, tr, passphrase.ConstantRetriever(""))
+}
+
+func targetDigest(t client.Target) (digest.Digest, error) {
+	h, ok := t.Hashes["sha256"]
+	if !ok {
+		return "", errors.New("no valid hash, expecting sha256")
+	}
+	return digest.NewDigestFromHex("sha256", hex.EncodeToString(h)), nil
+}
+
+func trustError(repoName string, err error) error {
+	return fmt.Errorf("content trust verification failed for %s: %v", repoName, err)
+}
@@ -0,0 +1,46 @@
+package distribution
+
+import (
+	"testing"
+
+	"github.com/docker/docker/reference"
+	"github.com/docker/docker/registry"
+)
+
+func TestTrustServer(t *testing.T) {
+	service := registry.NewService(registry.ServiceOptions{})
+	repoInfo := func(name string) *registry.RepositoryInfo {
+		ref, err := reference.ParseNamed(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := service.ResolveRepository(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+
+	testCases := []struct {
+		name     string
+		server   string
+		expected string
+	}{
+		{"busybox", "", registry.NotaryServer},
+		{"example.com/busybox", "", "https://example.com"},
+		{"example.com/busybox", "https://notary.example.com", "https://notary.example.com"},
+	}
+	for _, tc := range testCases {
+		server, err := trustServer(repoInfo(tc.name), &ContentTrustConfig{Server: tc.server})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if server != tc.expected {
+			t.Fatalf("expected trust server %s for %s, got %s", tc.expected, tc.name, server)
+		}
+	}
+
+	if _, err := trustServer(repoInfo("busybox"), &ContentTrustConfig{Server: "http://notary.example.com"}); err == nil {
+		t.Fatal("expected an error for a trust server without https")
+	}
+}
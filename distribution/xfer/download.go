@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/archive"
@@ -33,6 +34,12 @@ func NewLayerDownloadManager(layerStore layer.Store, concurrencyLimit int) *Laye
 	}
 }
 
+// SetConcurrency changes the number of layers that may be downloaded at the
+// same time.
+func (ldm *LayerDownloadManager) SetConcurrency(concurrency int) {
+	ldm.tm.SetConcurrency(concurrency)
+}
+
 type downloadTransfer struct {
 	Transfer
 
@@ -213,7 +220,14 @@ func (ldm *LayerDownloadManager) makeDownloadFunc(descriptor DownloadDescriptor,
 			case <-start:
 			default:
 				progress.Update(progressOutput, descriptor.ID(), "Waiting")
-				<-start
+				select {
+				case <-start:
+				case <-d.Transfer.Context().Done():
+					// give up the place in the queue to the transfers
+					// that are still wanted
+					d.err = errors.New("download cancelled while waiting")
+					return
+				}
 			}
 
 			if parentDownload != nil {
@@ -313,7 +327,15 @@ func (ldm *LayerDownloadManager) makeDownloadFunc(descriptor DownloadDescriptor,
 				return
 			}
 
-			d.layer, err = d.layerStore.Register(inflatedLayerData, parentLayer)
+			var src distribution.Descriptor
+			if fs, ok := descriptor.(distribution.Describable); ok {
+				src = fs.Descriptor()
+			}
+			if ds, ok := d.layerStore.(layer.DescribableStore); ok {
+				d.layer, err = ds.RegisterWithDescriptor(inflatedLayerData, parentLayer, src)
+			} else {
+				d.layer, err = d.layerStore.Register(inflatedLayerData, parentLayer)
+			}
 			if err != nil {
 				select {
 				case <-d.Transfer.Context().Done():
@@ -363,7 +385,12 @@ func (ldm *LayerDownloadManager) makeDownloadFuncFromDownload(descriptor Downloa
 				close(progressChan)
 			}()
 
-			<-start
+			select {
+			case <-start:
+			case <-d.Transfer.Context().Done():
+				d.err = errors.New("layer registration cancelled")
+				return
+			}
 
 			close(inactive)
 
@@ -404,7 +431,15 @@ func (ldm *LayerDownloadManager) makeDownloadFuncFromDownload(descriptor Downloa
 			}
 			defer layerReader.Close()
 
-			d.layer, err = d.layerStore.Register(layerReader, parentLayer)
+			var src distribution.Descriptor
+			if fs, ok := l.(distribution.Describable); ok {
+				src = fs.Descriptor()
+			}
+			if ds, ok := d.layerStore.(layer.DescribableStore); ok {
+				d.layer, err = ds.RegisterWithDescriptor(layerReader, parentLayer, src)
+			} else {
+				d.layer, err = d.layerStore.Register(layerReader, parentLayer)
+			}
 			if err != nil {
 				d.err = fmt.Errorf("failed to register layer: %v", err)
 				return
@@ -279,6 +279,9 @@ type TransferManager interface {
 	// so, it returns progress and error output from that transfer.
 	// Otherwise, it will call xferFunc to initiate the transfer.
 	Transfer(key string, xferFunc DoFunc, progressOutput progress.Output) (Transfer, *Watcher)
+	// SetConcurrency changes the number of transfers that may run at the
+	// same time.
+	SetConcurrency(concurrency int)
 }
 
 type transferManager struct {
@@ -298,6 +301,22 @@ func NewTransferManager(concurrencyLimit int) TransferManager {
 	}
 }
 
+// SetConcurrency changes the number of transfers that may run at the same
+// time. Transfers which are already running are not interrupted when the
+// limit is lowered.
+func (tm *transferManager) SetConcurrency(concurrency int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.concurrencyLimit = concurrency
+	// Start waiting transfers if the limit was raised
+	for tm.activeTransfers < tm.concurrencyLimit && len(tm.waitingTransfers) != 0 {
+		close(tm.waitingTransfers[0])
+		tm.waitingTransfers = tm.waitingTransfers[1:]
+		tm.activeTransfers++
+	}
+}
+
 // Transfer checks if a transfer matching the given key is in progress. If not,
 // it starts one by calling xferFunc. The caller supplies a channel which
 // receives progress output from the transfer.
@@ -380,13 +399,22 @@ func (tm *transferManager) inactivate(start chan struct{}) {
 	// count.
 	select {
 	case <-start:
-		// Start next transfer if any are waiting
-		if len(tm.waitingTransfers) != 0 {
+		// Start next transfer if any are waiting, unless the limit was
+		// lowered below the number of active transfers
+		if len(tm.waitingTransfers) != 0 && tm.activeTransfers <= tm.concurrencyLimit {
 			close(tm.waitingTransfers[0])
 			tm.waitingTransfers = tm.waitingTransfers[1:]
 		} else {
 			tm.activeTransfers--
 		}
 	default:
+		// The transfer was cancelled before it was started, so it must
+		// not be given a slot.
+		for i, waiting := range tm.waitingTransfers {
+			if waiting == start {
+				tm.waitingTransfers = append(tm.waitingTransfers[:i], tm.waitingTransfers[i+1:]...)
+				break
+			}
+		}
 	}
 }
@@ -125,6 +125,98 @@ func TestConcurrencyLimit(t *testing.T) {
 	}
 }
 
+func TestSetConcurrency(t *testing.T) {
+	var runningJobs int32
+	releases := make(map[string]chan struct{})
+
+	makeXferFunc := func(id string) DoFunc {
+		release := make(chan struct{})
+		releases[id] = release
+		return func(progressChan chan<- progress.Progress, start <-chan struct{}, inactive chan<- struct{}) Transfer {
+			xfer := NewTransfer()
+			go func() {
+				<-start
+				atomic.AddInt32(&runningJobs, 1)
+				<-release
+				atomic.AddInt32(&runningJobs, -1)
+				close(progressChan)
+			}()
+			return xfer
+		}
+	}
+
+	waitRunning := func(expected int32) {
+		for i := 0; i < 100; i++ {
+			if atomic.LoadInt32(&runningJobs) == expected {
+				// make sure no other job starts behind our back
+				time.Sleep(20 * time.Millisecond)
+				if running := atomic.LoadInt32(&runningJobs); running != expected {
+					t.Fatalf("%d jobs running instead of %d", running, expected)
+				}
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%d jobs running instead of %d", atomic.LoadInt32(&runningJobs), expected)
+	}
+
+	tm := NewTransferManager(1)
+	progressChan := make(chan progress.Progress)
+	progressDone := make(chan struct{})
+	go func() {
+		for range progressChan {
+		}
+		close(progressDone)
+	}()
+
+	ids := []string{"id1", "id2", "id3", "id4", "id5"}
+	xfers := make(map[string]Transfer)
+	watchers := make(map[string]*Watcher)
+	startTransfer := func(id string) {
+		xfers[id], watchers[id] = tm.Transfer(id, makeXferFunc(id), progress.ChanOutput(progressChan))
+	}
+	finishTransfer := func(id string) {
+		close(releases[id])
+		<-xfers[id].Done()
+		xfers[id].Release(watchers[id])
+	}
+
+	// Raising the limit starts waiting transfers
+	startTransfer("id1")
+	startTransfer("id2")
+	startTransfer("id3")
+	waitRunning(1)
+	tm.SetConcurrency(3)
+	waitRunning(3)
+
+	// Lowering the limit does not interrupt running transfers, but waiting
+	// transfers only start once enough of them are finished
+	tm.SetConcurrency(1)
+	startTransfer("id4")
+	startTransfer("id5")
+	waitRunning(3)
+	finishTransfer("id1")
+	waitRunning(2)
+	finishTransfer("id2")
+	waitRunning(1)
+	finishTransfer("id3")
+	waitRunning(1)
+	finishTransfer("id4")
+	waitRunning(1)
+	finishTransfer("id5")
+	waitRunning(0)
+
+	for _, id := range ids {
+		select {
+		case <-xfers[id].Done():
+		default:
+			t.Fatalf("transfer %s did not finish", id)
+		}
+	}
+	close(progressChan)
+	<-progressDone
+}
+
 func TestInactiveJobs(t *testing.T) {
 	concurrencyLimit := 3
 	var runningJobs int32
@@ -187,6 +279,60 @@ func TestInactiveJobs(t *testing.T) {
 	}
 }
 
+func TestCancelWaitingTransfer(t *testing.T) {
+	blocked := make(chan struct{})
+	var started int32
+
+	makeXferFunc := func(id string) DoFunc {
+		return func(progressChan chan<- progress.Progress, start <-chan struct{}, inactive chan<- struct{}) Transfer {
+			xfer := NewTransfer()
+			go func() {
+				defer close(progressChan)
+				select {
+				case <-start:
+				case <-xfer.Context().Done():
+					return
+				}
+				if id == "waiting" {
+					atomic.AddInt32(&started, 1)
+				}
+				if id == "blocking" {
+					<-blocked
+				}
+			}()
+			return xfer
+		}
+	}
+
+	tm := NewTransferManager(1)
+	blocking, blockingWatcher := tm.Transfer("blocking", makeXferFunc("blocking"), progress.ChanOutput(make(chan progress.Progress, 10)))
+	waiting, waitingWatcher := tm.Transfer("waiting", makeXferFunc("waiting"), progress.ChanOutput(make(chan progress.Progress, 10)))
+
+	// releasing the only watcher cancels the transfer, which must leave the
+	// queue without being started
+	waiting.Release(waitingWatcher)
+	select {
+	case <-waiting.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled transfer did not leave the queue")
+	}
+
+	next, nextWatcher := tm.Transfer("next", makeXferFunc("next"), progress.ChanOutput(make(chan progress.Progress, 10)))
+	close(blocked)
+	<-blocking.Done()
+	blocking.Release(blockingWatcher)
+	select {
+	case <-next.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the transfer after the cancelled one was not started")
+	}
+	next.Release(nextWatcher)
+
+	if atomic.LoadInt32(&started) != 0 {
+		t.Fatal("the cancelled transfer was started")
+	}
+}
+
 func TestWatchRelease(t *testing.T) {
 	ready := make(chan struct{})
 
@@ -26,6 +26,12 @@ func NewLayerUploadManager(concurrencyLimit int) *LayerUploadManager {
 	}
 }
 
+// SetConcurrency changes the number of layers that may be uploaded at the
+// same time.
+func (lum *LayerUploadManager) SetConcurrency(concurrency int) {
+	lum.tm.SetConcurrency(concurrency)
+}
+
 type uploadTransfer struct {
 	Transfer
 
@@ -108,7 +114,14 @@ func (lum *LayerUploadManager) makeUploadFunc(descriptor UploadDescriptor) DoFun
 			case <-start:
 			default:
 				progress.Update(progressOutput, descriptor.ID(), "Waiting")
-				<-start
+				select {
+				case <-start:
+				case <-u.Transfer.Context().Done():
+					// give up the place in the queue to the transfers
+					// that are still wanted
+					u.err = errors.New("upload cancelled while waiting")
+					return
+				}
 			}
 
 			retries := 0
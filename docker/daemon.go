@@ -276,6 +276,10 @@ func (cli *DaemonCli) CmdDaemon(args ...string) error {
 		api.Accept(protoAddrParts[1], l...)
 	}
 
+	if err := startMetricsServer(cli.Config.MetricsAddress); err != nil {
+		logrus.Fatal(err)
+	}
+
 	if err := migrateKey(); err != nil {
 		logrus.Fatal(err)
 	}
@@ -297,6 +301,10 @@ func (cli *DaemonCli) CmdDaemon(args ...string) error {
 		logrus.Fatalf("Error starting daemon: %v", err)
 	}
 
+	if err := startRegistryCacheServer(cli.Config.RegistryCacheAddress, d.RegistryCacheHandler()); err != nil {
+		logrus.Fatal(err)
+	}
+
 	logrus.Info("Daemon has completed initialization")
 
 	logrus.WithFields(logrus.Fields{
@@ -313,6 +321,9 @@ func (cli *DaemonCli) CmdDaemon(args ...string) error {
 			logrus.Errorf("Error reconfiguring the daemon: %v", err)
 			return
 		}
+		if config.IsValueSet("log-level") {
+			setDaemonLogLevel(config.LogLevel)
+		}
 		if config.IsValueSet("debug") {
 			debugEnabled := utils.IsDebugEnabled()
 			switch {
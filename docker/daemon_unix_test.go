@@ -210,3 +210,44 @@ func TestLoadDaemonConfigWithTrueDefaultValuesLeaveDefaults(t *testing.T) {
 		t.Fatal("expected userland proxy to be enabled, got disabled")
 	}
 }
+
+func TestLoadDaemonConfigWithUlimitsAndRuntimes(t *testing.T) {
+	c := &daemon.Config{}
+	common := &cli.CommonFlags{}
+	flags := mflag.NewFlagSet("test", mflag.ContinueOnError)
+	c.InstallFlags(flags, func(s string) string { return s })
+
+	f, err := ioutil.TempFile("", "docker-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := f.Name()
+	f.Write([]byte(`{
+		"default-ulimits": {"nofile": {"Name": "nofile", "Soft": 512, "Hard": 1024}},
+		"runtimes": {"custom": {"path": "/usr/local/bin/custom-runtime"}},
+		"ip-masq": false,
+		"api-cors-header": "*"
+	}`))
+	f.Close()
+
+	loadedConfig, err := loadDaemonCliConfig(c, flags, common, configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadedConfig == nil {
+		t.Fatal("expected configuration, got nil")
+	}
+	if l, ok := loadedConfig.Ulimits["nofile"]; !ok || l.Soft != 512 || l.Hard != 1024 {
+		t.Fatalf("expected nofile ulimit 512:1024, got %v", loadedConfig.Ulimits)
+	}
+	if r, ok := loadedConfig.Runtimes["custom"]; !ok || r.Path != "/usr/local/bin/custom-runtime" {
+		t.Fatalf("expected custom runtime, got %v", loadedConfig.Runtimes)
+	}
+	if loadedConfig.EnableIPMasq {
+		t.Fatal("expected IP masquerading to be disabled")
+	}
+	if loadedConfig.CorsHeaders != "*" {
+		t.Fatalf("expected CORS headers `*`, got %q", loadedConfig.CorsHeaders)
+	}
+}
@@ -0,0 +1,35 @@
+// +build daemon
+
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/metrics"
+)
+
+// startMetricsServer serves the daemon metrics in the Prometheus format on
+// addr. Nothing is served if addr is empty.
+func startMetricsServer(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if err := allocateDaemonPort(addr); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	go func() {
+		logrus.Infof("Serving metrics on %s", l.Addr())
+		if err := http.Serve(l, mux); err != nil {
+			logrus.Errorf("metrics server error: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,32 @@
+// +build daemon
+
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// startRegistryCacheServer serves the images of the daemon over the registry
+// v2 protocol on addr. Nothing is served if addr is empty.
+func startRegistryCacheServer(addr string, handler http.Handler) error {
+	if addr == "" {
+		return nil
+	}
+	if err := allocateDaemonPort(addr); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		logrus.Infof("Serving the registry cache on %s", l.Addr())
+		if err := http.Serve(l, handler); err != nil {
+			logrus.Errorf("registry cache server error: %v", err)
+		}
+	}()
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/docker/distribution/digest"
+	"github.com/docker/docker/layer"
 	"github.com/docker/engine-api/types/container"
 )
 
@@ -82,6 +83,18 @@ func (img *Image) RunConfig() *container.Config {
 	return img.Config
 }
 
+// LastLayer returns the layer added by the last entry of the image's history,
+// or an empty DiffID if that entry did not add a layer.
+func (img *Image) LastLayer() layer.DiffID {
+	if len(img.History) == 0 || img.History[len(img.History)-1].EmptyLayer {
+		return ""
+	}
+	if img.RootFS == nil || len(img.RootFS.DiffIDs) == 0 {
+		return ""
+	}
+	return img.RootFS.DiffIDs[len(img.RootFS.DiffIDs)-1]
+}
+
 // MarshalJSON serializes the image to JSON. It sorts the top-level keys so
 // that JSON that's been manipulated by a push/pull cycle with a legacy
 // registry won't end up with a different key order.
@@ -57,3 +57,22 @@ func TestMarshalKeyOrder(t *testing.T) {
 		t.Fatal("invalid key order in JSON: ", string(b))
 	}
 }
+
+func TestLastLayer(t *testing.T) {
+	rootFS := NewRootFS()
+	rootFS.Append("sha256:base")
+	rootFS.Append("sha256:make")
+
+	img := &Image{RootFS: rootFS, History: []History{{}, {}}}
+	if l := img.LastLayer(); l != "sha256:make" {
+		t.Fatalf("expected the last layer to be sha256:make, got %q", l)
+	}
+	img.History = append(img.History, History{EmptyLayer: true})
+	if l := img.LastLayer(); l != "" {
+		t.Fatalf("expected no layer for an empty history entry, got %q", l)
+	}
+	img.History = nil
+	if l := img.LastLayer(); l != "" {
+		t.Fatalf("expected no layer without history, got %q", l)
+	}
+}
@@ -1,6 +1,8 @@
 package tarexport
 
 import (
+	"archive/tar"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -38,31 +40,25 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	if err := chrootarchive.Untar(inTar, tmpDir, nil); err != nil {
-		return err
-	}
-	// read manifest, if no file then load in legacy mode
-	manifestPath, err := safePath(tmpDir, manifestFileName)
-	if err != nil {
-		return err
-	}
-	manifestFile, err := os.Open(manifestPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return l.legacyLoad(tmpDir, outStream, progressOutput)
-		}
-		return manifestFile.Close()
+	s := &loadSession{
+		tarexporter:    l,
+		tmpDir:         tmpDir,
+		progressOutput: progressOutput,
+		configs:        make(map[string]*image.Image),
 	}
-	defer manifestFile.Close()
+	defer s.release()
 
-	var manifest []manifestItem
-	if err := json.NewDecoder(manifestFile).Decode(&manifest); err != nil {
+	if err := s.read(inTar); err != nil {
 		return err
 	}
+	// if there is no manifest then load in legacy mode
+	if s.manifest == nil {
+		return l.legacyLoad(tmpDir, outStream, progressOutput)
+	}
 
 	var parentLinks []parentLink
 
-	for _, m := range manifest {
+	for _, m := range s.manifest {
 		configPath, err := safePath(tmpDir, m.Config)
 		if err != nil {
 			return err
@@ -135,6 +131,174 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 	return nil
 }
 
+// loadSession reads an archive, loading its layers into the layer store as
+// they are read, without writing them to disk first. This is only possible
+// for the layers that come after the manifest and the configuration of
+// their image, and after their parent layer, as in the archives written by
+// save. The rest of the archive is extracted to tmpDir.
+type loadSession struct {
+	*tarexporter
+	tmpDir         string
+	progressOutput progress.Output
+	manifest       []manifestItem
+	// configs are the image configurations read, by path
+	configs map[string]*image.Image
+	// layers are held until the images referencing them are created
+	layers []layer.Layer
+}
+
+func (s *loadSession) read(inTar io.Reader) (err error) {
+	decompressed, err := archive.DecompressStream(inTar)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	pr, pw := io.Pipe()
+	untarDone := make(chan error, 1)
+	go func() {
+		err := chrootarchive.UntarUncompressed(pr, s.tmpDir, nil)
+		pr.CloseWithError(err)
+		untarDone <- err
+	}()
+	defer func() {
+		pw.CloseWithError(err)
+		if untarErr := <-untarDone; err == nil {
+			err = untarErr
+		}
+	}()
+
+	tr := tar.NewReader(decompressed)
+	tw := tar.NewWriter(pw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(hdr.Name)
+
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA {
+			if filepath.Base(name) == legacyLayerFileName {
+				loaded, err := s.loadLayer(name, tr, hdr.Size)
+				if err != nil {
+					return err
+				}
+				if loaded {
+					continue
+				}
+			} else if name == manifestFileName || s.isConfig(name) {
+				data, err := ioutil.ReadAll(tr)
+				if err != nil {
+					return err
+				}
+				if err := s.readMetadata(name, data); err != nil {
+					return err
+				}
+				if err := writeTarEntry(tw, hdr, bytes.NewReader(data)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := writeTarEntry(tw, hdr, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// readMetadata parses the manifest or an image configuration.
+func (s *loadSession) readMetadata(name string, data []byte) error {
+	if name == manifestFileName {
+		return json.Unmarshal(data, &s.manifest)
+	}
+	img, err := image.NewFromJSON(data)
+	if err != nil {
+		return err
+	}
+	s.configs[name] = img
+	return nil
+}
+
+func (s *loadSession) isConfig(name string) bool {
+	for _, m := range s.manifest {
+		if filepath.Clean(m.Config) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadLayer loads the layer.tar file at name into the layer store, if the
+// image it belongs to and its parent layer are known. It returns false if
+// the layer cannot be loaded yet.
+func (s *loadSession) loadLayer(name string, r io.Reader, size int64) (bool, error) {
+	for _, m := range s.manifest {
+		img, ok := s.configs[filepath.Clean(m.Config)]
+		if !ok || img.RootFS == nil || len(img.RootFS.DiffIDs) != len(m.Layers) {
+			continue
+		}
+		for i, layerPath := range m.Layers {
+			if filepath.Clean(layerPath) != name {
+				continue
+			}
+			diffIDs := img.RootFS.DiffIDs[:i+1]
+			if l, err := s.ls.Get(layer.CreateChainID(diffIDs)); err == nil {
+				s.layers = append(s.layers, l)
+				return true, nil
+			}
+			parent := layer.CreateChainID(diffIDs[:i])
+			if i > 0 {
+				l, err := s.ls.Get(parent)
+				if err != nil {
+					continue
+				}
+				layer.ReleaseAndLog(s.ls, l)
+			}
+
+			inflatedLayerData, err := archive.DecompressStream(r)
+			if err != nil {
+				return false, err
+			}
+			defer inflatedLayerData.Close()
+
+			var layerData io.Reader = inflatedLayerData
+			if s.progressOutput != nil {
+				layerData = progress.NewProgressReader(inflatedLayerData, s.progressOutput, size, stringid.TruncateID(diffIDs[i].String()), "Loading layer")
+			}
+			newLayer, err := s.ls.Register(layerData, parent)
+			if err != nil {
+				return false, err
+			}
+			s.layers = append(s.layers, newLayer)
+			if expected, actual := diffIDs[i], newLayer.DiffID(); expected != actual {
+				return false, fmt.Errorf("invalid diffID for layer %d: expected %q, got %q", i, expected, actual)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// release releases the layers held by the session.
+func (s *loadSession) release() {
+	for _, l := range s.layers {
+		layer.ReleaseAndLog(s.ls, l)
+	}
+	s.layers = nil
+}
+
+func writeTarEntry(tw *tar.Writer, hdr *tar.Header, r io.Reader) error {
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
 func (l *tarexporter) setParentID(id, parentID image.ID) error {
 	img, err := l.is.Get(id)
 	if err != nil {
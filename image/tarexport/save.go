@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/docker/distribution/digest"
@@ -28,6 +29,10 @@ type saveSession struct {
 	outDir      string
 	images      map[image.ID]*imageDescriptor
 	savedLayers map[string]struct{}
+	// layerDirs are the directories of the saved layers, parents first
+	layerDirs []string
+	// layerPaths are the layer.tar files written, by layer chain
+	layerPaths map[layer.ChainID]string
 }
 
 func (l *tarexporter) Save(names []string, outStream io.Writer) error {
@@ -116,6 +121,7 @@ func (l *tarexporter) parseNames(names []string) (map[image.ID]*imageDescriptor,
 
 func (s *saveSession) save(outStream io.Writer) error {
 	s.savedLayers = make(map[string]struct{})
+	s.layerPaths = make(map[layer.ChainID]string)
 
 	// get image json
 	tempDir, err := ioutil.TempDir("", "docker-export-")
@@ -130,6 +136,10 @@ func (s *saveSession) save(outStream io.Writer) error {
 	var manifest []manifestItem
 	var parentLinks []parentLink
 
+	// The manifest and the configurations come first, and the layers
+	// parents first, so that the archive can be loaded as it is read.
+	includeFiles := []string{manifestFileName}
+
 	for id, imageDescr := range s.images {
 		if err = s.saveImage(id); err != nil {
 			return err
@@ -167,6 +177,7 @@ func (s *saveSession) save(outStream io.Writer) error {
 	}
 
 	if len(reposLegacy) > 0 {
+		includeFiles = append(includeFiles, legacyRepositoriesFileName)
 		reposFile := filepath.Join(tempDir, legacyRepositoriesFileName)
 		f, err := os.OpenFile(reposFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
@@ -200,7 +211,15 @@ func (s *saveSession) save(outStream io.Writer) error {
 		return err
 	}
 
-	fs, err := archive.Tar(tempDir, archive.Uncompressed)
+	for _, m := range manifest {
+		includeFiles = append(includeFiles, m.Config)
+	}
+	includeFiles = append(includeFiles, s.layerDirs...)
+
+	fs, err := archive.TarWithOptions(tempDir, &archive.TarOptions{
+		Compression:  archive.Uncompressed,
+		IncludeFiles: includeFiles,
+	})
 	if err != nil {
 		return err
 	}
@@ -284,8 +303,35 @@ func (s *saveSession) saveLayer(id layer.ChainID, legacyImg image.V1Image, creat
 		return err
 	}
 
+	s.savedLayers[legacyImg.ID] = struct{}{}
+	s.layerDirs = append(s.layerDirs, legacyImg.ID)
+
+	// The same layer is in a different directory when it is the top layer
+	// of an image, as the configuration of the image is part of the legacy
+	// ID. It is only written once, the other directories link to it. The
+	// layer is written again where symlinks cannot be created.
+	layerPath := filepath.Join(outDir, legacyLayerFileName)
+	if relPath, exists := s.layerPaths[id]; exists {
+		if err := os.Symlink(filepath.Join("..", relPath), layerPath); err == nil {
+			mtime := createdTime
+			if mtime.Before(time.Unix(0, 0)) {
+				mtime = time.Unix(0, 0)
+			}
+			ts := []syscall.Timespec{syscall.NsecToTimespec(mtime.UnixNano()), syscall.NsecToTimespec(mtime.UnixNano())}
+			if err := system.LUtimesNano(layerPath, ts); err != nil && err != system.ErrNotSupportedPlatform {
+				return err
+			}
+			for _, fname := range []string{"", legacyVersionFileName, legacyConfigFileName} {
+				if err := system.Chtimes(filepath.Join(outDir, fname), createdTime, createdTime); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
 	// serialize filesystem
-	tarFile, err := os.Create(filepath.Join(outDir, legacyLayerFileName))
+	tarFile, err := os.Create(layerPath)
 	if err != nil {
 		return err
 	}
@@ -314,6 +360,6 @@ func (s *saveSession) saveLayer(id layer.ChainID, legacyImg image.V1Image, creat
 		}
 	}
 
-	s.savedLayers[legacyImg.ID] = struct{}{}
+	s.layerPaths[id] = filepath.Join(legacyImg.ID, legacyLayerFileName)
 	return nil
 }
@@ -3,9 +3,13 @@ package main
 import (
 	"archive/tar"
 	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/docker/docker/pkg/integration/checker"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/engine-api/types"
 	"github.com/go-check/check"
 )
 
@@ -255,3 +259,67 @@ func (s *DockerSuite) TestBuildApiDockerfileSymlink(c *check.C) {
 	// a nonexistent file.
 	c.Assert(string(out), checker.Contains, "Cannot locate specified Dockerfile: Dockerfile", check.Commentf("Didn't complain about leaving build context"))
 }
+
+func (s *DockerSuite) TestBuildApiStructuredOutput(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	dockerfile := []byte(`FROM busybox
+ENV FOO bar
+RUN echo from run > /out`)
+
+	build := func() ([]types.BuildStep, string) {
+		buffer := new(bytes.Buffer)
+		tw := tar.NewWriter(buffer)
+		c.Assert(tw.WriteHeader(&tar.Header{Name: "Dockerfile", Size: int64(len(dockerfile))}), checker.IsNil)
+		_, err := tw.Write(dockerfile)
+		c.Assert(err, checker.IsNil)
+		c.Assert(tw.Close(), checker.IsNil)
+
+		res, body, err := sockRequestRaw("POST", "/build?t=structuredoutput&structured=1", buffer, "application/x-tar")
+		c.Assert(err, checker.IsNil)
+		c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+		defer body.Close()
+
+		var (
+			steps  []types.BuildStep
+			stream string
+		)
+		dec := json.NewDecoder(body)
+		for {
+			var m jsonmessage.JSONMessage
+			if err := dec.Decode(&m); err == io.EOF {
+				break
+			} else {
+				c.Assert(err, checker.IsNil)
+			}
+			c.Assert(m.Error, checker.IsNil)
+			if m.Aux != nil {
+				var step types.BuildStep
+				c.Assert(json.Unmarshal(*m.Aux, &step), checker.IsNil)
+				steps = append(steps, step)
+			}
+			stream += m.Stream
+		}
+		return steps, stream
+	}
+
+	steps, stream := build()
+	c.Assert(stream, checker.Not(checker.Contains), "Step 1 :")
+	c.Assert(stream, checker.Contains, "Successfully built")
+	c.Assert(steps, checker.HasLen, 3)
+	for i, step := range steps {
+		c.Assert(step.Step, checker.Equals, i+1)
+		c.Assert(step.ImageID, checker.Not(checker.Equals), "")
+	}
+	c.Assert(steps[1].Instruction, checker.Equals, "ENV FOO bar")
+	c.Assert(steps[0].Layer, checker.Equals, "")
+	c.Assert(steps[1].Layer, checker.Equals, "")
+	c.Assert(steps[2].Layer, checker.Not(checker.Equals), "")
+	c.Assert(steps[2].Cached, checker.False)
+
+	imageID := steps[2].ImageID
+	steps, _ = build()
+	c.Assert(steps, checker.HasLen, 3)
+	c.Assert(steps[1].Cached, checker.True)
+	c.Assert(steps[2].Cached, checker.True)
+	c.Assert(steps[2].ImageID, checker.Equals, imageID)
+}
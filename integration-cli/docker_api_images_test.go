@@ -117,6 +117,26 @@ func (s *DockerSuite) TestApiImagesHistory(c *check.C) {
 	c.Assert(historydata[0].Tags[0], checker.Equals, "test-api-images-history:latest")
 }
 
+func (s *DockerSuite) TestApiImagesOnBuild(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "test-api-images-onbuild"
+	_, err := buildImage(name, "FROM busybox\nONBUILD ADD . /app\nONBUILD RUN make", true)
+	c.Assert(err, checker.IsNil)
+
+	status, body, err := sockRequest("GET", "/images/"+name+"/onbuild", nil)
+	c.Assert(err, checker.IsNil)
+	c.Assert(status, checker.Equals, http.StatusOK)
+
+	var triggers []string
+	c.Assert(json.Unmarshal(body, &triggers), checker.IsNil)
+	c.Assert(triggers, checker.DeepEquals, []string{"ADD . /app", "RUN make"})
+
+	status, body, err = sockRequest("GET", "/images/busybox/onbuild", nil)
+	c.Assert(err, checker.IsNil)
+	c.Assert(status, checker.Equals, http.StatusOK)
+	c.Assert(strings.TrimSpace(string(body)), checker.Equals, "[]")
+}
+
 // #14846
 func (s *DockerSuite) TestApiImagesSearchJSONContentType(c *check.C) {
 	testRequires(c, Network)
@@ -4467,6 +4467,25 @@ func (s *DockerSuite) TestBuildOnBuildOutput(c *check.C) {
 	if !strings.Contains(out, "# Executing 1 build trigger") {
 		c.Fatal("failed to find the build trigger output", out)
 	}
+	if !strings.Contains(out, "# Build trigger 1/1 of "+name+": RUN echo foo") {
+		c.Fatal("failed to find the output of the running build trigger", out)
+	}
+}
+
+func (s *DockerSuite) TestBuildSkipOnBuild(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildskiponbuildparent"
+	if _, err := buildImage(name, "FROM busybox\nONBUILD RUN touch /onbuild\n", true); err != nil {
+		c.Fatal(err)
+	}
+
+	child := "testbuildskiponbuild"
+	_, out, err := buildImageWithOut(child, "FROM "+name+"\nRUN [ ! -e /onbuild ]\n", true, "--skip-onbuild")
+	c.Assert(err, checker.IsNil, check.Commentf(out))
+	c.Assert(out, checker.Contains, "# Skipping 1 build trigger of "+name)
+
+	res := inspectFieldJSON(c, child, "Config.OnBuild")
+	c.Assert(res, checker.Equals, "[]")
 }
 
 func (s *DockerSuite) TestBuildInvalidTag(c *check.C) {
@@ -5905,6 +5924,30 @@ func (s *DockerSuite) TestBuildNullStringInAddCopyVolume(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *DockerSuite) TestBuildShell(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildshell"
+	_, out, err := buildImageWithOut(name,
+		`FROM busybox
+		SHELL ["/bin/sh", "-x", "-c"]
+		RUN echo shelltest
+		CMD echo cmdtest`,
+		false)
+	c.Assert(err, check.IsNil, check.Commentf("%s", out))
+	c.Assert(out, checker.Contains, "+ echo shelltest")
+
+	res := inspectFieldJSON(c, name, "Config.Shell")
+	c.Assert(res, checker.Equals, `["/bin/sh","-x","-c"]`)
+	res = inspectFieldJSON(c, name, "Config.Cmd")
+	c.Assert(res, checker.Equals, `["/bin/sh","-x","-c","echo cmdtest"]`)
+
+	_, _, err = buildImageWithOut("testbuildshellnotjson",
+		`FROM busybox
+		SHELL /bin/sh -c`,
+		false)
+	c.Assert(err, checker.NotNil)
+}
+
 func (s *DockerSuite) TestBuildStopSignal(c *check.C) {
 	testRequires(c, DaemonIsLinux) // Windows does not support STOPSIGNAL yet
 	imgName := "test_build_stop_signal"
@@ -6883,3 +6926,231 @@ func (s *DockerRegistryAuthHtpasswdSuite) TestBuildWithExternalAuth(c *check.C)
 	out, _, err := runCommandWithOutput(buildCmd)
 	c.Assert(err, check.IsNil, check.Commentf(out))
 }
+
+func (s *DockerSuite) TestBuildMultiStageCopyFrom(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildmultistagecopyfrom"
+	dockerfile := `FROM busybox AS build
+	COPY foo /src/foo
+	RUN echo -n bar > /src/bar && touch /src/build-only
+
+	FROM busybox
+	COPY --from=build /src/foo /src/bar /app/
+	COPY --from=0 /src/bar /app/baz`
+	ctx, err := fakeContext(dockerfile, map[string]string{
+		"foo": "foo",
+	})
+	c.Assert(err, check.IsNil)
+	defer ctx.Close()
+
+	id1, err := buildImageFromContext(name, ctx, true)
+	c.Assert(err, check.IsNil)
+
+	out, _ := dockerCmd(c, "run", "--rm", name, "cat", "/app/foo", "/app/bar", "/app/baz")
+	c.Assert(out, checker.Equals, "foobarbar")
+
+	_, _, err = dockerCmdWithError("run", "--rm", name, "ls", "/src")
+	c.Assert(err, checker.NotNil, check.Commentf("files of the first stage should not be in the final image"))
+
+	id2, err := buildImageFromContext(name, ctx, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(id1, checker.Equals, id2, check.Commentf("the cache should be used for COPY --from"))
+
+	// a change in the first stage must invalidate the cache of the second one
+	ctx.Add("foo", "changed")
+	id3, err := buildImageFromContext(name, ctx, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(id3, checker.Not(checker.Equals), id1)
+}
+
+func (s *DockerSuite) TestBuildMultiStageInvalidCopyFrom(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildmultistageinvalidcopyfrom"
+
+	_, out, err := buildImageWithOut(name, `FROM busybox AS build
+	COPY --from=build /bin/sh /sh`, true)
+	c.Assert(err, checker.NotNil)
+	c.Assert(out, checker.Contains, "no such build stage")
+
+	_, out, err = buildImageWithOut(name, `FROM busybox AS build
+	FROM busybox AS BUILD`, true)
+	c.Assert(err, checker.NotNil)
+	c.Assert(out, checker.Contains, "duplicate name for build stage")
+}
+
+func (s *DockerSuite) TestBuildMultiStageParallel(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildmultistageparallel"
+	dockerfile := `FROM busybox AS first
+	RUN sleep 2 && echo -n first > /first
+
+	FROM busybox AS second
+	RUN echo -n second > /second
+
+	FROM busybox
+	COPY --from=first /first /app/
+	COPY --from=second /second /app/`
+
+	_, out, err := buildImageWithOut(name, dockerfile, false, "--parallel", "2")
+	c.Assert(err, checker.IsNil, check.Commentf(out))
+
+	// the output of the stages is in the order of the Dockerfile
+	step1 := strings.Index(out, "Step 1 :")
+	step3 := strings.Index(out, "Step 3 :")
+	step5 := strings.Index(out, "Step 5 :")
+	c.Assert(step1 >= 0 && step1 < step3 && step3 < step5, checker.True, check.Commentf(out))
+
+	out, _ = dockerCmd(c, "run", "--rm", name, "cat", "/app/first", "/app/second")
+	c.Assert(out, checker.Equals, "firstsecond")
+
+	_, out, err = buildImageWithOut(name, `FROM busybox AS failing
+	RUN exit 1
+
+	FROM busybox
+	COPY --from=failing /bin/sh /sh`, false, "--parallel", "2")
+	c.Assert(err, checker.NotNil)
+	c.Assert(out, checker.Contains, "returned a non-zero code: 1")
+}
+
+func (s *DockerSuite) TestBuildCheck(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	ctx, err := fakeContext(`FROM busybox
+MAINTAINER someone
+FOO bar`, nil)
+	c.Assert(err, check.IsNil)
+	defer ctx.Close()
+
+	out, exitCode, err := dockerCmdWithError("build", "--check", ctx.Dir)
+	c.Assert(err, checker.NotNil, check.Commentf(out))
+	c.Assert(exitCode, checker.Equals, 1)
+	c.Assert(out, checker.Contains, "Dockerfile:2: warning: MAINTAINER is deprecated")
+	c.Assert(out, checker.Contains, "Dockerfile:3: error: Unknown instruction: FOO")
+	c.Assert(out, checker.Not(checker.Contains), "Sending build context")
+
+	c.Assert(ctx.Add("Dockerfile", "FROM busybox\nRUN true"), check.IsNil)
+	out, _ = dockerCmd(c, "build", "--check", ctx.Dir)
+	c.Assert(strings.TrimSpace(out), checker.Equals, "")
+}
+
+func (s *DockerSuite) TestBuildCacheFrom(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildcachefrom"
+	dockerfile := `FROM busybox
+	ENV FOO=bar
+	ADD baz /
+	RUN touch bax`
+	ctx, err := fakeContext(dockerfile, map[string]string{
+		"baz": "baz",
+	})
+	c.Assert(err, check.IsNil)
+	defer ctx.Close()
+
+	id1, err := buildImageFromContext(name, ctx, true)
+	c.Assert(err, check.IsNil)
+
+	// the parent chain of the image is lost when it goes through a
+	// save and load, like it would through a push and pull
+	tempDir, err := ioutil.TempDir("", "test-build-cache-from-")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(tempDir)
+	tarFile := filepath.Join(tempDir, "cache.tar")
+	dockerCmd(c, "save", "-o", tarFile, name)
+	dockerCmd(c, "rmi", name)
+	dockerCmd(c, "load", "-i", tarFile)
+
+	_, out, err := buildImageFromContextWithOut(name+"2", ctx, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Count(out, "Using cache"), checker.Equals, 0)
+
+	id2, out, err := buildImageFromContextWithOut(name+"3", ctx, true, "--cache-from", name)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Count(out, "Using cache"), checker.Equals, 3)
+	c.Assert(id2, checker.Equals, id1)
+
+	// a change in the context is a cache miss from there on
+	ctx.Add("baz", "changed")
+	_, out, err = buildImageFromContextWithOut(name+"4", ctx, true, "--cache-from", name)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Count(out, "Using cache"), checker.Equals, 1)
+}
+
+func (s *DockerSuite) TestBuildSquash(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testbuildsquash"
+	dockerfile := `FROM busybox
+	RUN echo hello > /hello
+	RUN echo world >> /hello
+	RUN touch /remove_me && rm /remove_me
+	ENV HELLO world
+	RUN touch /remove_me_too && rm /remove_me_too`
+
+	origID, err := buildImage(name, dockerfile, true)
+	c.Assert(err, check.IsNil)
+
+	id, out, err := buildImageWithOut(name+"-squashed", dockerfile, true, "--squash")
+	c.Assert(err, check.IsNil)
+	c.Assert(id, checker.Not(checker.Equals), origID)
+	c.Assert(strings.Count(out, "Using cache"), checker.Equals, 5, check.Commentf("the build cache should be kept"))
+
+	out, _ = dockerCmd(c, "run", "--rm", id, "/bin/sh", "-c", "cat /hello && echo $HELLO")
+	c.Assert(out, checker.Equals, "hello\nworld\nworld\n")
+
+	dockerCmd(c, "run", "--rm", id, "/bin/sh", "-c", "[ ! -f /remove_me ]")
+
+	baseLayers := inspectField(c, "busybox", "RootFS.Layers")
+	squashedLayers := inspectField(c, id, "RootFS.Layers")
+	c.Assert(len(strings.Fields(squashedLayers)), checker.Equals, len(strings.Fields(baseLayers))+1)
+}
+
+func (s *DockerSuite) TestBuildSecret(c *check.C) {
+	testRequires(c, DaemonIsLinux, SameHostDaemon)
+	name := "testbuildsecret"
+
+	secretFile, err := ioutil.TempFile("", "testbuildsecret")
+	c.Assert(err, check.IsNil)
+	defer os.Remove(secretFile.Name())
+	_, err = secretFile.WriteString("s3cr3t")
+	c.Assert(err, check.IsNil)
+	secretFile.Close()
+
+	dockerfile := `FROM busybox
+	RUN --mount=type=secret,id=token [ "$(cat /run/secrets/token)" = "s3cr3t" ]
+	RUN --mount=type=secret,id=token,target=/token cp /token /copy && rm /copy
+	RUN --mount=type=secret,id=missing [ ! -s /run/secrets/missing ]`
+
+	id, _, err := buildImageWithOut(name, dockerfile, true, "--secret", "id=token,src="+secretFile.Name())
+	c.Assert(err, check.IsNil)
+
+	out, _ := dockerCmd(c, "history", "--no-trunc", id)
+	c.Assert(out, checker.Contains, "--mount=type=secret,id=token,target=/token")
+	c.Assert(out, checker.Not(checker.Contains), "s3cr3t")
+
+	out, _ = dockerCmd(c, "run", "--rm", id, "/bin/sh", "-c", "cat /run/secrets/token /token 2>/dev/null; true")
+	c.Assert(out, checker.Not(checker.Contains), "s3cr3t")
+
+	_, _, err = buildImageWithOut(name+"-required", `FROM busybox
+	RUN --mount=type=secret,id=token,required true`, true)
+	c.Assert(err, checker.NotNil, check.Commentf("a missing required secret should fail the build"))
+}
+
+func (s *DockerSuite) TestBuildNetworkModeAndExtraHosts(c *check.C) {
+	testRequires(c, DaemonIsLinux, NotUserNamespace)
+	name := "testbuildnetworkmode"
+
+	_, _, err := buildImageWithOut(name, `FROM busybox
+	RUN [ "$(ls /sys/class/net)" = "lo" ]`, false, "--network", "none")
+	c.Assert(err, check.IsNil, check.Commentf("RUN should only see the loopback interface with --network none"))
+
+	dockerCmd(c, "run", "-d", "--name", "netcontainer", "busybox", "top")
+	hostname, _ := dockerCmd(c, "exec", "netcontainer", "hostname")
+	_, _, err = buildImageWithOut(name, `FROM busybox
+	RUN grep -q `+strings.TrimSpace(hostname)+` /etc/hosts`, false, "--network", "container:netcontainer")
+	c.Assert(err, check.IsNil)
+
+	_, _, err = buildImageWithOut(name, `FROM busybox
+	RUN grep -q "10.0.0.1[[:space:]]*proxy.example.com" /etc/hosts`, false, "--add-host", "proxy.example.com:10.0.0.1")
+	c.Assert(err, check.IsNil)
+
+	_, _, err = buildImageWithOut(name, `FROM busybox`, false, "--network", "mynet")
+	c.Assert(err, checker.NotNil, check.Commentf("user-defined networks should not be supported"))
+}
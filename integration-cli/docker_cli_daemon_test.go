@@ -2221,3 +2221,27 @@ func (s *DockerSuite) TestDaemonDiscoveryBackendConfigReload(c *check.C) {
 	c.Assert(out, checker.Contains, fmt.Sprintf("Cluster Store: consul://consuladdr:consulport/some/path"))
 	c.Assert(out, checker.Contains, fmt.Sprintf("Cluster Advertise: 192.168.56.100:0"))
 }
+
+func (s *DockerDaemonSuite) TestDaemonServeRegistryCache(c *check.C) {
+	testRequires(c, SameHostDaemon, DaemonIsLinux)
+	err := s.d.StartWithBusybox("--serve-registry-cache=127.0.0.1:5050")
+	c.Assert(err, check.IsNil)
+
+	// the images of the daemon are served under their Docker Hub names
+	repoName := "127.0.0.1:5050/busybox"
+	dockerCmd(c, "pull", repoName)
+	defer deleteImages(repoName)
+	out, _ := dockerCmd(c, "run", "--rm", repoName, "echo", "cached")
+	c.Assert(out, checker.Contains, "cached")
+
+	// the layers are served uncompressed, with the same diffIDs
+	expected, err := s.d.Cmd("inspect", "--format", "{{.RootFS.Layers}}", "busybox")
+	c.Assert(err, check.IsNil, check.Commentf(expected))
+	c.Assert(inspectField(c, repoName, "RootFS.Layers"), checker.Equals, strings.TrimSpace(expected))
+
+	// the cache is read-only
+	dockerCmd(c, "tag", "busybox", "127.0.0.1:5050/pushed")
+	defer deleteImages("127.0.0.1:5050/pushed")
+	_, _, err = dockerCmdWithError("push", "127.0.0.1:5050/pushed")
+	c.Assert(err, check.NotNil)
+}
@@ -391,3 +391,27 @@ func (s *DockerSuite) TestInspectRootFS(c *check.C) {
 
 	c.Assert(len(imageJSON[0].RootFS.Layers), checker.GreaterOrEqualThan, 1)
 }
+
+func (s *DockerSuite) TestInspectImageLayers(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "testinspectimagelayers"
+	_, err := buildImage(name, `FROM busybox
+ENV FOO bar
+RUN dd if=/dev/zero of=/file bs=1024 count=1024`, true)
+	c.Assert(err, checker.IsNil)
+
+	out, _ := dockerCmd(c, "inspect", name)
+	var imageJSON []types.ImageInspect
+	c.Assert(json.Unmarshal([]byte(out), &imageJSON), checker.IsNil)
+
+	// ENV does not create a layer
+	layers := imageJSON[0].Layers
+	c.Assert(layers, checker.HasLen, len(imageJSON[0].RootFS.Layers))
+	for i, l := range layers {
+		c.Assert(l.DiffID, checker.Equals, imageJSON[0].RootFS.Layers[i])
+	}
+	last := layers[len(layers)-1]
+	c.Assert(last.CreatedBy, checker.Contains, "dd if=/dev/zero")
+	c.Assert(last.Size, checker.GreaterOrEqualThan, int64(1024*1024))
+	c.Assert(last.Digest, checker.Equals, "")
+}
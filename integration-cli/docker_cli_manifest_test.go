@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/pkg/integration/checker"
+	"github.com/docker/engine-api/types"
+	"github.com/go-check/check"
+)
+
+func (s *DockerRegistrySuite) TestManifestListPushPull(c *check.C) {
+	repoName := fmt.Sprintf("%v/dockercli/multiarch", privateRegistryURL)
+	amd64Image := repoName + ":amd64"
+	armImage := repoName + ":arm"
+	listName := repoName + ":latest"
+
+	dockerCmd(c, "tag", "busybox", amd64Image)
+	dockerCmd(c, "push", amd64Image)
+	dockerCmd(c, "tag", "busybox", armImage)
+	dockerCmd(c, "push", armImage)
+
+	out, _ := dockerCmd(c, "manifest", "create", listName, amd64Image, armImage)
+	c.Assert(out, checker.Contains, "Created manifest list")
+
+	out, _, err := dockerCmdWithError("manifest", "create", listName, amd64Image)
+	c.Assert(err, checker.NotNil, check.Commentf("expected creating an existing manifest list to fail"))
+	c.Assert(out, checker.Contains, "already exists")
+
+	out, _, err = dockerCmdWithError("manifest", "create", repoName+"-other", amd64Image)
+	c.Assert(err, checker.NotNil, check.Commentf("expected an image of another repository to be rejected"))
+
+	dockerCmd(c, "manifest", "annotate", "--arch", "arm", "--variant", "v7", listName, armImage)
+
+	out, _ = dockerCmd(c, "manifest", "inspect", listName)
+	var images []types.ManifestListEntry
+	c.Assert(json.Unmarshal([]byte(out), &images), checker.IsNil)
+	c.Assert(images, checker.HasLen, 2)
+	c.Assert(images[1].Image, checker.Equals, armImage)
+	c.Assert(images[1].Platform.Architecture, checker.Equals, "arm")
+	c.Assert(images[1].Platform.Variant, checker.Equals, "v7")
+
+	out, _ = dockerCmd(c, "manifest", "push", "--purge", listName)
+	c.Assert(out, checker.Contains, "latest: digest: sha256:")
+
+	_, _, err = dockerCmdWithError("manifest", "inspect", listName)
+	c.Assert(err, checker.NotNil, check.Commentf("expected the manifest list to be removed after the push"))
+
+	// pulling the list selects the image of the platform of the daemon
+	dockerCmd(c, "rmi", amd64Image, armImage)
+	out, _ = dockerCmd(c, "pull", listName)
+	c.Assert(strings.TrimSpace(out), checker.Contains, "Digest: sha256:")
+	c.Assert(inspectField(c, listName, "Id"), checker.Equals, inspectField(c, "busybox", "Id"))
+}
@@ -363,3 +363,29 @@ func (s *DockerTrustSuite) TestTrustedPullIgnoresOtherDelegationRoles(c *check.C
 	c.Assert(err, check.NotNil, check.Commentf(out))
 	c.Assert(out, checker.Contains, "No trusted tags for")
 }
+
+func (s *DockerTrustSuite) TestTrustedPullEnforcedByDaemon(c *check.C) {
+	testRequires(c, SameHostDaemon, DaemonIsLinux)
+	repoName := s.setupTrustedImage(c, "trusted-daemon-pull")
+
+	unsignedName := fmt.Sprintf("%v/dockercli/unsigned-daemon-pull:latest", privateRegistryURL)
+	dockerCmd(c, "tag", "busybox", unsignedName)
+	dockerCmd(c, "push", unsignedName)
+
+	d := NewDaemon(c)
+	c.Assert(d.Start("--content-trust", "--content-trust-server="+notaryURL, "--insecure-registry="+notaryHost), check.IsNil)
+	defer d.Stop()
+
+	// The daemon verifies the signature even though the client does not
+	out, err := d.Cmd("pull", repoName)
+	c.Assert(err, check.IsNil, check.Commentf(out))
+	c.Assert(out, checker.Contains, "Tagging", check.Commentf(out))
+
+	out, err = d.Cmd("inspect", "--format", "{{range .ContentTrust}}{{.Repository}} {{len .RootKeyIDs}}{{end}}", repoName)
+	c.Assert(err, check.IsNil, check.Commentf(out))
+	c.Assert(strings.TrimSpace(out), checker.Equals, strings.TrimSuffix(repoName, ":latest")+" 1")
+
+	out, err = d.Cmd("pull", unsignedName)
+	c.Assert(err, check.NotNil, check.Commentf(out))
+	c.Assert(out, checker.Contains, "content trust verification failed", check.Commentf(out))
+}
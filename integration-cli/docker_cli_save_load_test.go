@@ -350,3 +350,68 @@ func (s *DockerSuite) TestSaveLoadParents(c *check.C) {
 	inspectOut = inspectField(c, idFoo, "Parent")
 	c.Assert(inspectOut, checker.Equals, "")
 }
+
+func (s *DockerSuite) TestSaveAndLoadCompressed(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	repoName := "foobar-save-load-compressed"
+	dockerCmd(c, "tag", "busybox:latest", repoName)
+	before := inspectField(c, repoName, "Id")
+
+	tmpDir, err := ioutil.TempDir("", "save-load-compressed")
+	c.Assert(err, checker.IsNil)
+	defer os.RemoveAll(tmpDir)
+
+	compressions := []string{"none", "gzip"}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		compressions = append(compressions, "zstd")
+	}
+	for _, compression := range compressions {
+		outfile := filepath.Join(tmpDir, compression+".tar")
+		dockerCmd(c, "save", "--compress", compression, "-o", outfile, repoName)
+		deleteImages(repoName)
+		dockerCmd(c, "load", "-i", outfile)
+
+		after := inspectField(c, repoName, "Id")
+		c.Assert(after, checker.Equals, before, check.Commentf("image ID is not the same after a save / load with %s compression", compression))
+	}
+
+	out, _, err := dockerCmdWithError("save", "--compress", "lzma", "-o", filepath.Join(tmpDir, "lzma.tar"), repoName)
+	c.Assert(err, checker.NotNil, check.Commentf("save should fail with an invalid compression"))
+	c.Assert(out, checker.Contains, "invalid compression")
+}
+
+func (s *DockerSuite) TestSaveSharedLayersOnce(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	// both images have the layers of busybox, with a different
+	// configuration
+	nameFoo := "foobar-save-shared-layers-foo"
+	nameBar := "foobar-save-shared-layers-bar"
+	_, err := buildImage(nameFoo, "FROM busybox\nENV foo foo", true)
+	c.Assert(err, checker.IsNil)
+	_, err = buildImage(nameBar, "FROM busybox\nENV bar bar", true)
+	c.Assert(err, checker.IsNil)
+	idFoo := inspectField(c, nameFoo, "Id")
+	idBar := inspectField(c, nameBar, "Id")
+
+	tmpDir, err := ioutil.TempDir("", "save-shared-layers")
+	c.Assert(err, checker.IsNil)
+	defer os.RemoveAll(tmpDir)
+	outfile := filepath.Join(tmpDir, "out.tar")
+	dockerCmd(c, "save", "-o", outfile, nameFoo, nameBar)
+
+	// the layer is written once, the other layer.tar links to it
+	out, _, err := runCommandWithOutput(exec.Command("tar", "tvf", outfile))
+	c.Assert(err, checker.IsNil, check.Commentf("failed to list the archive: %s", out))
+	var links int
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.HasPrefix(l, "l") && strings.Contains(l, "/layer.tar -> ") {
+			links++
+		}
+	}
+	c.Assert(links, checker.GreaterThan, 0, check.Commentf("no shared layer in the archive: %s", out))
+
+	deleteImages(nameFoo, nameBar)
+	dockerCmd(c, "load", "-i", outfile)
+	c.Assert(inspectField(c, nameFoo, "Id"), checker.Equals, idFoo)
+	c.Assert(inspectField(c, nameBar, "Id"), checker.Equals, idBar)
+}
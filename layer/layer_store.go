@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/digest"
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
@@ -128,6 +129,11 @@ func (ls *layerStore) loadLayer(layer ChainID) (*roLayer, error) {
 		return nil, fmt.Errorf("failed to get parent for %s: %s", layer, err)
 	}
 
+	descriptor, err := ls.store.GetDescriptor(layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descriptor for %s: %s", layer, err)
+	}
+
 	cl = &roLayer{
 		chainID:    layer,
 		diffID:     diff,
@@ -135,6 +141,7 @@ func (ls *layerStore) loadLayer(layer ChainID) (*roLayer, error) {
 		cacheID:    cacheID,
 		layerStore: ls,
 		references: map[Layer]struct{}{},
+		descriptor: descriptor,
 	}
 
 	if parent != "" {
@@ -228,6 +235,16 @@ func (ls *layerStore) applyTar(tx MetadataTransaction, ts io.Reader, parent stri
 }
 
 func (ls *layerStore) Register(ts io.Reader, parent ChainID) (Layer, error) {
+	return ls.registerWithDescriptor(ts, parent, distribution.Descriptor{})
+}
+
+// RegisterWithDescriptor registers a layer along with the descriptor it was
+// downloaded with, such as the URLs of a foreign layer.
+func (ls *layerStore) RegisterWithDescriptor(ts io.Reader, parent ChainID, descriptor distribution.Descriptor) (Layer, error) {
+	return ls.registerWithDescriptor(ts, parent, descriptor)
+}
+
+func (ls *layerStore) registerWithDescriptor(ts io.Reader, parent ChainID, descriptor distribution.Descriptor) (Layer, error) {
 	// err is used to hold the error which will always trigger
 	// cleanup of creates sources but may not be an error returned
 	// to the caller (already exists).
@@ -261,6 +278,7 @@ func (ls *layerStore) Register(ts io.Reader, parent ChainID) (Layer, error) {
 		referenceCount: 1,
 		layerStore:     ls,
 		references:     map[Layer]struct{}{},
+		descriptor:     descriptor,
 	}
 
 	if err = ls.driver.Create(layer.cacheID, pid, "", nil); err != nil {
@@ -345,6 +363,20 @@ func (ls *layerStore) Get(l ChainID) (Layer, error) {
 	return layer.getReference(), nil
 }
 
+// Map returns all read-only layers in the store. The returned layers are
+// not referenced, so they must not be released and are only valid until
+// they are deleted from the store.
+func (ls *layerStore) Map() map[ChainID]Layer {
+	ls.layerL.Lock()
+	defer ls.layerL.Unlock()
+
+	layers := make(map[ChainID]Layer, len(ls.layerMap))
+	for k, v := range ls.layerMap {
+		layers[k] = v
+	}
+	return layers
+}
+
 func (ls *layerStore) deleteLayer(layer *roLayer, metadata *Metadata) error {
 	err := ls.driver.Remove(layer.cacheID)
 	if err != nil {
@@ -417,6 +449,30 @@ func (ls *layerStore) Release(l Layer) ([]Metadata, error) {
 	return ls.releaseLayer(layer)
 }
 
+// CollectGarbage removes the read-only layers which are not referenced by
+// any image, layer or container. Such layers are left behind by pulls
+// interrupted by a restart of the daemon, or when removing a layer failed.
+func (ls *layerStore) CollectGarbage() ([]Metadata, error) {
+	ls.layerL.Lock()
+	defer ls.layerL.Unlock()
+
+	removed := []Metadata{}
+	for _, l := range ls.layerMap {
+		if l.referenceCount != 0 || l.hasReferences() {
+			continue
+		}
+		// releasing the layer removes it, along with the parents
+		// only it referenced
+		l.referenceCount++
+		metadata, err := ls.releaseLayer(l)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, metadata...)
+	}
+	return removed, nil
+}
+
 func (ls *layerStore) CreateRWLayer(name string, parent ChainID, mountLabel string, initFunc MountInit, storageOpt map[string]string) (RWLayer, error) {
 	ls.mountL.Lock()
 	defer ls.mountL.Unlock()
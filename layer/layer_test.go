@@ -1,15 +1,19 @@
 package layer
 
 import (
+	"archive/tar"
 	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/digest"
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/daemon/graphdriver/vfs"
@@ -356,6 +360,42 @@ func TestLayerRelease(t *testing.T) {
 	releaseAndCheckDeleted(t, ls, layer3a, layer3a, layer2, layer1)
 }
 
+func TestStoreMap(t *testing.T) {
+	// TODO Windows: Figure out why this is failing
+	if runtime.GOOS == "windows" {
+		t.Skip("Failing on Windows")
+	}
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	layer1, err := createLayer(ls, "", initWithFiles(newTestFile("layer1.txt", []byte("layer 1 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer2, err := createLayer(ls, layer1.ChainID(), initWithFiles(newTestFile("layer2.txt", []byte("layer 2 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers := ls.Map()
+	if len(layers) != 2 {
+		t.Fatalf("Unexpected number of layers %d, expected 2", len(layers))
+	}
+	for _, l := range []Layer{layer1, layer2} {
+		if _, ok := layers[l.ChainID()]; !ok {
+			t.Fatalf("Layer %s missing from map", l.ChainID())
+		}
+	}
+
+	// Layers returned by Map are not referenced.
+	releaseAndCheckDeleted(t, ls, layer2, layer2)
+	releaseAndCheckDeleted(t, ls, layer1, layer1)
+	if len(ls.Map()) != 0 {
+		t.Fatalf("Expected no layers after release, got %d", len(ls.Map()))
+	}
+}
+
 func TestStoreRestore(t *testing.T) {
 	// TODO Windows: Figure out why this is failing
 	if runtime.GOOS == "windows" {
@@ -786,3 +826,151 @@ func TestTarStreamVerification(t *testing.T) {
 		t.Fatalf("wrong error returned from tarstream: %q", err)
 	}
 }
+
+func TestTarStreamFrom(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Needs the vfs driver diff")
+	}
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	layer1, err := createLayer(ls, "", initWithFiles(newTestFile("layer1.txt", []byte("layer 1 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer2, err := createLayer(ls, layer1.ChainID(), initWithFiles(newTestFile("layer2.txt", []byte("layer 2 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer3, err := createLayer(ls, layer2.ChainID(), initWithFiles(newTestFile("layer3.txt", []byte("layer 3 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := func(parent ChainID) []string {
+		ts, err := layer3.TarStreamFrom(parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ts.Close()
+		var names []string
+		tr := tar.NewReader(ts)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, hdr.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if names := files(layer1.ChainID()); !reflect.DeepEqual(names, []string{"layer2.txt", "layer3.txt"}) {
+		t.Fatalf("unexpected files since layer 1: %v", names)
+	}
+	if names := files(""); !reflect.DeepEqual(names, []string{"layer1.txt", "layer2.txt", "layer3.txt"}) {
+		t.Fatalf("unexpected files of the whole chain: %v", names)
+	}
+	if _, err := layer1.TarStreamFrom(layer3.ChainID()); err == nil {
+		t.Fatal("expected an error for a layer which is not a parent")
+	}
+}
+
+func TestRegisterWithDescriptor(t *testing.T) {
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	tar1, err := tarFromFiles(newTestFile("/etc/profile", []byte("# Base configuration"), 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descriptor := distribution.Descriptor{
+		MediaType: "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip",
+		Size:      int64(len(tar1)),
+		Digest:    digest.FromBytes(tar1),
+		URLs:      []string{"https://foreign.example.com/layer.tar"},
+	}
+
+	layer1, err := ls.(DescribableStore).RegisterWithDescriptor(bytes.NewReader(tar1), "", descriptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := layer1.(distribution.Describable).Descriptor(); !reflect.DeepEqual(d, descriptor) {
+		t.Fatalf("Unexpected descriptor %#v, expected %#v", d, descriptor)
+	}
+
+	// the descriptor is restored with the layer
+	ls2, err := NewStoreFromGraphDriver(ls.(*layerStore).store, ls.(*layerStore).driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer1b, err := ls2.Get(layer1.ChainID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := layer1b.(distribution.Describable).Descriptor(); !reflect.DeepEqual(d, descriptor) {
+		t.Fatalf("Unexpected restored descriptor %#v, expected %#v", d, descriptor)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	// TODO Windows: Figure out why this is failing
+	if runtime.GOOS == "windows" {
+		t.Skip("Failing on Windows")
+	}
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	layer1, err := createLayer(ls, "", initWithFiles(newTestFile("layer1.txt", []byte("layer 1 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer2, err := createLayer(ls, layer1.ChainID(), initWithFiles(newTestFile("layer2.txt", []byte("layer 2 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer3, err := createLayer(ls, layer1.ChainID(), initWithFiles(newTestFile("layer3.txt", []byte("layer 3 file"), 0644)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a restarted store only has the references of the child layers
+	ls2, err := NewStoreFromGraphDriver(ls.(*layerStore).store, ls.(*layerStore).driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer3b, err := ls2.Get(layer3.ChainID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ls2.(GarbageCollector).CollectGarbage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMetadata(t, removed, createMetadata(layer2))
+
+	if _, err := ls2.Get(layer2.ChainID()); err != ErrLayerDoesNotExist {
+		t.Fatalf("Expected layer %s to be removed, got %v", layer2.ChainID(), err)
+	}
+	for _, l := range []Layer{layer1, layer3} {
+		l2, err := ls2.Get(l.ChainID())
+		if err != nil {
+			t.Fatalf("Expected layer %s to be kept: %v", l.ChainID(), err)
+		}
+		ls2.Release(l2)
+	}
+
+	// once it is released, the layer and its parent are removed
+	if _, err := ls2.Release(layer3b); err != nil {
+		t.Fatal(err)
+	}
+	if len(ls2.Map()) != 0 {
+		t.Fatalf("Expected no layer left, got %v", ls2.Map())
+	}
+}
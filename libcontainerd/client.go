@@ -3,26 +3,65 @@ package libcontainerd
 import (
 	"fmt"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/locker"
+	"golang.org/x/net/context"
 )
 
+// lockHeldWarnThreshold is how long a container lock can be held before it
+// is reported as a potential deadlock.
+const lockHeldWarnThreshold = 2 * time.Minute
+
+// signalAllParallelism is the maximum number of containers SignalAll
+// signals concurrently.
+const signalAllParallelism = 32
+
 // clientCommon contains the platform agnostic fields used in the client structure
 type clientCommon struct {
 	backend    Backend
 	containers map[string]*container
 	locker     *locker.Locker
 	mapMutex   sync.RWMutex // protects read/write oprations from containers map
+
+	lockWatchMutex sync.Mutex             // protects lockWatchers
+	lockWatchers   map[string]*time.Timer // reports locks held for too long
+
+	draining bool           // protected by mapMutex
+	creates  sync.WaitGroup // in-flight Create calls
 }
 
 func (clnt *client) lock(containerID string) {
 	clnt.locker.Lock(containerID)
+	clnt.watchLock(containerID)
 }
 
 func (clnt *client) unlock(containerID string) {
+	clnt.lockWatchMutex.Lock()
+	if t, ok := clnt.lockWatchers[containerID]; ok {
+		t.Stop()
+		delete(clnt.lockWatchers, containerID)
+	}
+	clnt.lockWatchMutex.Unlock()
 	clnt.locker.Unlock(containerID)
 }
 
+// watchLock arranges for a warning to be logged if the lock of the container
+// is not released within lockHeldWarnThreshold. Must hold the container lock.
+func (clnt *client) watchLock(containerID string) {
+	acquired := time.Now()
+	clnt.lockWatchMutex.Lock()
+	if clnt.lockWatchers == nil {
+		clnt.lockWatchers = make(map[string]*time.Timer)
+	}
+	clnt.lockWatchers[containerID] = time.AfterFunc(lockHeldWarnThreshold, func() {
+		logrus.Warnf("libcontainerd: lock for container %s held since %s, possible deadlock", containerID, acquired.Format(time.RFC3339))
+	})
+	clnt.lockWatchMutex.Unlock()
+}
+
 // must hold a lock for cont.containerID
 func (clnt *client) appendContainer(cont *container) {
 	clnt.mapMutex.Lock()
@@ -40,7 +79,86 @@ func (clnt *client) getContainer(containerID string) (*container, error) {
 	container, ok := clnt.containers[containerID]
 	defer clnt.mapMutex.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("invalid container: %s", containerID) // fixme: typed error
+		return nil, ErrContainerNotFound{containerID}
 	}
 	return container, nil
 }
+
+// SignalAll sends a signal to all given containers, with at most
+// signalAllParallelism requests to containerd in flight at once. The
+// returned map holds the result of signalling each container.
+func (clnt *client) SignalAll(ctx context.Context, containerIDs []string, sig int) map[string]error {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(containerIDs))
+		sem     = make(chan struct{}, signalAllParallelism)
+	)
+	for _, id := range containerIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string) {
+			defer wg.Done()
+			err := clnt.Signal(ctx, id, sig)
+			<-sem
+			mu.Lock()
+			results[id] = err
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+// beginCreate registers an in-flight Create call. It fails once the client
+// is draining.
+func (clnt *client) beginCreate(containerID string) error {
+	clnt.mapMutex.Lock()
+	defer clnt.mapMutex.Unlock()
+	if clnt.draining {
+		return ErrDraining{containerID}
+	}
+	clnt.creates.Add(1)
+	return nil
+}
+
+func (clnt *client) endCreate() {
+	clnt.creates.Done()
+}
+
+// Drain stops the client from accepting new containers and waits for
+// in-flight Create calls and pending state notifications to complete. If
+// terminate is set, SIGTERM is sent to all managed containers first.
+func (clnt *client) Drain(ctx context.Context, timeout time.Duration, terminate bool) error {
+	clnt.mapMutex.Lock()
+	clnt.draining = true
+	ids := make([]string, 0, len(clnt.containers))
+	for id := range clnt.containers {
+		ids = append(ids, id)
+	}
+	clnt.mapMutex.Unlock()
+
+	if terminate {
+		for id, err := range clnt.SignalAll(ctx, ids, int(syscall.SIGTERM)) {
+			if err != nil && !IsNotFound(err) {
+				logrus.Warnf("libcontainerd: failed to signal %s while draining: %v", id, err)
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		clnt.creates.Wait()
+		clnt.waitPendingEvents()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("libcontainerd: timed out after %s draining containers", timeout)
+	}
+}
@@ -3,11 +3,13 @@ package libcontainerd
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	containerd "github.com/docker/containerd/api/grpc/types"
@@ -24,9 +26,11 @@ type client struct {
 	remote        *remote
 	q             queue
 	exitNotifiers map[string]*exitNotifier
+	statsStreams  map[string]*statsStream
+	fifoJanitors  map[string]struct{} // fifo directories being cleaned up, protected by mapMutex
 }
 
-func (clnt *client) AddProcess(containerID, processFriendlyName string, specp Process) error {
+func (clnt *client) AddProcess(ctx context.Context, containerID, processFriendlyName string, specp Process) error {
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
 	container, err := clnt.getContainer(containerID)
@@ -57,6 +61,18 @@ func (clnt *client) AddProcess(containerID, processFriendlyName string, specp Pr
 	if specp.Capabilities != nil {
 		sp.Capabilities = specp.Capabilities
 	}
+	if specp.Rlimits != nil {
+		sp.Rlimits = specp.Rlimits
+	}
+	if specp.ApparmorProfile != nil {
+		sp.ApparmorProfile = *specp.ApparmorProfile
+	}
+	if specp.SelinuxLabel != nil {
+		sp.SelinuxLabel = *specp.SelinuxLabel
+	}
+	if specp.NoNewPrivileges != nil {
+		sp.NoNewPrivileges = *specp.NoNewPrivileges
+	}
 
 	p := container.newProcess(processFriendlyName)
 
@@ -87,7 +103,7 @@ func (clnt *client) AddProcess(containerID, processFriendlyName string, specp Pr
 		return err
 	}
 
-	if _, err := clnt.remote.apiClient.AddProcess(context.Background(), r); err != nil {
+	if _, err := clnt.remote.apiClient.AddProcess(ctx, r); err != nil {
 		p.closeFifos(iopipe)
 		return err
 	}
@@ -97,10 +113,24 @@ func (clnt *client) AddProcess(containerID, processFriendlyName string, specp Pr
 	clnt.unlock(containerID)
 
 	if err := clnt.backend.AttachStreams(processFriendlyName, *iopipe); err != nil {
+		clnt.lock(containerID)
 		return err
 	}
 	clnt.lock(containerID)
 
+	// Queued so that it is always delivered before the matching exit-process.
+	st := StateInfo{
+		CommonStateInfo: CommonStateInfo{
+			State:     StateStartProcess,
+			ProcessID: processFriendlyName,
+		},
+	}
+	clnt.q.append(containerID, func() {
+		if err := clnt.backend.StateChanged(containerID, st); err != nil {
+			logrus.Error(err)
+		}
+	})
+
 	return nil
 }
 
@@ -129,7 +159,11 @@ func (clnt *client) prepareBundleDir(uid, gid int) (string, error) {
 	return p, nil
 }
 
-func (clnt *client) Create(containerID string, spec Spec, options ...CreateOption) (err error) {
+func (clnt *client) Create(ctx context.Context, containerID string, spec Spec, options ...CreateOption) (err error) {
+	if err := clnt.beginCreate(containerID); err != nil {
+		return err
+	}
+	defer clnt.endCreate()
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
 
@@ -138,7 +172,7 @@ func (clnt *client) Create(containerID string, spec Spec, options ...CreateOptio
 			ctr.restartManager.Cancel()
 			ctr.clean()
 		} else {
-			return fmt.Errorf("Container %s is already active", containerID)
+			return ErrAlreadyActive{containerID}
 		}
 	}
 
@@ -155,6 +189,11 @@ func (clnt *client) Create(containerID string, spec Spec, options ...CreateOptio
 	if err := container.clean(); err != nil {
 		return err
 	}
+	if container.checkpoint != "" {
+		if err := container.loadCheckpoints(); err != nil {
+			return err
+		}
+	}
 
 	defer func() {
 		if err != nil {
@@ -182,13 +221,13 @@ func (clnt *client) Create(containerID string, spec Spec, options ...CreateOptio
 		return err
 	}
 
-	return container.start()
+	return container.start(ctx)
 }
 
-func (clnt *client) Signal(containerID string, sig int) error {
+func (clnt *client) Signal(ctx context.Context, containerID string, sig int) error {
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
-	_, err := clnt.remote.apiClient.Signal(context.Background(), &containerd.SignalRequest{
+	_, err := clnt.remote.apiClient.Signal(ctx, &containerd.SignalRequest{
 		Id:     containerID,
 		Pid:    InitFriendlyName,
 		Signal: uint32(sig),
@@ -196,13 +235,13 @@ func (clnt *client) Signal(containerID string, sig int) error {
 	return err
 }
 
-func (clnt *client) Resize(containerID, processFriendlyName string, width, height int) error {
+func (clnt *client) Resize(ctx context.Context, containerID, processFriendlyName string, width, height int) error {
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
 	if _, err := clnt.getContainer(containerID); err != nil {
 		return err
 	}
-	_, err := clnt.remote.apiClient.UpdateProcess(context.Background(), &containerd.UpdateProcessRequest{
+	_, err := clnt.remote.apiClient.UpdateProcess(ctx, &containerd.UpdateProcessRequest{
 		Id:     containerID,
 		Pid:    processFriendlyName,
 		Width:  uint32(width),
@@ -211,11 +250,11 @@ func (clnt *client) Resize(containerID, processFriendlyName string, width, heigh
 	return err
 }
 
-func (clnt *client) Pause(containerID string) error {
-	return clnt.setState(containerID, StatePause)
+func (clnt *client) Pause(ctx context.Context, containerID string) error {
+	return clnt.setState(ctx, containerID, StatePause)
 }
 
-func (clnt *client) setState(containerID, state string) error {
+func (clnt *client) setState(ctx context.Context, containerID, state string) error {
 	clnt.lock(containerID)
 	container, err := clnt.getContainer(containerID)
 	if err != nil {
@@ -224,14 +263,14 @@ func (clnt *client) setState(containerID, state string) error {
 	}
 	if container.systemPid == 0 {
 		clnt.unlock(containerID)
-		return fmt.Errorf("No active process for container %s", containerID)
+		return ErrNoActiveProcess{containerID}
 	}
 	st := "running"
 	if state == StatePause {
 		st = "paused"
 	}
 	chstate := make(chan struct{})
-	_, err = clnt.remote.apiClient.UpdateContainer(context.Background(), &containerd.UpdateContainerRequest{
+	_, err = clnt.remote.apiClient.UpdateContainer(ctx, &containerd.UpdateContainerRequest{
 		Id:     containerID,
 		Pid:    InitFriendlyName,
 		Status: st,
@@ -242,29 +281,52 @@ func (clnt *client) setState(containerID, state string) error {
 	}
 	container.pauseMonitor.append(state, chstate)
 	clnt.unlock(containerID)
-	<-chstate
-	return nil
+
+	select {
+	case <-chstate:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-time.After(clnt.remote.stateChangeTimeout):
+		err = ErrTimeout{ContainerID: containerID, Operation: state}
+	}
+	clnt.lock(containerID)
+	container.pauseMonitor.remove(state, chstate)
+	clnt.unlock(containerID)
+	return err
 }
 
-func (clnt *client) Resume(containerID string) error {
-	return clnt.setState(containerID, StateResume)
+func (clnt *client) Resume(ctx context.Context, containerID string) error {
+	return clnt.setState(ctx, containerID, StateResume)
 }
 
-func (clnt *client) Stats(containerID string) (*Stats, error) {
-	resp, err := clnt.remote.apiClient.Stats(context.Background(), &containerd.StatsRequest{containerID})
+func (clnt *client) Stats(ctx context.Context, containerID string) (*Stats, error) {
+	resp, err := clnt.remote.apiClient.Stats(ctx, &containerd.StatsRequest{containerID})
 	if err != nil {
 		return nil, err
 	}
 	return (*Stats)(resp), nil
 }
 
+// Restore brings a container that was running when the daemon stopped back
+// under libcontainerd control. Unless live restore is enabled on the remote,
+// the container is stopped.
+func (clnt *client) Restore(ctx context.Context, containerID string, options ...CreateOption) error {
+	if clnt.remote.liveRestore {
+		return clnt.liveRestore(ctx, containerID, options...)
+	}
+	return clnt.shutdownRestore(ctx, containerID)
+}
+
 func (clnt *client) setExited(containerID string) error {
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
 
 	var exitCode uint32
 	if event, ok := clnt.remote.pastEvents[containerID]; ok {
-		exitCode = event.Status
+		if event.Type == StateExit {
+			exitCode = event.Status
+		}
 		delete(clnt.remote.pastEvents, containerID)
 	}
 
@@ -289,8 +351,8 @@ func (clnt *client) setExited(containerID string) error {
 	return err
 }
 
-func (clnt *client) GetPidsForContainer(containerID string) ([]int, error) {
-	cont, err := clnt.getContainerdContainer(containerID)
+func (clnt *client) GetPidsForContainer(ctx context.Context, containerID string) ([]int, error) {
+	cont, err := clnt.getContainerdContainer(ctx, containerID)
 	if err != nil {
 		return nil, err
 	}
@@ -302,13 +364,39 @@ func (clnt *client) GetPidsForContainer(containerID string) ([]int, error) {
 }
 
 // Summary returns a summary of the processes running in a container.
-// This is a no-op on Linux.
-func (clnt *client) Summary(containerID string) ([]Summary, error) {
-	return nil, nil
+// Processes started by containerd are reported with their command line,
+// any other process in the container's cgroup only with its pid.
+func (clnt *client) Summary(ctx context.Context, containerID string) ([]Summary, error) {
+	cont, err := clnt.getContainerdContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	var s []Summary
+	known := make(map[uint32]struct{}, len(cont.Processes))
+	for _, p := range cont.Processes {
+		known[p.SystemPid] = struct{}{}
+		s = append(s, Summary{
+			Pid:       p.SystemPid,
+			ProcessID: p.Pid,
+			Command:   strings.Join(p.Args, " "),
+		})
+	}
+	for _, pid := range cont.Pids {
+		if _, ok := known[pid]; !ok {
+			s = append(s, Summary{Pid: pid})
+		}
+	}
+	return s, nil
+}
+
+// waitPendingEvents waits for the queued state notifications to be
+// delivered to the backend.
+func (clnt *client) waitPendingEvents() {
+	clnt.q.wait()
 }
 
-func (clnt *client) getContainerdContainer(containerID string) (*containerd.Container, error) {
-	resp, err := clnt.remote.apiClient.State(context.Background(), &containerd.StateRequest{Id: containerID})
+func (clnt *client) getContainerdContainer(ctx context.Context, containerID string) (*containerd.Container, error) {
+	resp, err := clnt.remote.apiClient.State(ctx, &containerd.StateRequest{Id: containerID})
 	if err != nil {
 		return nil, err
 	}
@@ -339,10 +427,13 @@ func (clnt *client) newContainer(dir string, options ...CreateOption) *container
 			logrus.Error(err)
 		}
 	}
+	if container.fifoRoot != "" {
+		clnt.startFifoJanitor(container.fifoRoot)
+	}
 	return container
 }
 
-func (clnt *client) UpdateResources(containerID string, resources Resources) error {
+func (clnt *client) UpdateResources(ctx context.Context, containerID string, resources Resources) error {
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
 	container, err := clnt.getContainer(containerID)
@@ -350,9 +441,9 @@ func (clnt *client) UpdateResources(containerID string, resources Resources) err
 		return err
 	}
 	if container.systemPid == 0 {
-		return fmt.Errorf("No active process for container %s", containerID)
+		return ErrNoActiveProcess{containerID}
 	}
-	_, err = clnt.remote.apiClient.UpdateContainer(context.Background(), &containerd.UpdateContainerRequest{
+	_, err = clnt.remote.apiClient.UpdateContainer(ctx, &containerd.UpdateContainerRequest{
 		Id:        containerID,
 		Pid:       InitFriendlyName,
 		Resources: (*containerd.UpdateResource)(&resources),
@@ -363,6 +454,81 @@ func (clnt *client) UpdateResources(containerID string, resources Resources) err
 	return nil
 }
 
+// Checkpoint creates a checkpoint of a running container using CRIU.
+func (clnt *client) Checkpoint(ctx context.Context, containerID, checkpointID string, opts CheckpointOpts) error {
+	clnt.lock(containerID)
+	defer clnt.unlock(containerID)
+	container, err := clnt.getContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if container.systemPid == 0 {
+		return ErrNoActiveProcess{containerID}
+	}
+	_, err = clnt.remote.apiClient.CreateCheckpoint(ctx, &containerd.CreateCheckpointRequest{
+		Id: containerID,
+		Checkpoint: &containerd.Checkpoint{
+			Name:        checkpointID,
+			Exit:        opts.Exit,
+			Tcp:         opts.TCP,
+			UnixSockets: opts.UnixSockets,
+			Shell:       opts.Shell,
+		},
+	})
+	return err
+}
+
+// DeleteCheckpoint removes a checkpoint of a container. Checkpoints of
+// containers that are not running are removed from the saved location.
+func (clnt *client) DeleteCheckpoint(ctx context.Context, containerID, checkpointID string) error {
+	clnt.lock(containerID)
+	defer clnt.unlock(containerID)
+	if _, err := clnt.getContainer(containerID); err != nil {
+		p := filepath.Join(clnt.checkpointsDir(containerID), checkpointID)
+		if _, err := os.Stat(p); err != nil {
+			return fmt.Errorf("checkpoint %s does not exist for container %s", checkpointID, containerID)
+		}
+		return os.RemoveAll(p)
+	}
+	_, err := clnt.remote.apiClient.DeleteCheckpoint(ctx, &containerd.DeleteCheckpointRequest{
+		Id:   containerID,
+		Name: checkpointID,
+	})
+	return err
+}
+
+// ListCheckpoints returns the checkpoints of a container. Checkpoints of
+// containers that are not running are listed from the saved location.
+func (clnt *client) ListCheckpoints(ctx context.Context, containerID string) ([]Checkpoint, error) {
+	clnt.lock(containerID)
+	defer clnt.unlock(containerID)
+	var checkpoints []Checkpoint
+	if _, err := clnt.getContainer(containerID); err != nil {
+		fis, err := ioutil.ReadDir(clnt.checkpointsDir(containerID))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, fi := range fis {
+			checkpoints = append(checkpoints, Checkpoint{Name: fi.Name()})
+		}
+		return checkpoints, nil
+	}
+	resp, err := clnt.remote.apiClient.ListCheckpoint(ctx, &containerd.ListCheckpointRequest{Id: containerID})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range resp.Checkpoints {
+		checkpoints = append(checkpoints, Checkpoint(*c))
+	}
+	return checkpoints, nil
+}
+
+// checkpointsDir returns the directory checkpoints of a container are saved
+// to while the container is not running.
+func (clnt *client) checkpointsDir(containerID string) string {
+	return filepath.Join(clnt.remote.stateDir, checkpointsDirname, containerID)
+}
+
 func (clnt *client) getExitNotifier(containerID string) *exitNotifier {
 	clnt.mapMutex.RLock()
 	defer clnt.mapMutex.RUnlock()
@@ -1,15 +1,14 @@
-// +build experimental
-
 package libcontainerd
 
 import (
-	"fmt"
+	"path/filepath"
 
 	"github.com/Sirupsen/logrus"
 	containerd "github.com/docker/containerd/api/grpc/types"
+	"golang.org/x/net/context"
 )
 
-func (clnt *client) restore(cont *containerd.Container, options ...CreateOption) (err error) {
+func (clnt *client) restore(ctx context.Context, cont *containerd.Container, options ...CreateOption) (err error) {
 	clnt.lock(cont.Id)
 	defer clnt.unlock(cont.Id)
 
@@ -17,7 +16,7 @@ func (clnt *client) restore(cont *containerd.Container, options ...CreateOption)
 
 	containerID := cont.Id
 	if _, err := clnt.getContainer(containerID); err == nil {
-		return fmt.Errorf("container %s is already active", containerID)
+		return ErrAlreadyActive{containerID}
 	}
 
 	defer func() {
@@ -33,6 +32,11 @@ func (clnt *client) restore(cont *containerd.Container, options ...CreateOption)
 	for _, p := range cont.Processes {
 		if p.Pid == InitFriendlyName {
 			terminal = p.Terminal
+			// Reopen the fifos containerd is actually using, they might
+			// have been created with a different fifo directory.
+			if p.Stdin != "" {
+				container.fifoDir = filepath.Dir(p.Stdin)
+			}
 		}
 	}
 
@@ -73,10 +77,12 @@ func (clnt *client) restore(cont *containerd.Container, options ...CreateOption)
 	return nil
 }
 
-func (clnt *client) Restore(containerID string, options ...CreateOption) error {
-	cont, err := clnt.getContainerdContainer(containerID)
+// liveRestore reattaches to a container that is still running in containerd,
+// leaving it running.
+func (clnt *client) liveRestore(ctx context.Context, containerID string, options ...CreateOption) error {
+	cont, err := clnt.getContainerdContainer(ctx, containerID)
 	if err == nil && cont.Status != "stopped" {
-		if err := clnt.restore(cont, options...); err != nil {
+		if err := clnt.restore(ctx, cont, options...); err != nil {
 			logrus.Errorf("error restoring %s: %v", containerID, err)
 		}
 		return nil
@@ -1,5 +1,3 @@
-// +build !experimental
-
 package libcontainerd
 
 import (
@@ -7,12 +5,15 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
-func (clnt *client) Restore(containerID string, options ...CreateOption) error {
+// shutdownRestore stops a container that is still running in containerd,
+// so it can be started fresh by the daemon.
+func (clnt *client) shutdownRestore(ctx context.Context, containerID string) error {
 	w := clnt.getOrCreateExitNotifier(containerID)
 	defer w.close()
-	cont, err := clnt.getContainerdContainer(containerID)
+	cont, err := clnt.getContainerdContainer(ctx, containerID)
 	if err == nil && cont.Status != "stopped" {
 		clnt.lock(cont.Id)
 		container := clnt.newContainer(cont.BundlePath)
@@ -20,12 +21,12 @@ func (clnt *client) Restore(containerID string, options ...CreateOption) error {
 		clnt.appendContainer(container)
 		clnt.unlock(cont.Id)
 
-		if err := clnt.Signal(containerID, int(syscall.SIGTERM)); err != nil {
+		if err := clnt.Signal(ctx, containerID, int(syscall.SIGTERM)); err != nil {
 			logrus.Errorf("error sending sigterm to %v: %v", containerID, err)
 		}
 		select {
 		case <-time.After(10 * time.Second):
-			if err := clnt.Signal(containerID, int(syscall.SIGKILL)); err != nil {
+			if err := clnt.Signal(ctx, containerID, int(syscall.SIGKILL)); err != nil {
 				logrus.Errorf("error sending sigkill to %v: %v", containerID, err)
 			}
 			select {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Microsoft/hcsshim"
 	"github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 type client struct {
@@ -105,8 +106,12 @@ const defaultOwner = "docker"
 
 // Create is the entrypoint to create a container from a spec, and if successfully
 // created, start it too.
-func (clnt *client) Create(containerID string, spec Spec, options ...CreateOption) error {
+func (clnt *client) Create(ctx context.Context, containerID string, spec Spec, options ...CreateOption) error {
 	logrus.Debugln("LCD client.Create() with spec", spec)
+	if err := clnt.beginCreate(containerID); err != nil {
+		return err
+	}
+	defer clnt.endCreate()
 
 	cu := &containerInit{
 		SystemType: "Container",
@@ -236,7 +241,7 @@ func (clnt *client) Create(containerID string, spec Spec, options ...CreateOptio
 
 // AddProcess is the handler for adding a process to an already running
 // container. It's called through docker exec.
-func (clnt *client) AddProcess(containerID, processFriendlyName string, procToAdd Process) error {
+func (clnt *client) AddProcess(ctx context.Context, containerID, processFriendlyName string, procToAdd Process) error {
 
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
@@ -308,9 +313,21 @@ func (clnt *client) AddProcess(containerID, processFriendlyName string, procToAd
 
 	// Tell the engine to attach streams back to the client
 	if err := clnt.backend.AttachStreams(processFriendlyName, *iopipe); err != nil {
+		clnt.lock(containerID)
 		return err
 	}
 
+	// Tell the engine that the exec'd process has started.
+	si := StateInfo{
+		CommonStateInfo: CommonStateInfo{
+			State:     StateStartProcess,
+			Pid:       pid,
+			ProcessID: processFriendlyName,
+		}}
+	if err := clnt.backend.StateChanged(containerID, si); err != nil {
+		logrus.Error(err)
+	}
+
 	// Lock again so that the defer unlock doesn't fail. (I really don't like this code)
 	clnt.lock(containerID)
 
@@ -323,7 +340,7 @@ func (clnt *client) AddProcess(containerID, processFriendlyName string, procToAd
 // Signal handles `docker stop` on Windows. While Linux has support for
 // the full range of signals, signals aren't really implemented on Windows.
 // We fake supporting regular stop and -9 to force kill.
-func (clnt *client) Signal(containerID string, sig int) error {
+func (clnt *client) Signal(ctx context.Context, containerID string, sig int) error {
 	var (
 		cont *container
 		err  error
@@ -339,13 +356,7 @@ func (clnt *client) Signal(containerID string, sig int) error {
 	logrus.Debugf("lcd: Signal() containerID=%s sig=%d pid=%d", containerID, sig, cont.systemPid)
 	context := fmt.Sprintf("Signal: sig=%d pid=%d", sig, cont.systemPid)
 
-	if syscall.Signal(sig) == syscall.SIGKILL {
-		// Terminate the compute system
-		if err := hcsshim.TerminateComputeSystem(containerID, hcsshim.TimeoutInfinite, context); err != nil {
-			logrus.Errorf("Failed to terminate %s - %q", containerID, err)
-		}
-
-	} else {
+	if syscall.Signal(sig) != syscall.SIGKILL {
 		// Terminate Process
 		if err = hcsshim.TerminateProcessInComputeSystem(containerID, cont.systemPid); err != nil {
 			logrus.Warnf("Failed to terminate pid %d in %s: %q", cont.systemPid, containerID, err)
@@ -353,17 +364,25 @@ func (clnt *client) Signal(containerID string, sig int) error {
 			err = nil
 		}
 
-		// Shutdown the compute system
-		if err := hcsshim.ShutdownComputeSystem(containerID, hcsshim.TimeoutInfinite, context); err != nil {
-			logrus.Errorf("Failed to shutdown %s - %q", containerID, err)
+		// Shutdown the compute system, falling back to terminating it
+		err = hcsshim.ShutdownComputeSystem(containerID, hcsshim.TimeoutInfinite, context)
+		if err == nil || isAlreadyStoppedErr(err) {
+			return nil
 		}
+		logrus.Warnf("Failed to shutdown %s, terminating it instead - %q", containerID, err)
+	}
+
+	// Terminate the compute system
+	if err := hcsshim.TerminateComputeSystem(containerID, hcsshim.TimeoutInfinite, context); err != nil && !isAlreadyStoppedErr(err) {
+		logrus.Errorf("Failed to terminate %s - %q", containerID, err)
+		return err
 	}
 	return nil
 }
 
 // Resize handles a CLI event to resize an interactive docker run or docker exec
 // window.
-func (clnt *client) Resize(containerID, processFriendlyName string, width, height int) error {
+func (clnt *client) Resize(ctx context.Context, containerID, processFriendlyName string, width, height int) error {
 	// Get the libcontainerd container object
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
@@ -389,22 +408,27 @@ func (clnt *client) Resize(containerID, processFriendlyName string, width, heigh
 }
 
 // Pause handles pause requests for containers
-func (clnt *client) Pause(containerID string) error {
+func (clnt *client) Pause(ctx context.Context, containerID string) error {
 	return errors.New("Windows: Containers cannot be paused")
 }
 
 // Resume handles resume requests for containers
-func (clnt *client) Resume(containerID string) error {
+func (clnt *client) Resume(ctx context.Context, containerID string) error {
 	return errors.New("Windows: Containers cannot be paused")
 }
 
 // Stats handles stats requests for containers
-func (clnt *client) Stats(containerID string) (*Stats, error) {
+func (clnt *client) Stats(ctx context.Context, containerID string) (*Stats, error) {
 	return nil, errors.New("Windows: Stats not implemented")
 }
 
+// StatsStream handles streaming stats requests for containers
+func (clnt *client) StatsStream(ctx context.Context, containerID string) (<-chan *Stats, func(), error) {
+	return nil, nil, errors.New("Windows: Stats not implemented")
+}
+
 // Restore is the handler for restoring a container
-func (clnt *client) Restore(containerID string, unusedOnWindows ...CreateOption) error {
+func (clnt *client) Restore(ctx context.Context, containerID string, unusedOnWindows ...CreateOption) error {
 	// TODO Windows: Implement this. For now, just tell the backend the container exited.
 	logrus.Debugf("lcd Restore %s", containerID)
 	return clnt.backend.StateChanged(containerID, StateInfo{
@@ -416,7 +440,7 @@ func (clnt *client) Restore(containerID string, unusedOnWindows ...CreateOption)
 
 // GetPidsForContainer returns a list of process IDs running in a container.
 // Although implemented, this is not used in Windows.
-func (clnt *client) GetPidsForContainer(containerID string) ([]int, error) {
+func (clnt *client) GetPidsForContainer(ctx context.Context, containerID string) ([]int, error) {
 	var pids []int
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
@@ -440,7 +464,7 @@ func (clnt *client) GetPidsForContainer(containerID string) ([]int, error) {
 // the containers could be Hyper-V containers, they would not be
 // visible on the container host. However, libcontainerd does have
 // that information.
-func (clnt *client) Summary(containerID string) ([]Summary, error) {
+func (clnt *client) Summary(ctx context.Context, containerID string) ([]Summary, error) {
 	var s []Summary
 	clnt.lock(containerID)
 	defer clnt.unlock(containerID)
@@ -451,21 +475,43 @@ func (clnt *client) Summary(containerID string) ([]Summary, error) {
 
 	// Add the first process
 	s = append(s, Summary{
-		Pid:     cont.containerCommon.systemPid,
-		Command: cont.ociSpec.Process.Args[0]})
+		Pid:       cont.containerCommon.systemPid,
+		ProcessID: InitFriendlyName,
+		Command:   cont.ociSpec.Process.Args[0]})
 	// And add all the exec'd processes
 	for _, p := range cont.processes {
 		s = append(s, Summary{
-			Pid:     p.processCommon.systemPid,
-			Command: p.commandLine})
+			Pid:       p.processCommon.systemPid,
+			ProcessID: p.processCommon.friendlyName,
+			Command:   p.commandLine})
 	}
 	return s, nil
 
 }
 
+// waitPendingEvents is a no-op on Windows, state changes are delivered
+// synchronously to the backend.
+func (clnt *client) waitPendingEvents() {
+}
+
 // UpdateResources updates resources for a running container.
-func (clnt *client) UpdateResources(containerID string, resources Resources) error {
+func (clnt *client) UpdateResources(ctx context.Context, containerID string, resources Resources) error {
 	// Updating resource isn't supported on Windows
 	// but we should return nil for enabling updating container
 	return nil
 }
+
+// Checkpoint creates a checkpoint of a running container
+func (clnt *client) Checkpoint(ctx context.Context, containerID, checkpointID string, opts CheckpointOpts) error {
+	return errors.New("Windows: Containers do not support checkpoints")
+}
+
+// DeleteCheckpoint removes a checkpoint of a container
+func (clnt *client) DeleteCheckpoint(ctx context.Context, containerID, checkpointID string) error {
+	return errors.New("Windows: Containers do not support checkpoints")
+}
+
+// ListCheckpoints lists the checkpoints of a container
+func (clnt *client) ListCheckpoints(ctx context.Context, containerID string) ([]Checkpoint, error) {
+	return nil, errors.New("Windows: Containers do not support checkpoints")
+}
@@ -2,6 +2,7 @@ package libcontainerd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/docker/docker/restartmanager"
 )
@@ -9,14 +10,16 @@ import (
 const (
 	// InitFriendlyName is the name given in the lookup map of processes
 	// for the first process started in a container.
-	InitFriendlyName = "init"
-	configFilename   = "config.json"
+	InitFriendlyName   = "init"
+	configFilename     = "config.json"
+	checkpointsDirname = "checkpoints"
 )
 
 type containerCommon struct {
 	process
 	restartManager restartmanager.RestartManager
 	restarting     bool
+	startedAt      time.Time // startedAt is when the init process was last started
 	processes      map[string]*process
 }
 
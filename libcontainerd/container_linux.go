@@ -2,10 +2,12 @@ package libcontainerd
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	containerd "github.com/docker/containerd/api/grpc/types"
@@ -18,7 +20,66 @@ type container struct {
 
 	// Platform specific fields are below here.
 	pauseMonitor
-	oom bool
+	oom         bool
+	checkpoint  string
+	runtime     string
+	runtimeArgs []string
+	fifoRoot    string
+}
+
+// WithRuntime sets the runc compatible binary containerd uses to run the
+// container, together with its extra arguments.
+func WithRuntime(path string, args []string) CreateOption {
+	return runtime{path, args}
+}
+
+type runtime struct {
+	path string
+	args []string
+}
+
+func (rt runtime) Apply(p interface{}) error {
+	if pr, ok := p.(*container); ok {
+		pr.runtime = rt.path
+		pr.runtimeArgs = rt.args
+		return nil
+	}
+	return fmt.Errorf("WithRuntime option not supported for this client")
+}
+
+// WithFifoDir places the stdio fifos of the container and its exec'd
+// processes in a directory named after the container under dir, instead of
+// in the bundle. Fifos of containers which no longer exist are removed from
+// dir periodically.
+func WithFifoDir(dir string) CreateOption {
+	return fifoDir(dir)
+}
+
+type fifoDir string
+
+func (d fifoDir) Apply(p interface{}) error {
+	if pr, ok := p.(*container); ok {
+		pr.fifoRoot = string(d)
+		pr.process.fifoDir = filepath.Join(string(d), pr.containerID)
+		return nil
+	}
+	return fmt.Errorf("WithFifoDir option not supported for this client")
+}
+
+// WithCheckpoint makes Create start the container from the named checkpoint
+// instead of running its process from scratch.
+func WithCheckpoint(checkpointID string) CreateOption {
+	return checkpoint(checkpointID)
+}
+
+type checkpoint string
+
+func (c checkpoint) Apply(p interface{}) error {
+	if pr, ok := p.(*container); ok {
+		pr.checkpoint = string(c)
+		return nil
+	}
+	return fmt.Errorf("WithCheckpoint option not supported for this client")
 }
 
 func (ctr *container) clean() error {
@@ -30,12 +91,62 @@ func (ctr *container) clean() error {
 	}
 
 	syscall.Unmount(filepath.Join(ctr.dir, "rootfs"), syscall.MNT_DETACH) // ignore error
+	if err := ctr.saveCheckpoints(); err != nil {
+		logrus.Warnf("libcontainerd: failed to save checkpoints of %s: %v", ctr.containerID, err)
+	}
 	if err := os.RemoveAll(ctr.dir); err != nil {
 		return err
 	}
+	if ctr.fifoDir != "" {
+		if err := os.RemoveAll(ctr.fifoDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveCheckpoints moves the checkpoints containerd keeps in the bundle out of
+// it, so they survive the bundle being removed when the container exits.
+func (ctr *container) saveCheckpoints() error {
+	src := filepath.Join(ctr.dir, checkpointsDirname)
+	fis, err := ioutil.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	dst := ctr.client.checkpointsDir(ctr.containerID)
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if err := os.RemoveAll(filepath.Join(dst, fi.Name())); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(src, fi.Name()), filepath.Join(dst, fi.Name())); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// loadCheckpoints moves previously saved checkpoints back into the bundle
+// where containerd expects them.
+func (ctr *container) loadCheckpoints() error {
+	src := ctr.client.checkpointsDir(ctr.containerID)
+	if _, err := os.Stat(src); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(ctr.dir, 0700); err != nil {
+		return err
+	}
+	return os.Rename(src, filepath.Join(ctr.dir, checkpointsDirname))
+}
+
 func (ctr *container) spec() (*specs.Spec, error) {
 	var spec specs.Spec
 	dt, err := ioutil.ReadFile(filepath.Join(ctr.dir, configFilename))
@@ -48,7 +159,7 @@ func (ctr *container) spec() (*specs.Spec, error) {
 	return &spec, nil
 }
 
-func (ctr *container) start() error {
+func (ctr *container) start(ctx context.Context) error {
 	spec, err := ctr.spec()
 	if err != nil {
 		return nil
@@ -59,26 +170,32 @@ func (ctr *container) start() error {
 	}
 
 	r := &containerd.CreateContainerRequest{
-		Id:         ctr.containerID,
-		BundlePath: ctr.dir,
-		Stdin:      ctr.fifo(syscall.Stdin),
-		Stdout:     ctr.fifo(syscall.Stdout),
-		Stderr:     ctr.fifo(syscall.Stderr),
+		Id:          ctr.containerID,
+		BundlePath:  ctr.dir,
+		Stdin:       ctr.fifo(syscall.Stdin),
+		Stdout:      ctr.fifo(syscall.Stdout),
+		Stderr:      ctr.fifo(syscall.Stderr),
+		Checkpoint:  ctr.checkpoint,
+		Runtime:     ctr.runtime,
+		RuntimeArgs: ctr.runtimeArgs,
 		// check to see if we are running in ramdisk to disable pivot root
 		NoPivotRoot: os.Getenv("DOCKER_RAMDISK") != "",
 	}
 	ctr.client.appendContainer(ctr)
 
-	resp, err := ctr.client.remote.apiClient.CreateContainer(context.Background(), r)
+	resp, err := ctr.client.remote.apiClient.CreateContainer(ctx, r)
 	if err != nil {
 		ctr.closeFifos(iopipe)
 		return err
 	}
+	// A restart must not bring the container back to the checkpoint.
+	ctr.checkpoint = ""
 
 	if err := ctr.client.backend.AttachStreams(ctr.containerID, *iopipe); err != nil {
 		return err
 	}
 	ctr.systemPid = systemPid(resp.Container)
+	ctr.startedAt = time.Now()
 
 	return ctr.client.backend.StateChanged(ctr.containerID, StateInfo{
 		CommonStateInfo: CommonStateInfo{
@@ -89,7 +206,8 @@ func (ctr *container) start() error {
 
 func (ctr *container) newProcess(friendlyName string) *process {
 	return &process{
-		dir: ctr.dir,
+		dir:     ctr.dir,
+		fifoDir: ctr.fifoDir,
 		processCommon: processCommon{
 			containerID:  ctr.containerID,
 			friendlyName: friendlyName,
@@ -108,22 +226,29 @@ func (ctr *container) handleEvent(e *containerd.Event) error {
 				State:    e.Type,
 				ExitCode: e.Status,
 			},
-			OOMKilled: e.Type == StateExit && ctr.oom,
 		}
 		if e.Type == StateOOM {
 			ctr.oom = true
 		}
-		if e.Type == StateExit && e.Pid != InitFriendlyName {
-			st.ProcessID = e.Pid
-			st.State = StateExitProcess
+		if e.Type == StateExit {
+			if e.Pid != InitFriendlyName {
+				st.ProcessID = e.Pid
+				st.State = StateExitProcess
+			} else {
+				// The OOM notification for the cgroup is only meaningful for
+				// the init process, exec'd processes never report it.
+				st.OOMKilled = ctr.oom
+			}
 		}
 		if st.State == StateExit && ctr.restartManager != nil {
-			restart, wait, err := ctr.restartManager.ShouldRestart(e.Status, false)
+			restart, wait, err := ctr.restartManager.ShouldRestart(e.Status, false, time.Since(ctr.startedAt))
 			if err != nil {
 				logrus.Error(err)
 			} else if restart {
 				st.State = StateRestart
 				ctr.restarting = true
+				// The restarted container starts with a clean slate.
+				ctr.oom = false
 				ctr.client.deleteContainer(e.Id)
 				go func() {
 					err := <-wait
@@ -137,12 +262,20 @@ func (ctr *container) handleEvent(e *containerd.Event) error {
 						})
 						logrus.Error(err)
 					} else {
-						ctr.start()
+						ctr.start(context.Background())
 					}
 				}()
 			}
 		}
 
+		// An exec'd process is gone for good, drop it along with its fifos.
+		if st.State == StateExitProcess {
+			if p, ok := ctr.processes[e.Pid]; ok {
+				delete(ctr.processes, e.Pid)
+				p.removeFifos()
+			}
+		}
+
 		// Remove process from list if we have exited
 		// We need to do so here in case the Message Handler decides to restart it.
 		if st.State == StateExit {
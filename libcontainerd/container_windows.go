@@ -4,9 +4,11 @@ import (
 	"io"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Microsoft/hcsshim"
 	"github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 )
 
 type container struct {
@@ -90,6 +92,7 @@ func (ctr *container) start() error {
 	// Save the PID
 	logrus.Debugf("Process started - PID %d", pid)
 	ctr.systemPid = uint32(pid)
+	ctr.startedAt = time.Now()
 
 	// Spin up a go routine waiting for exit to handle cleanup
 	go ctr.waitExit(pid, InitFriendlyName, true)
@@ -142,6 +145,9 @@ func (ctr *container) waitExit(pid uint32, processFriendlyName string, isFirstPr
 	// But it could have been an exec'd process which exited
 	if !isFirstProcessToStart {
 		si.State = StateExitProcess
+		ctr.client.lock(ctr.containerID)
+		delete(ctr.processes, processFriendlyName)
+		ctr.client.unlock(ctr.containerID)
 	}
 
 	// If this is the init process, always call into vmcompute.dll to
@@ -157,10 +163,7 @@ func (ctr *container) waitExit(pid uint32, processFriendlyName string, isFirstPr
 		// (remote) possibility that ShutdownComputeSystem hangs indefinitely.
 		const shutdownTimeout = 5 * 60 * 1000 // 5 minutes
 		if err := hcsshim.ShutdownComputeSystem(ctr.containerID, shutdownTimeout, "waitExit"); err != nil {
-			if herr, ok := err.(*hcsshim.HcsError); !ok ||
-				(herr.Err != hcsshim.ERROR_SHUTDOWN_IN_PROGRESS &&
-					herr.Err != ErrorBadPathname &&
-					herr.Err != syscall.ERROR_PATH_NOT_FOUND) {
+			if !isAlreadyStoppedErr(err) {
 				logrus.Warnf("Ignoring error from ShutdownComputeSystem %s", err)
 			}
 		} else {
@@ -168,7 +171,7 @@ func (ctr *container) waitExit(pid uint32, processFriendlyName string, isFirstPr
 		}
 
 		if si.State == StateExit && ctr.restartManager != nil {
-			restart, wait, err := ctr.restartManager.ShouldRestart(uint32(exitCode), false)
+			restart, wait, err := ctr.restartManager.ShouldRestart(uint32(exitCode), false, time.Since(ctr.startedAt))
 			if err != nil {
 				logrus.Error(err)
 			} else if restart {
@@ -185,7 +188,7 @@ func (ctr *container) waitExit(pid uint32, processFriendlyName string, isFirstPr
 						}
 						logrus.Error(err)
 					} else {
-						ctr.client.Create(ctr.containerID, ctr.ociSpec, ctr.options...)
+						ctr.client.Create(context.Background(), ctr.containerID, ctr.ociSpec, ctr.options...)
 					}
 				}()
 			}
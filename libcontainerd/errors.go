@@ -0,0 +1,78 @@
+package libcontainerd
+
+import "fmt"
+
+// ErrContainerNotFound is returned when an operation refers to a container
+// that is not known to libcontainerd.
+type ErrContainerNotFound struct {
+	ContainerID string
+}
+
+func (e ErrContainerNotFound) Error() string {
+	return fmt.Sprintf("invalid container: %s", e.ContainerID)
+}
+
+// ErrNoActiveProcess is returned when an operation requires the init
+// process of a container to be running, but it is not.
+type ErrNoActiveProcess struct {
+	ContainerID string
+}
+
+func (e ErrNoActiveProcess) Error() string {
+	return fmt.Sprintf("No active process for container %s", e.ContainerID)
+}
+
+// ErrAlreadyActive is returned when trying to create or restore a container
+// that is already active.
+type ErrAlreadyActive struct {
+	ContainerID string
+}
+
+func (e ErrAlreadyActive) Error() string {
+	return fmt.Sprintf("Container %s is already active", e.ContainerID)
+}
+
+// ErrTimeout is returned when containerd did not confirm an operation on a
+// container in time.
+type ErrTimeout struct {
+	ContainerID string
+	Operation   string
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("timeout waiting for %s of container %s", e.Operation, e.ContainerID)
+}
+
+// ErrDraining is returned when trying to create a container while the
+// client is draining.
+type ErrDraining struct {
+	ContainerID string
+}
+
+func (e ErrDraining) Error() string {
+	return fmt.Sprintf("Cannot create container %s, libcontainerd is shutting down", e.ContainerID)
+}
+
+// IsNotFound returns true if the error indicates that the container is
+// not known to libcontainerd.
+func IsNotFound(err error) bool {
+	_, ok := err.(ErrContainerNotFound)
+	return ok
+}
+
+// IsConflict returns true if the error indicates that the operation
+// conflicts with the current state of the container.
+func IsConflict(err error) bool {
+	switch err.(type) {
+	case ErrNoActiveProcess, ErrAlreadyActive, ErrDraining:
+		return true
+	}
+	return false
+}
+
+// IsTimeout returns true if the error indicates that containerd did not
+// confirm an operation in time.
+func IsTimeout(err error) bool {
+	_, ok := err.(ErrTimeout)
+	return ok
+}
@@ -0,0 +1,77 @@
+package libcontainerd
+
+import (
+	"time"
+
+	containerd "github.com/docker/containerd/api/grpc/types"
+	"github.com/docker/docker/pkg/metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+var grpcDuration = metrics.NewHistogram("docker_libcontainerd_grpc_duration_seconds", "Latency of the requests made to containerd, in seconds.", nil, "method")
+
+func observeGRPC(method string, start time.Time) {
+	grpcDuration.Observe(time.Since(start).Seconds(), method)
+}
+
+// instrumentedAPIClient records the latency of every request made to
+// containerd. For Events, only the time to subscribe is recorded.
+type instrumentedAPIClient struct {
+	containerd.APIClient
+}
+
+func (c instrumentedAPIClient) CreateContainer(ctx context.Context, in *containerd.CreateContainerRequest, opts ...grpc.CallOption) (*containerd.CreateContainerResponse, error) {
+	defer observeGRPC("CreateContainer", time.Now())
+	return c.APIClient.CreateContainer(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) UpdateContainer(ctx context.Context, in *containerd.UpdateContainerRequest, opts ...grpc.CallOption) (*containerd.UpdateContainerResponse, error) {
+	defer observeGRPC("UpdateContainer", time.Now())
+	return c.APIClient.UpdateContainer(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) Signal(ctx context.Context, in *containerd.SignalRequest, opts ...grpc.CallOption) (*containerd.SignalResponse, error) {
+	defer observeGRPC("Signal", time.Now())
+	return c.APIClient.Signal(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) UpdateProcess(ctx context.Context, in *containerd.UpdateProcessRequest, opts ...grpc.CallOption) (*containerd.UpdateProcessResponse, error) {
+	defer observeGRPC("UpdateProcess", time.Now())
+	return c.APIClient.UpdateProcess(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) AddProcess(ctx context.Context, in *containerd.AddProcessRequest, opts ...grpc.CallOption) (*containerd.AddProcessResponse, error) {
+	defer observeGRPC("AddProcess", time.Now())
+	return c.APIClient.AddProcess(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) CreateCheckpoint(ctx context.Context, in *containerd.CreateCheckpointRequest, opts ...grpc.CallOption) (*containerd.CreateCheckpointResponse, error) {
+	defer observeGRPC("CreateCheckpoint", time.Now())
+	return c.APIClient.CreateCheckpoint(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) DeleteCheckpoint(ctx context.Context, in *containerd.DeleteCheckpointRequest, opts ...grpc.CallOption) (*containerd.DeleteCheckpointResponse, error) {
+	defer observeGRPC("DeleteCheckpoint", time.Now())
+	return c.APIClient.DeleteCheckpoint(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) ListCheckpoint(ctx context.Context, in *containerd.ListCheckpointRequest, opts ...grpc.CallOption) (*containerd.ListCheckpointResponse, error) {
+	defer observeGRPC("ListCheckpoint", time.Now())
+	return c.APIClient.ListCheckpoint(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) State(ctx context.Context, in *containerd.StateRequest, opts ...grpc.CallOption) (*containerd.StateResponse, error) {
+	defer observeGRPC("State", time.Now())
+	return c.APIClient.State(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) Events(ctx context.Context, in *containerd.EventsRequest, opts ...grpc.CallOption) (containerd.API_EventsClient, error) {
+	defer observeGRPC("Events", time.Now())
+	return c.APIClient.Events(ctx, in, opts...)
+}
+
+func (c instrumentedAPIClient) Stats(ctx context.Context, in *containerd.StatsRequest, opts ...grpc.CallOption) (*containerd.StatsResponse, error) {
+	defer observeGRPC("Stats", time.Now())
+	return c.APIClient.Stats(ctx, in, opts...)
+}
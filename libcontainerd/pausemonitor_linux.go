@@ -29,3 +29,13 @@ func (m *pauseMonitor) append(t string, waiter chan struct{}) {
 	}
 	m.waiters[t] = append(m.waiters[t], waiter)
 }
+
+func (m *pauseMonitor) remove(t string, waiter chan struct{}) {
+	q := m.waiters[t]
+	for i, w := range q {
+		if w == waiter {
+			m.waiters[t] = append(q[:i:i], q[i+1:]...)
+			return
+		}
+	}
+}
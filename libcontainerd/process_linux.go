@@ -3,15 +3,22 @@ package libcontainerd
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	containerd "github.com/docker/containerd/api/grpc/types"
 	"github.com/docker/docker/pkg/ioutils"
 	"golang.org/x/net/context"
 )
 
+// fifoJanitorInterval is how often directories given with WithFifoDir are
+// checked for fifos of containers that no longer exist.
+const fifoJanitorInterval = 5 * time.Minute
+
 var fdNames = map[int]string{
 	syscall.Stdin:  "stdin",
 	syscall.Stdout: "stdout",
@@ -24,14 +31,22 @@ type process struct {
 
 	// Platform specific fields are below here.
 	dir string
+	// fifoDir is where the stdio fifos are created, the bundle
+	// directory is used if empty.
+	fifoDir string
 }
 
-func (p *process) openFifos(terminal bool) (*IOPipe, error) {
-	bundleDir := p.dir
-	if err := os.MkdirAll(bundleDir, 0700); err != nil {
+func (p *process) openFifos(terminal bool) (_ *IOPipe, err error) {
+	if err := os.MkdirAll(p.fifoRoot(), 0700); err != nil {
 		return nil, err
 	}
 
+	defer func() {
+		if err != nil {
+			p.removeFifos()
+		}
+	}()
+
 	for i := 0; i < 3; i++ {
 		f := p.fifo(i)
 		if err := syscall.Mkfifo(f, 0700); err != nil && !os.IsExist(err) {
@@ -71,6 +86,15 @@ func (p *process) closeFifos(io *IOPipe) {
 	closeReaderFifo(p.fifo(syscall.Stderr))
 }
 
+// removeFifos removes the fifos of a process that has exited.
+func (p *process) removeFifos() {
+	for _, i := range []int{syscall.Stdin, syscall.Stdout, syscall.Stderr} {
+		if err := os.Remove(p.fifo(i)); err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("libcontainerd: failed to remove fifo %s: %v", p.fifo(i), err)
+		}
+	}
+}
+
 type emptyReader struct{}
 
 func (r emptyReader) Read(b []byte) (int, error) {
@@ -102,6 +126,57 @@ func closeReaderFifo(fn string) {
 	f.Close()
 }
 
+func (p *process) fifoRoot() string {
+	if p.fifoDir != "" {
+		return p.fifoDir
+	}
+	return p.dir
+}
+
 func (p *process) fifo(index int) string {
-	return filepath.Join(p.dir, p.friendlyName+"-"+fdNames[index])
+	return filepath.Join(p.fifoRoot(), p.friendlyName+"-"+fdNames[index])
+}
+
+// removeOrphanedFifos removes the fifo directories under root which belong
+// to containers that are no longer known to the client.
+func (clnt *client) removeOrphanedFifos(root string) {
+	fis, err := ioutil.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("libcontainerd: failed to list fifo directory %s: %v", root, err)
+		}
+		return
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		id := fi.Name()
+		// Holding the container lock makes sure that the container is
+		// not being created or restored concurrently.
+		clnt.lock(id)
+		if _, err := clnt.getContainer(id); err != nil {
+			logrus.Debugf("libcontainerd: removing orphaned fifos of %s", id)
+			if err := os.RemoveAll(filepath.Join(root, id)); err != nil {
+				logrus.Warnf("libcontainerd: failed to remove orphaned fifos of %s: %v", id, err)
+			}
+		}
+		clnt.unlock(id)
+	}
+}
+
+// startFifoJanitor periodically removes orphaned fifos under root. It is
+// started at most once per directory.
+func (clnt *client) startFifoJanitor(root string) {
+	clnt.mapMutex.Lock()
+	defer clnt.mapMutex.Unlock()
+	if _, ok := clnt.fifoJanitors[root]; ok {
+		return
+	}
+	clnt.fifoJanitors[root] = struct{}{}
+	go func() {
+		for range time.Tick(fifoJanitorInterval) {
+			clnt.removeOrphanedFifos(root)
+		}
+	}()
 }
@@ -1,10 +1,14 @@
 package libcontainerd
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 type queue struct {
 	sync.Mutex
-	fns map[string]chan struct{}
+	fns     map[string]chan struct{}
+	pending int32 // number of functions not yet run
 }
 
 func (q *queue) append(id string, f func()) {
@@ -19,11 +23,31 @@ func (q *queue) append(id string, f func()) {
 
 	fn, ok := q.fns[id]
 	q.fns[id] = done
+	atomic.AddInt32(&q.pending, 1)
 	go func() {
 		if ok {
 			<-fn
 		}
 		f()
+		atomic.AddInt32(&q.pending, -1)
 		close(done)
 	}()
 }
+
+// len returns the number of functions which have not run yet.
+func (q *queue) len() int {
+	return int(atomic.LoadInt32(&q.pending))
+}
+
+// wait blocks until all functions appended so far have run.
+func (q *queue) wait() {
+	q.Lock()
+	pending := make([]chan struct{}, 0, len(q.fns))
+	for _, done := range q.fns {
+		pending = append(pending, done)
+	}
+	q.Unlock()
+	for _, done := range pending {
+		<-done
+	}
+}
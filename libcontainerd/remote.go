@@ -9,6 +9,19 @@ type Remote interface {
 	// Cleanup stops containerd if it was started by libcontainerd.
 	// Note this is not used on Windows as there is no remote containerd.
 	Cleanup()
+	// Status reports the state of containerd and of the connection to it.
+	Status() RemoteStatus
+}
+
+// RemoteStatus describes the state of containerd as seen by libcontainerd.
+type RemoteStatus struct {
+	// Version is the version of containerd, empty if unknown.
+	Version string
+	// ConnectionState is the state of the gRPC connection to containerd.
+	ConnectionState string
+	// EventBacklog is the number of state changes from containerd which
+	// have not been delivered to the backend yet.
+	EventBacklog int
 }
 
 // RemoteOption allows to configure parameters of remotes.
@@ -18,6 +18,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	containerd "github.com/docker/containerd/api/grpc/types"
 	"github.com/docker/docker/pkg/locker"
+	"github.com/docker/docker/pkg/metrics"
 	sysinfo "github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/utils"
 	"golang.org/x/net/context"
@@ -33,6 +34,9 @@ const (
 	containerdPidFilename     = "docker-containerd.pid"
 	containerdSockFilename    = "docker-containerd.sock"
 	eventTimestampFilename    = "event.ts"
+	eventsReconnectMinDelay   = 100 * time.Millisecond
+	eventsReconnectMaxDelay   = 30 * time.Second
+	defaultStateChangeTimeout = 60 * time.Second
 )
 
 type remote struct {
@@ -48,6 +52,15 @@ type remote struct {
 	eventTsPath string
 	pastEvents  map[string]*containerd.Event
 	runtimeArgs []string
+	closed      bool
+	liveRestore bool
+
+	// stateChangeTimeout is how long to wait for containerd to confirm a
+	// pause or resume before giving up.
+	stateChangeTimeout time.Duration
+
+	versionOnce sync.Once
+	version     string
 }
 
 // New creates a fresh instance of libcontainerd remote.
@@ -62,6 +75,8 @@ func New(stateDir string, options ...RemoteOption) (_ Remote, err error) {
 		daemonPid:   -1,
 		eventTsPath: filepath.Join(stateDir, eventTimestampFilename),
 		pastEvents:  make(map[string]*containerd.Event),
+
+		stateChangeTimeout: defaultStateChangeTimeout,
 	}
 	for _, option := range options {
 		if err := option.Apply(r); err != nil {
@@ -96,11 +111,14 @@ func New(stateDir string, options ...RemoteOption) (_ Remote, err error) {
 	}
 
 	r.rpcConn = conn
-	r.apiClient = containerd.NewAPIClient(conn)
+	r.apiClient = instrumentedAPIClient{containerd.NewAPIClient(conn)}
+	metrics.NewGaugeFunc("docker_libcontainerd_event_queue_depth", "The number of containerd state changes not yet delivered to the daemon.", func() float64 {
+		return float64(r.eventBacklog())
+	})
 
 	go r.handleConnectionChange()
 
-	if err := r.startEventsMonitor(); err != nil {
+	if err := r.startEventsMonitor(false); err != nil {
 		return nil, err
 	}
 
@@ -144,6 +162,9 @@ func (r *remote) handleConnectionChange() {
 }
 
 func (r *remote) Cleanup() {
+	r.Lock()
+	r.closed = true
+	r.Unlock()
 	if r.daemonPid == -1 {
 		return
 	}
@@ -178,6 +199,8 @@ func (r *remote) Client(b Backend) (Client, error) {
 		},
 		remote:        r,
 		exitNotifiers: make(map[string]*exitNotifier),
+		statsStreams:  make(map[string]*statsStream),
+		fifoJanitors:  make(map[string]struct{}),
 	}
 
 	r.Lock()
@@ -186,29 +209,44 @@ func (r *remote) Client(b Backend) (Client, error) {
 	return c, nil
 }
 
+// updateEventTimestamp persists the timestamp of the last processed event so
+// that events arriving while the daemon is down are replayed on startup. The
+// file is replaced atomically so a crash never leaves a truncated timestamp.
 func (r *remote) updateEventTimestamp(t time.Time) {
-	f, err := os.OpenFile(r.eventTsPath, syscall.O_CREAT|syscall.O_WRONLY|syscall.O_TRUNC, 0600)
-	defer f.Close()
+	b, err := t.MarshalText()
 	if err != nil {
-		logrus.Warnf("libcontainerd: failed to open event timestamp file: %v", err)
+		logrus.Warnf("libcontainerd: failed to encode timestamp: %v", err)
 		return
 	}
 
-	b, err := t.MarshalText()
+	tmp := r.eventTsPath + ".tmp"
+	f, err := os.OpenFile(tmp, syscall.O_CREAT|syscall.O_WRONLY|syscall.O_TRUNC, 0600)
 	if err != nil {
-		logrus.Warnf("libcontainerd: failed to encode timestamp: %v", err)
+		logrus.Warnf("libcontainerd: failed to open event timestamp file: %v", err)
 		return
 	}
-
 	n, err := f.Write(b)
-	if err != nil || n != len(b) {
+	if err == nil && n != len(b) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, r.eventTsPath)
+	}
+	if err != nil {
 		logrus.Warnf("libcontainerd: failed to update event timestamp file: %v", err)
-		f.Truncate(0)
-		return
+		os.Remove(tmp)
 	}
-
 }
 
+// getLastEventTimestamp returns the timestamp of the last processed event.
+// If the timestamp cannot be decoded, the modification time of the file is
+// used instead, so that no events since then are missed.
 func (r *remote) getLastEventTimestamp() int64 {
 	t := time.Now()
 
@@ -217,26 +255,24 @@ func (r *remote) getLastEventTimestamp() int64 {
 		return t.Unix()
 	}
 
-	f, err := os.Open(r.eventTsPath)
-	defer f.Close()
+	b, err := ioutil.ReadFile(r.eventTsPath)
 	if err != nil {
-		logrus.Warn("libcontainerd: Unable to access last event ts: %v", err)
-		return t.Unix()
+		logrus.Warnf("libcontainerd: Unable to read last event ts: %v", err)
+		return fi.ModTime().Unix()
 	}
 
-	b := make([]byte, fi.Size())
-	n, err := f.Read(b)
-	if err != nil || n != len(b) {
-		logrus.Warn("libcontainerd: Unable to read last event ts: %v", err)
-		return t.Unix()
+	if err := t.UnmarshalText(b); err != nil {
+		logrus.Warnf("libcontainerd: Unable to decode last event ts: %v", err)
+		return fi.ModTime().Unix()
 	}
 
-	t.UnmarshalText(b)
-
 	return t.Unix()
 }
 
-func (r *remote) startEventsMonitor() error {
+// startEventsMonitor subscribes to the containerd events starting from the
+// last processed one. When reconnecting, the replayed events are dispatched
+// to the containers they belong to instead of being kept for a later restore.
+func (r *remote) startEventsMonitor(reconnect bool) error {
 	// First, get past events
 	er := &containerd.EventsRequest{
 		Timestamp: uint64(r.getLastEventTimestamp()),
@@ -245,50 +281,106 @@ func (r *remote) startEventsMonitor() error {
 	if err != nil {
 		return err
 	}
-	go r.handleEventStream(events)
+	go r.handleEventStream(events, reconnect)
 	return nil
 }
 
-func (r *remote) handleEventStream(events containerd.API_EventsClient) {
+// reconnectEventsMonitor tries to subscribe to the containerd events again,
+// backing off exponentially between attempts, until it succeeds or the
+// remote is cleaned up.
+func (r *remote) reconnectEventsMonitor() {
+	delay := eventsReconnectMinDelay
+	for {
+		r.RLock()
+		closed := r.closed
+		r.RUnlock()
+		if closed {
+			return
+		}
+
+		err := r.startEventsMonitor(true)
+		if err == nil {
+			logrus.Infof("libcontainerd: connection to containerd restored")
+			r.notifyConnectionChanged(ConnectionRestored)
+			return
+		}
+		if err == grpc.ErrClientConnClosing {
+			return
+		}
+		logrus.Debugf("libcontainerd: failed to reconnect to containerd, retrying in %v: %v", delay, err)
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > eventsReconnectMaxDelay {
+			delay = eventsReconnectMaxDelay
+		}
+	}
+}
+
+func (r *remote) notifyConnectionChanged(state string) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, c := range r.clients {
+		c.backend.ConnectionChanged(state)
+	}
+}
+
+func (r *remote) handleEventStream(events containerd.API_EventsClient, reconnect bool) {
 	live := false
 	for {
 		e, err := events.Recv()
 		if err != nil {
+			r.RLock()
+			closed := r.closed
+			r.RUnlock()
+			if closed {
+				return
+			}
 			logrus.Errorf("failed to receive event from containerd: %v", err)
-			go r.startEventsMonitor()
+			r.notifyConnectionChanged(ConnectionLost)
+			go r.reconnectEventsMonitor()
 			return
 		}
 
 		if live == false {
 			logrus.Debugf("received past containerd event: %#v", e)
 
-			// Pause/Resume events should never happens after exit one
-			switch e.Type {
-			case StateExit:
-				r.pastEvents[e.Id] = e
-			case StatePause:
-				r.pastEvents[e.Id] = e
-			case StateResume:
-				r.pastEvents[e.Id] = e
-			case stateLive:
+			if e.Type == stateLive {
 				live = true
 				r.updateEventTimestamp(time.Unix(int64(e.Timestamp), 0))
+				continue
 			}
-		} else {
-			logrus.Debugf("received containerd event: %#v", e)
 
-			var container *container
-			var c *client
-			r.RLock()
-			for _, c = range r.clients {
-				container, err = c.getContainer(e.Id)
-				if err == nil {
-					break
+			// Events missed while disconnected go straight to the
+			// containers that are still tracked.
+			if reconnect {
+				if container := r.getContainer(e.Id); container != nil {
+					if err := container.handleEvent(e); err != nil {
+						logrus.Errorf("error processing state change for %s: %v", e.Id, err)
+					}
+					continue
 				}
 			}
-			r.RUnlock()
+
+			// Pause/Resume events should never happens after exit one.
+			// Only the exit of the init process carries the exit status
+			// of the container, exec'd processes must not override it.
+			switch e.Type {
+			case StateExit:
+				if e.Pid == InitFriendlyName {
+					r.pastEvents[e.Id] = e
+				}
+			case StatePause, StateResume:
+				if past, ok := r.pastEvents[e.Id]; !ok || past.Type != StateExit {
+					r.pastEvents[e.Id] = e
+				}
+			}
+		} else {
+			logrus.Debugf("received containerd event: %#v", e)
+
+			container := r.getContainer(e.Id)
 			if container == nil {
-				logrus.Errorf("no state for container: %q", err)
+				logrus.Errorf("no state for container: %q", e.Id)
 				continue
 			}
 
@@ -301,6 +393,66 @@ func (r *remote) handleEventStream(events containerd.API_EventsClient) {
 	}
 }
 
+// getContainer looks up the container with the given ID in all clients.
+func (r *remote) getContainer(containerID string) *container {
+	r.RLock()
+	defer r.RUnlock()
+	for _, c := range r.clients {
+		if container, err := c.getContainer(containerID); err == nil {
+			return container
+		}
+	}
+	return nil
+}
+
+// Status reports the version of containerd, the state of the gRPC
+// connection and the number of undelivered state changes.
+func (r *remote) Status() RemoteStatus {
+	st := RemoteStatus{
+		Version: r.containerdVersion(),
+	}
+	if state, err := r.rpcConn.State(); err != nil {
+		st.ConnectionState = err.Error()
+	} else {
+		st.ConnectionState = state.String()
+	}
+	st.EventBacklog = r.eventBacklog()
+	return st
+}
+
+// eventBacklog returns the number of state changes which have not been
+// delivered to the backends yet.
+func (r *remote) eventBacklog() int {
+	r.RLock()
+	defer r.RUnlock()
+	n := 0
+	for _, c := range r.clients {
+		n += c.q.len()
+	}
+	return n
+}
+
+// containerdVersion returns the version of the containerd binary. It is
+// only known when libcontainerd manages containerd itself.
+func (r *remote) containerdVersion() string {
+	if !r.startDaemon {
+		return ""
+	}
+	r.versionOnce.Do(func() {
+		out, err := exec.Command(containerdBinary, "--version").Output()
+		if err != nil {
+			logrus.Debugf("libcontainerd: failed to get containerd version: %v", err)
+			return
+		}
+		v := strings.TrimSpace(string(out))
+		if i := strings.Index(v, "version "); i >= 0 {
+			v = v[i+len("version "):]
+		}
+		r.version = v
+	})
+	return r.version
+}
+
 func (r *remote) runContainerdDaemon() error {
 	pidFilename := filepath.Join(r.stateDir, containerdPidFilename)
 	f, err := os.OpenFile(pidFilename, os.O_RDWR|os.O_CREATE, 0600)
@@ -439,3 +591,35 @@ func (d debugLog) Apply(r Remote) error {
 	}
 	return fmt.Errorf("WithDebugLog option not supported for this remote")
 }
+
+// WithStateChangeTimeout sets how long pause and resume wait for containerd
+// to confirm the state change.
+func WithStateChangeTimeout(timeout time.Duration) RemoteOption {
+	return stateChangeTimeout(timeout)
+}
+
+type stateChangeTimeout time.Duration
+
+func (t stateChangeTimeout) Apply(r Remote) error {
+	if remote, ok := r.(*remote); ok {
+		remote.stateChangeTimeout = time.Duration(t)
+		return nil
+	}
+	return fmt.Errorf("WithStateChangeTimeout option not supported for this remote")
+}
+
+// WithLiveRestore defines if containers are kept running across daemon
+// restarts.
+func WithLiveRestore(v bool) RemoteOption {
+	return liveRestore(v)
+}
+
+type liveRestore bool
+
+func (l liveRestore) Apply(r Remote) error {
+	if remote, ok := r.(*remote); ok {
+		remote.liveRestore = bool(l)
+		return nil
+	}
+	return fmt.Errorf("WithLiveRestore option not supported for this remote")
+}
@@ -20,6 +20,11 @@ func (r *remote) Client(b Backend) (Client, error) {
 func (r *remote) Cleanup() {
 }
 
+// Status is a no-op on Windows as there is no remote containerd.
+func (r *remote) Status() RemoteStatus {
+	return RemoteStatus{}
+}
+
 // New creates a fresh instance of libcontainerd remote. On Windows,
 // this is not used as there is no remote containerd process.
 func New(_ string, _ ...RemoteOption) (Remote, error) {
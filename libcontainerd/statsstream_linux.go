@@ -0,0 +1,143 @@
+package libcontainerd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	containerd "github.com/docker/containerd/api/grpc/types"
+	"golang.org/x/net/context"
+)
+
+// statsStreamInterval is the interval at which a stats stream samples
+// containerd for a container.
+const statsStreamInterval = time.Second
+
+// statsStream samples the stats of a single container and fans them out
+// to all subscribers, so containerd is queried once per interval no matter
+// how many consumers there are.
+type statsStream struct {
+	sync.Mutex
+	containerID string
+	subscribers map[chan *Stats]struct{}
+	stop        chan struct{}
+}
+
+// StatsStream returns a channel receiving stats samples for the container
+// until either the returned cancel function is called, the context is done
+// or the container goes away.
+func (clnt *client) StatsStream(ctx context.Context, containerID string) (<-chan *Stats, func(), error) {
+	clnt.lock(containerID)
+	defer clnt.unlock(containerID)
+	if _, err := clnt.getContainer(containerID); err != nil {
+		return nil, nil, err
+	}
+
+	clnt.mapMutex.Lock()
+	s, ok := clnt.statsStreams[containerID]
+	if !ok {
+		s = &statsStream{
+			containerID: containerID,
+			subscribers: make(map[chan *Stats]struct{}),
+			stop:        make(chan struct{}),
+		}
+		clnt.statsStreams[containerID] = s
+		go clnt.sampleStats(s)
+	}
+	ch := make(chan *Stats, 1)
+	s.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.Unlock()
+	clnt.mapMutex.Unlock()
+
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(done)
+			clnt.evictStatsSubscriber(s, ch)
+		})
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+	return ch, cancel, nil
+}
+
+// evictStatsSubscriber removes a subscriber from the stream and stops the
+// sampling loop once the last subscriber is gone.
+func (clnt *client) evictStatsSubscriber(s *statsStream, ch chan *Stats) {
+	clnt.mapMutex.Lock()
+	defer clnt.mapMutex.Unlock()
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.subscribers[ch]; !ok {
+		return
+	}
+	delete(s.subscribers, ch)
+	close(ch)
+	if len(s.subscribers) == 0 {
+		s.closeLocked(clnt)
+	}
+}
+
+// closeLocked stops the sampling loop and closes all subscriber channels.
+// Both the client map mutex and the stream lock must be held.
+func (s *statsStream) closeLocked(clnt *client) {
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+	close(s.stop)
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+	if clnt.statsStreams[s.containerID] == s {
+		delete(clnt.statsStreams, s.containerID)
+	}
+}
+
+func (s *statsStream) publish(st *Stats) {
+	s.Lock()
+	defer s.Unlock()
+	for ch := range s.subscribers {
+		// Never block on slow subscribers, they just miss this sample.
+		select {
+		case ch <- st:
+		default:
+		}
+	}
+}
+
+func (clnt *client) sampleStats(s *statsStream) {
+	ticker := time.NewTicker(statsStreamInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		resp, err := clnt.remote.apiClient.Stats(context.Background(), &containerd.StatsRequest{Id: s.containerID})
+		if err != nil {
+			if _, err := clnt.getContainer(s.containerID); err != nil {
+				clnt.mapMutex.Lock()
+				s.Lock()
+				s.closeLocked(clnt)
+				s.Unlock()
+				clnt.mapMutex.Unlock()
+				return
+			}
+			logrus.Debugf("libcontainerd: failed to sample stats for %s: %v", s.containerID, err)
+			continue
+		}
+		s.publish((*Stats)(resp))
+	}
+}
@@ -1,6 +1,11 @@
 package libcontainerd
 
-import "io"
+import (
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+)
 
 // State constants used in state change reporting.
 const (
@@ -16,6 +21,12 @@ const (
 	stateLive         = "live"
 )
 
+// Connection state constants used in containerd connection change reporting.
+const (
+	ConnectionLost     = "containerd-disconnect"
+	ConnectionRestored = "containerd-reconnect"
+)
+
 // CommonStateInfo contains the state info common to all platforms.
 type CommonStateInfo struct { // FIXME: event?
 	State     string
@@ -28,21 +39,32 @@ type CommonStateInfo struct { // FIXME: event?
 type Backend interface {
 	StateChanged(containerID string, state StateInfo) error
 	AttachStreams(processFriendlyName string, io IOPipe) error
+	// ConnectionChanged is called when the connection to containerd is
+	// lost or restored. It is never called on Windows.
+	ConnectionChanged(state string)
 }
 
-// Client provides access to containerd features.
+// Client provides access to containerd features. Every method takes a
+// context that is passed down to containerd so that callers can cancel
+// or put a deadline on operations that would otherwise block.
 type Client interface {
-	Create(containerID string, spec Spec, options ...CreateOption) error
-	Signal(containerID string, sig int) error
-	AddProcess(containerID, processFriendlyName string, process Process) error
-	Resize(containerID, processFriendlyName string, width, height int) error
-	Pause(containerID string) error
-	Resume(containerID string) error
-	Restore(containerID string, options ...CreateOption) error
-	Stats(containerID string) (*Stats, error)
-	GetPidsForContainer(containerID string) ([]int, error)
-	Summary(containerID string) ([]Summary, error)
-	UpdateResources(containerID string, resources Resources) error
+	Create(ctx context.Context, containerID string, spec Spec, options ...CreateOption) error
+	Signal(ctx context.Context, containerID string, sig int) error
+	SignalAll(ctx context.Context, containerIDs []string, sig int) map[string]error
+	AddProcess(ctx context.Context, containerID, processFriendlyName string, process Process) error
+	Resize(ctx context.Context, containerID, processFriendlyName string, width, height int) error
+	Pause(ctx context.Context, containerID string) error
+	Resume(ctx context.Context, containerID string) error
+	Restore(ctx context.Context, containerID string, options ...CreateOption) error
+	Stats(ctx context.Context, containerID string) (*Stats, error)
+	StatsStream(ctx context.Context, containerID string) (<-chan *Stats, func(), error)
+	GetPidsForContainer(ctx context.Context, containerID string) ([]int, error)
+	Summary(ctx context.Context, containerID string) ([]Summary, error)
+	UpdateResources(ctx context.Context, containerID string, resources Resources) error
+	Checkpoint(ctx context.Context, containerID, checkpointID string, opts CheckpointOpts) error
+	DeleteCheckpoint(ctx context.Context, containerID, checkpointID string) error
+	ListCheckpoints(ctx context.Context, containerID string) ([]Checkpoint, error)
+	Drain(ctx context.Context, timeout time.Duration, terminate bool) error
 }
 
 // CreateOption allows to configure parameters of container creation.
@@ -57,3 +79,15 @@ type IOPipe struct {
 	Stderr   io.Reader
 	Terminal bool // Whether stderr is connected on Windows
 }
+
+// CheckpointOpts contains the options used when checkpointing a container.
+type CheckpointOpts struct {
+	// Exit stops the container once the checkpoint has been taken.
+	Exit bool
+	// TCP allows established TCP connections to be checkpointed.
+	TCP bool
+	// UnixSockets allows external unix sockets to be checkpointed.
+	UnixSockets bool
+	// Shell allows shell jobs to be checkpointed.
+	Shell bool
+}
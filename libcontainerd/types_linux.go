@@ -31,6 +31,8 @@ type Process struct {
 	ApparmorProfile *string `json:"apparmorProfile,omitempty"`
 	// SelinuxProcessLabel specifies the selinux context that the container process is run as.
 	SelinuxLabel *string `json:"selinuxLabel,omitempty"`
+	// NoNewPrivileges controls whether the process can gain additional privileges.
+	NoNewPrivileges *bool `json:"noNewPrivileges,omitempty"`
 }
 
 // StateInfo contains description about the new state container has entered.
@@ -44,8 +46,13 @@ type StateInfo struct {
 // Stats contains a stats properties from containerd.
 type Stats containerd.StatsResponse
 
-// Summary container a container summary from containerd
-type Summary struct{}
+// Summary contains the details of a process managed by containerd inside
+// a container.
+type Summary struct {
+	Pid       uint32
+	ProcessID string
+	Command   string
+}
 
 // User specifies linux specific user and group information for the container's
 // main process.
@@ -53,3 +60,6 @@ type User specs.User
 
 // Resources defines updatable container resource values.
 type Resources containerd.UpdateResource
+
+// Checkpoint contains the details of a container checkpoint.
+type Checkpoint containerd.Checkpoint
@@ -11,10 +11,11 @@ type Process windowsoci.Process
 // User specifies user information for the containers main process.
 type User windowsoci.User
 
-// Summary container a container summary from containerd
+// Summary contains the details of a process running inside a container.
 type Summary struct {
-	Pid     uint32
-	Command string
+	Pid       uint32
+	ProcessID string
+	Command   string
 }
 
 // StateInfo contains description about the new state container has entered.
@@ -30,3 +31,6 @@ type Stats struct{}
 
 // Resources defines updatable container resource values.
 type Resources struct{}
+
+// Checkpoint contains the details of a container checkpoint.
+type Checkpoint struct{}
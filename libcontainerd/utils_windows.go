@@ -1,6 +1,11 @@
 package libcontainerd
 
-import "strings"
+import (
+	"strings"
+	"syscall"
+
+	"github.com/Microsoft/hcsshim"
+)
 
 // setupEnvironmentVariables convert a string array of environment variables
 // into a map as required by the HCS. Source array is in format [v1=k1] [v2=k2] etc.
@@ -14,3 +19,15 @@ func setupEnvironmentVariables(a []string) map[string]string {
 	}
 	return r
 }
+
+// isAlreadyStoppedErr returns true if an error from HCS only indicates that
+// the compute system is already shutting down or gone.
+func isAlreadyStoppedErr(err error) bool {
+	herr, ok := err.(*hcsshim.HcsError)
+	if !ok {
+		return false
+	}
+	return herr.Err == hcsshim.ERROR_SHUTDOWN_IN_PROGRESS ||
+		herr.Err == ErrorBadPathname ||
+		herr.Err == syscall.ERROR_PATH_NOT_FOUND
+}
@@ -0,0 +1,28 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+)
+
+func TestNamedRuntimeOpt(t *testing.T) {
+	runtimes := make(map[string]types.Runtime)
+	o := NewNamedRuntimeOpt("runtimes", &runtimes, "runc")
+
+	if err := o.Set("custom=/usr/local/bin/custom-runc"); err != nil {
+		t.Fatal(err)
+	}
+	if o.Name() != "runtimes" {
+		t.Errorf("%s != runtimes", o.Name())
+	}
+	if rt, exist := runtimes["custom"]; !exist || rt.Path != "/usr/local/bin/custom-runc" {
+		t.Errorf("expected custom to be in the values, got %v", runtimes)
+	}
+
+	for _, invalid := range []string{"custom=/bin/other", "runc=/bin/runc", "noequal", "=/bin/runc", "name="} {
+		if err := o.Set(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -136,6 +136,39 @@ func TestDecompressStreamXz(t *testing.T) {
 	}
 }
 
+func TestCompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	var compressed bytes.Buffer
+	w, err := CompressStream(&compressed, Zstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if compression := DetectCompression(compressed.Bytes()); compression != Zstd {
+		t.Fatalf("expected zstd compression to be detected, got %s", compression.Extension())
+	}
+
+	r, err := DecompressStream(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	decompressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != "hello" {
+		t.Fatalf("expected hello after decompression, got %q", decompressed)
+	}
+}
+
 func TestCompressStreamXzUnsuported(t *testing.T) {
 	dest, err := os.Create(tmp + "dest")
 	if err != nil {
@@ -205,6 +238,13 @@ func TestExtensionXz(t *testing.T) {
 		t.Fatalf("The extension of a bzip2 archive should be 'tar.xz'")
 	}
 }
+func TestExtensionZstd(t *testing.T) {
+	compression := Zstd
+	output := compression.Extension()
+	if output != "tar.zst" {
+		t.Fatalf("The extension of a zstd archive should be 'tar.zst'")
+	}
+}
 
 func TestCmdStreamLargeStderr(t *testing.T) {
 	cmd := exec.Command("sh", "-c", "dd if=/dev/zero bs=1k count=1000 of=/dev/stderr; echo hello")
@@ -772,6 +812,45 @@ func TestTarWithOptions(t *testing.T) {
 	}
 }
 
+func TestTarWithOptionsExceptions(t *testing.T) {
+	origin, err := ioutil.TempDir("", "docker-test-tar-exceptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(origin)
+	for _, name := range []string{"docs/README", "docs/other", "src/main.go"} {
+		path := filepath.Join(origin, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rdr, err := TarWithOptions(origin, &TarOptions{ExcludePatterns: []string{"*", "!*/README"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+
+	var names []string
+	tr := tar.NewReader(rdr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 1 || names[0] != "docs/README" {
+		t.Fatalf("expected only docs/README in the archive, got %v", names)
+	}
+}
+
 // Some tar archives such as http://haproxy.1wt.eu/download/1.5/src/devel/haproxy-1.5-dev21.tar.gz
 // use PAX Global Extended Headers.
 // Failing prevents the archives from being uncompressed during ADD
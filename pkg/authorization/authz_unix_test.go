@@ -138,6 +138,29 @@ func TestResponseModifier(t *testing.T) {
 	}
 }
 
+func TestResponseModifierFlushHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := NewResponseModifier(w)
+		m.Header().Set("h1", "v1")
+		m.WriteHeader(201)
+		m.Write([]byte("body"))
+		m.FlushAll()
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("h1") != "v1" {
+		t.Fatalf("Header value must be sent to the client %q", resp.Header.Get("h1"))
+	}
+	if resp.StatusCode != 201 {
+		t.Fatalf("Status code must be correct %d", resp.StatusCode)
+	}
+}
+
 func TestDrainBody(t *testing.T) {
 
 	tests := []struct {
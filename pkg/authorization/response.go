@@ -174,18 +174,19 @@ func (rm *responseModifier) Flush() {
 
 // FlushAll flushes all data to the HTTP response
 func (rm *responseModifier) FlushAll() error {
-	// Copy the status code
-	if rm.statusCode > 0 {
-		rm.rw.WriteHeader(rm.statusCode)
-	}
-
-	// Copy the header
+	// Copy the header, it must be done before the status code is written
+	// as later changes to the header are ignored by the response writer
 	for k, vv := range rm.header {
 		for _, v := range vv {
 			rm.rw.Header().Add(k, v)
 		}
 	}
 
+	// Copy the status code
+	if rm.statusCode > 0 {
+		rm.rw.WriteHeader(rm.statusCode)
+	}
+
 	var err error
 	if len(rm.body) > 0 {
 		// Write body
@@ -0,0 +1,121 @@
+// Package bspatch applies the binary diffs made by bsdiff, in the BSDIFF40
+// format, to files too large to be held in memory.
+package bspatch
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const headerSize = 32
+
+var magic = []byte("BSDIFF40")
+
+// ErrCorruptPatch is returned for a patch that is not in the BSDIFF40 format
+// or does not apply to the old file.
+var ErrCorruptPatch = errors.New("corrupt bsdiff patch")
+
+// Patch writes to new the file made by applying patch to old. old is read
+// at random offsets, and the patch in three sequential sections, so neither
+// of them is held in memory.
+func Patch(old io.ReaderAt, oldSize int64, patch io.ReaderAt, patchSize int64, new io.Writer) error {
+	header := make([]byte, headerSize)
+	if _, err := patch.ReadAt(header, 0); err != nil {
+		return ErrCorruptPatch
+	}
+	if !bytes.Equal(header[:8], magic) {
+		return ErrCorruptPatch
+	}
+	ctrlLen := offtin(header[8:16])
+	diffLen := offtin(header[16:24])
+	newSize := offtin(header[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || headerSize+ctrlLen+diffLen > patchSize {
+		return ErrCorruptPatch
+	}
+
+	ctrl := bzip2.NewReader(io.NewSectionReader(patch, headerSize, ctrlLen))
+	diff := bzip2.NewReader(io.NewSectionReader(patch, headerSize+ctrlLen, diffLen))
+	extra := bzip2.NewReader(io.NewSectionReader(patch, headerSize+ctrlLen+diffLen, patchSize-headerSize-ctrlLen-diffLen))
+
+	var (
+		newPos, oldPos int64
+		ctrlBuf        = make([]byte, 24)
+		buf            = make([]byte, 32*1024)
+		oldBuf         = make([]byte, 32*1024)
+	)
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, ctrlBuf); err != nil {
+			return fmt.Errorf("reading bsdiff control block: %v", err)
+		}
+		diffSize, extraSize, seek := offtin(ctrlBuf[0:8]), offtin(ctrlBuf[8:16]), offtin(ctrlBuf[16:24])
+		if diffSize < 0 || extraSize < 0 || newPos+diffSize+extraSize > newSize {
+			return ErrCorruptPatch
+		}
+
+		// the diff bytes are added to the bytes of old at the same
+		// offset, the bytes out of old are taken as is
+		for diffSize > 0 {
+			n := int64(len(buf))
+			if n > diffSize {
+				n = diffSize
+			}
+			if _, err := io.ReadFull(diff, buf[:n]); err != nil {
+				return fmt.Errorf("reading bsdiff diff block: %v", err)
+			}
+			if err := readOld(old, oldSize, oldPos, oldBuf[:n]); err != nil {
+				return err
+			}
+			for i := int64(0); i < n; i++ {
+				buf[i] += oldBuf[i]
+			}
+			if _, err := new.Write(buf[:n]); err != nil {
+				return err
+			}
+			diffSize -= n
+			oldPos += n
+			newPos += n
+		}
+
+		if _, err := io.CopyN(new, extra, extraSize); err != nil {
+			return fmt.Errorf("reading bsdiff extra block: %v", err)
+		}
+		newPos += extraSize
+		oldPos += seek
+	}
+	return nil
+}
+
+// readOld fills buf with the bytes of old from offset, and with zeros where
+// it falls out of old.
+func readOld(old io.ReaderAt, oldSize, offset int64, buf []byte) error {
+	for i := range buf {
+		buf[i] = 0
+	}
+	start, end := offset, offset+int64(len(buf))
+	if start < 0 {
+		start = 0
+	}
+	if end > oldSize {
+		end = oldSize
+	}
+	if start >= end {
+		return nil
+	}
+	_, err := old.ReadAt(buf[start-offset:end-offset], start)
+	return err
+}
+
+// offtin decodes the sign and magnitude little endian integers of bsdiff.
+func offtin(b []byte) int64 {
+	y := int64(b[7] & 0x7f)
+	for i := 6; i >= 0; i-- {
+		y = y<<8 | int64(b[i])
+	}
+	if b[7]&0x80 != 0 {
+		y = -y
+	}
+	return y
+}
@@ -0,0 +1,47 @@
+package bspatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	old, err := ioutil.ReadFile("testdata/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("testdata/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := ioutil.ReadFile("testdata/patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var new bytes.Buffer
+	if err := Patch(bytes.NewReader(old), int64(len(old)), bytes.NewReader(patch), int64(len(patch)), &new); err != nil {
+		t.Fatalf("error applying patch: %v", err)
+	}
+	if !bytes.Equal(new.Bytes(), expected) {
+		t.Fatalf("unexpected patched file:\n%s", new.Bytes())
+	}
+}
+
+func TestPatchCorrupt(t *testing.T) {
+	patch, err := ioutil.ReadFile("testdata/patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][]byte{
+		[]byte("BSDIFF40"),
+		append([]byte("BSDIFF41"), patch[8:]...),
+		patch[:len(patch)-50],
+	} {
+		var new bytes.Buffer
+		if err := Patch(bytes.NewReader(nil), 0, bytes.NewReader(p), int64(len(p)), &new); err == nil {
+			t.Fatalf("expected an error applying a corrupt patch of %d bytes", len(p))
+		}
+	}
+}
@@ -68,12 +68,12 @@ func Matches(file string, patterns []string) (bool, error) {
 		return false, nil
 	}
 
-	patterns, patDirs, _, err := CleanPatterns(patterns)
+	pm, err := NewPatternMatcher(patterns)
 	if err != nil {
 		return false, err
 	}
 
-	return OptimizedMatches(file, patterns, patDirs)
+	return pm.Matches(file), nil
 }
 
 // OptimizedMatches is basically the same as fileutils.Matches() but optimized for archive.go.
@@ -120,6 +120,115 @@ func OptimizedMatches(file string, patterns []string, patDirs [][]string) (bool,
 	return matched, nil
 }
 
+// PatternMatcher matches paths against a list of patterns, in the format of
+// the .dockerignore file. The patterns are cleaned and compiled once, so that
+// it can be used on every file of a large directory tree.
+type PatternMatcher struct {
+	patterns   []*pattern
+	exclusions bool
+}
+
+// pattern is a compiled pattern of a PatternMatcher.
+type pattern struct {
+	regexp    *regexp.Regexp
+	dirs      []string
+	exclusion bool
+}
+
+// NewPatternMatcher cleans and compiles patterns, and returns a
+// PatternMatcher for them.
+func NewPatternMatcher(patterns []string) (*PatternMatcher, error) {
+	patterns, patDirs, exclusions, err := CleanPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	pm := &PatternMatcher{exclusions: exclusions}
+	for i, p := range patterns {
+		negative := exclusion(p)
+		if negative {
+			p = p[1:]
+		}
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("Error in pattern (%s): %s", p, err)
+		}
+		pm.patterns = append(pm.patterns, &pattern{regexp: re, dirs: patDirs[i], exclusion: negative})
+	}
+	return pm, nil
+}
+
+// Exclusions returns whether any of the patterns is an exception pattern,
+// prefixed with !.
+func (pm *PatternMatcher) Exclusions() bool {
+	return pm.exclusions
+}
+
+// Matches returns true if file matches any of the patterns and isn't
+// excluded by any of the subsequent patterns. It behaves like
+// OptimizedMatches.
+func (pm *PatternMatcher) Matches(file string) bool {
+	matched := false
+	file = filepath.FromSlash(file)
+	parentPath := filepath.Dir(file)
+	parentPathDirs := strings.Split(parentPath, string(os.PathSeparator))
+
+	for _, p := range pm.patterns {
+		match := p.regexp.MatchString(file)
+
+		if !match && parentPath != "." {
+			// Check to see if the pattern matches one of our parent dirs.
+			if len(p.dirs) <= len(parentPathDirs) {
+				match = p.regexp.MatchString(strings.Join(parentPathDirs[:len(p.dirs)], string(os.PathSeparator)))
+			}
+		}
+
+		if match {
+			matched = !p.exclusion
+		}
+	}
+
+	if matched {
+		logrus.Debugf("Skipping excluded path: %s", file)
+	}
+
+	return matched
+}
+
+// CanSkipDir returns whether the content of dir, a directory that Matches,
+// can be skipped altogether, because none of the exception patterns could
+// match a path in it. This lets a directory walk prune excluded directories
+// without looking at their content.
+func (pm *PatternMatcher) CanSkipDir(dir string) bool {
+	if !pm.exclusions {
+		return true
+	}
+	dirs := strings.Split(filepath.FromSlash(dir), string(os.PathSeparator))
+
+	for _, p := range pm.patterns {
+		if !p.exclusion || len(p.dirs) <= len(dirs) && !strings.Contains(strings.Join(p.dirs, ""), "**") {
+			// a pattern with no more elements than dir matches the
+			// paths in dir through dir or its parents, so it matches
+			// them like it matches dir
+			continue
+		}
+		prefix := true
+		for i := 0; i < len(dirs) && i < len(p.dirs); i++ {
+			if strings.Contains(p.dirs[i], "**") {
+				break
+			}
+			if match, err := filepath.Match(p.dirs[i], dirs[i]); err == nil && !match {
+				prefix = false
+				break
+			}
+		}
+		if prefix {
+			return false
+		}
+	}
+	return true
+}
+
 // regexpMatch tries to match the logic of filepath.Match but
 // does so using regexp logic. We do this so that we can expand the
 // wildcard set to include other things, like "**" to mean any number
@@ -130,6 +239,16 @@ func OptimizedMatches(file string, patterns []string, patDirs [][]string) (bool,
 // As per the comment in golangs filepath.Match, on Windows, escaping
 // is disabled. Instead, '\\' is treated as path separator.
 func regexpMatch(pattern, path string) (bool, error) {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(path), nil
+}
+
+// compilePattern converts pattern to the regular expression used by
+// regexpMatch.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
 	regStr := "^"
 
 	// Do some syntax checking on the pattern.
@@ -138,8 +257,8 @@ func regexpMatch(pattern, path string) (bool, error) {
 	// error state and if there is an error in the pattern return it.
 	// If this becomes an issue we can remove this since its really only
 	// needed in the error (syntax) case - which isn't really critical.
-	if _, err := filepath.Match(pattern, path); err != nil {
-		return false, err
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
 	}
 
 	// Go through the pattern and convert it to a regexp.
@@ -206,14 +325,12 @@ func regexpMatch(pattern, path string) (bool, error) {
 
 	regStr += "$"
 
-	res, err := regexp.MatchString(regStr, path)
-
-	// Map regexp's error to filepath's so no one knows we're not using filepath
+	re, err := regexp.Compile(regStr)
 	if err != nil {
-		err = filepath.ErrBadPattern
+		// Map regexp's error to filepath's so no one knows we're not using filepath
+		return nil, filepath.ErrBadPattern
 	}
-
-	return res, err
+	return re, nil
 }
 
 // CopyFile copies from src to dst until either EOF is reached
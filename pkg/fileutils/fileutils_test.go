@@ -449,6 +449,47 @@ func TestCleanPatternsFolderSplit(t *testing.T) {
 	}
 }
 
+func TestPatternMatcherCanSkipDir(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		dir      string
+		skip     bool
+	}{
+		{[]string{"node_modules"}, "node_modules", true},
+		{[]string{"node_modules", "!node_modules/keep"}, "node_modules", false},
+		{[]string{"node_modules", "!src/keep"}, "node_modules", true},
+		{[]string{"*", "!*/README.md"}, "docs", false},
+		{[]string{"*", "!*/README.md"}, "docs/api", true},
+		{[]string{"*", "!docs/*/README.md"}, "docs/api", false},
+		{[]string{"*", "!docs/*/README.md"}, "src/api", true},
+		{[]string{"*", "!**/README.md"}, "docs/api", false},
+		{[]string{"docs", "!docs/*.md"}, "docs", false},
+		{[]string{"docs", "!docs/*.md"}, "docs/api", true},
+	}
+
+	for _, test := range tests {
+		pm, err := NewPatternMatcher(test.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pm.Matches(test.dir) {
+			t.Fatalf("expected %s to match %v", test.dir, test.patterns)
+		}
+		if skip := pm.CanSkipDir(test.dir); skip != test.skip {
+			t.Fatalf("expected CanSkipDir(%s) to be %v with %v, got %v", test.dir, test.skip, test.patterns, skip)
+		}
+	}
+}
+
+func TestNewPatternMatcherMalformedPattern(t *testing.T) {
+	if _, err := NewPatternMatcher([]string{"["}); err == nil {
+		t.Fatal("expected an error for a malformed pattern")
+	}
+	if _, err := NewPatternMatcher([]string{"!"}); err == nil {
+		t.Fatal("expected an error for a single exclamation mark")
+	}
+}
+
 func TestCreateIfNotExistsDir(t *testing.T) {
 	tempFolder, err := ioutil.TempDir("", "docker-fileutils-test")
 	if err != nil {
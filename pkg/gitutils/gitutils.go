@@ -1,6 +1,7 @@
 package gitutils
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -16,50 +17,110 @@ import (
 
 // Clone clones a repository into a newly created directory which
 // will be under "docker-build-git"
+//
+// The fragment of remoteURL selects the ref to check out and the directory
+// of the repository to use as the context, as in "#branch:subdir". The
+// history is only fetched as deep as needed when the server supports it, and
+// the submodules are checked out as well. The returned directory is the one
+// of the context, removing it removes the whole clone.
 func Clone(remoteURL string) (string, error) {
 	if !urlutil.IsGitTransport(remoteURL) {
 		remoteURL = "https://" + remoteURL
 	}
+	repo, fragment := remoteURL, ""
+	if i := strings.Index(remoteURL, "#"); i >= 0 {
+		repo, fragment = remoteURL[:i], remoteURL[i+1:]
+	}
+
 	root, err := ioutil.TempDir("", "docker-build-git")
 	if err != nil {
 		return "", err
 	}
 
-	u, err := url.Parse(remoteURL)
+	contextDir, err := cloneAndCheckout(repo, fragment, root)
+	if err != nil {
+		os.RemoveAll(root)
+		return "", redactOutput(err, repo)
+	}
+	if contextDir == root {
+		return root, nil
+	}
+
+	// the context is a subdirectory of the repository: move it out of the
+	// clone and remove the rest
+	newRoot, err := ioutil.TempDir("", "docker-build-git")
+	if err == nil {
+		err = os.Remove(newRoot)
+	}
+	if err == nil {
+		err = os.Rename(contextDir, newRoot)
+	}
+	os.RemoveAll(root)
 	if err != nil {
 		return "", err
 	}
+	return newRoot, nil
+}
 
-	fragment := u.Fragment
-	clone := cloneArgs(u, root)
+// cloneAndCheckout clones repo in root and checks out the ref of fragment.
+// A ref is fetched on its own with a shallow fetch, unless the server does
+// not support it, in which case the whole repository is cloned.
+func cloneAndCheckout(repo, fragment, root string) (string, error) {
+	ref := strings.SplitN(fragment, ":", 2)[0]
+	if ref != "" && supportsShallowClone(repo) {
+		if _, err := git("init", root); err != nil {
+			return "", err
+		}
+		if _, err := gitWithinDir(root, "remote", "add", "origin", repo); err != nil {
+			return "", err
+		}
+		if _, err := gitWithinDir(root, "fetch", "--depth", "1", "origin", ref); err == nil {
+			// the ref is checked out from FETCH_HEAD, there is no need
+			// for checkoutGit to do it again
+			if output, err := gitWithinDir(root, "checkout", "FETCH_HEAD"); err != nil {
+				return "", fmt.Errorf("Error trying to use git: %s (%s)", err, output)
+			}
+			if err := updateSubmodules(root); err != nil {
+				return "", err
+			}
+			return checkoutGit(strings.TrimPrefix(fragment, ref), root)
+		}
+		// not every server can fetch a commit by its ID, fall back to a
+		// full clone
+		if err := os.RemoveAll(filepath.Join(root, ".git")); err != nil {
+			return "", err
+		}
+	}
 
-	if output, err := git(clone...); err != nil {
+	if output, err := git(cloneArgs(repo, ref == "", root)...); err != nil {
 		return "", fmt.Errorf("Error trying to use git: %s (%s)", err, output)
 	}
 
 	return checkoutGit(fragment, root)
 }
 
-func cloneArgs(remoteURL *url.URL, root string) []string {
-	args := []string{"clone", "--recursive"}
-	shallow := len(remoteURL.Fragment) == 0
-
-	if shallow && strings.HasPrefix(remoteURL.Scheme, "http") {
-		res, err := http.Head(fmt.Sprintf("%s/info/refs?service=git-upload-pack", remoteURL))
-		if err != nil || res.Header.Get("Content-Type") != "application/x-git-upload-pack-advertisement" {
-			shallow = false
-		}
+// supportsShallowClone returns whether the server of repo supports shallow
+// fetches, which the dumb HTTP protocol does not.
+func supportsShallowClone(repo string) bool {
+	if !strings.HasPrefix(repo, "http") {
+		return true
 	}
-
-	if shallow {
-		args = append(args, "--depth", "1")
+	res, err := http.Head(fmt.Sprintf("%s/info/refs?service=git-upload-pack", repo))
+	if err != nil {
+		return false
 	}
+	res.Body.Close()
+	return res.Header.Get("Content-Type") == "application/x-git-upload-pack-advertisement"
+}
+
+func cloneArgs(repo string, shallow bool, root string) []string {
+	args := []string{"clone", "--recursive"}
 
-	if remoteURL.Fragment != "" {
-		remoteURL.Fragment = ""
+	if shallow && supportsShallowClone(repo) {
+		args = append(args, "--depth", "1")
 	}
 
-	return append(args, remoteURL.String(), root)
+	return append(args, repo, root)
 }
 
 func checkoutGit(fragment, root string) (string, error) {
@@ -69,6 +130,9 @@ func checkoutGit(fragment, root string) (string, error) {
 		if output, err := gitWithinDir(root, "checkout", refAndDir[0]); err != nil {
 			return "", fmt.Errorf("Error trying to use git: %s (%s)", err, output)
 		}
+		if err := updateSubmodules(root); err != nil {
+			return "", err
+		}
 	}
 
 	if len(refAndDir) > 1 && len(refAndDir[1]) != 0 {
@@ -90,6 +154,34 @@ func checkoutGit(fragment, root string) (string, error) {
 	return root, nil
 }
 
+// updateSubmodules checks out the submodules of the commit checked out in
+// root, which clone --recursive only did for the default branch.
+func updateSubmodules(root string) error {
+	// submodules are looked up relative to the work tree, git has to run
+	// from within it
+	cmd := exec.Command("git", "submodule", "update", "--init", "--recursive")
+	cmd.Dir = root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Error trying to use git: %s (%s)", err, output)
+	}
+	return nil
+}
+
+// redactOutput returns err with the password of repo, if any, redacted, as
+// the output of git can include it.
+func redactOutput(err error, repo string) error {
+	u, parseErr := url.Parse(repo)
+	if parseErr != nil || u.User == nil {
+		return err
+	}
+	password, hasPassword := u.User.Password()
+	if !hasPassword || password == "" {
+		return err
+	}
+	msg := strings.Replace(err.Error(), repo, urlutil.Redact(repo), -1)
+	return errors.New(strings.Replace(msg, password, "xxxxx", -1))
+}
+
 func gitWithinDir(dir string, args ...string) ([]byte, error) {
 	a := []string{"--work-tree", dir, "--git-dir", filepath.Join(dir, ".git")}
 	return git(append(a, args...)...)
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -26,7 +27,7 @@ func TestCloneArgsSmartHttp(t *testing.T) {
 		w.Header().Set("Content-Type", fmt.Sprintf("application/x-%s-advertisement", q))
 	})
 
-	args := cloneArgs(serverURL, "/tmp")
+	args := cloneArgs(gitURL, true, "/tmp")
 	exp := []string{"clone", "--recursive", "--depth", "1", gitURL, "/tmp"}
 	if !reflect.DeepEqual(args, exp) {
 		t.Fatalf("Expected %v, got %v", exp, args)
@@ -45,7 +46,7 @@ func TestCloneArgsDumbHttp(t *testing.T) {
 		w.Header().Set("Content-Type", "text/plain")
 	})
 
-	args := cloneArgs(serverURL, "/tmp")
+	args := cloneArgs(gitURL, true, "/tmp")
 	exp := []string{"clone", "--recursive", gitURL, "/tmp"}
 	if !reflect.DeepEqual(args, exp) {
 		t.Fatalf("Expected %v, got %v", exp, args)
@@ -53,17 +54,15 @@ func TestCloneArgsDumbHttp(t *testing.T) {
 }
 
 func TestCloneArgsGit(t *testing.T) {
-	u, _ := url.Parse("git://github.com/docker/docker")
-	args := cloneArgs(u, "/tmp")
+	args := cloneArgs("git://github.com/docker/docker", true, "/tmp")
 	exp := []string{"clone", "--recursive", "--depth", "1", "git://github.com/docker/docker", "/tmp"}
 	if !reflect.DeepEqual(args, exp) {
 		t.Fatalf("Expected %v, got %v", exp, args)
 	}
 }
 
-func TestCloneArgsStripFragment(t *testing.T) {
-	u, _ := url.Parse("git://github.com/docker/docker#test")
-	args := cloneArgs(u, "/tmp")
+func TestCloneArgsNotShallow(t *testing.T) {
+	args := cloneArgs("git://github.com/docker/docker", false, "/tmp")
 	exp := []string{"clone", "--recursive", "git://github.com/docker/docker", "/tmp"}
 	if !reflect.DeepEqual(args, exp) {
 		t.Fatalf("Expected %v, got %v", exp, args)
@@ -202,3 +201,67 @@ func TestCheckoutGit(t *testing.T) {
 		}
 	}
 }
+
+func TestCloneAndCheckoutRef(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-build-git-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	gitDir := filepath.Join(root, "repo")
+	if _, err = git("init", gitDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"config", "user.email", "test@docker.com"}, {"config", "user.name", "Docker test"}} {
+		if _, err = gitWithinDir(gitDir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subDir := filepath.Join(gitDir, "subdir")
+	if err = os.Mkdir(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"FROM scratch", "FROM busybox"} {
+		if err = ioutil.WriteFile(filepath.Join(subDir, "Dockerfile"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = gitWithinDir(gitDir, "add", "-A"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = gitWithinDir(gitDir, "commit", "-m", content); err != nil {
+			t.Fatal(err)
+		}
+		if content == "FROM scratch" {
+			if _, err = gitWithinDir(gitDir, "tag", "v1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for fragment, expected := range map[string]string{"v1:subdir": "FROM scratch", ":subdir": "FROM busybox"} {
+		cloneDir := filepath.Join(root, "clone-"+strings.Replace(fragment, ":", "-", -1))
+		if err = os.Mkdir(cloneDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		contextDir, err := cloneAndCheckout(gitDir, fragment, cloneDir)
+		if err != nil {
+			t.Fatalf("fragment %q: %v", fragment, err)
+		}
+		if contextDir != filepath.Join(cloneDir, "subdir") {
+			t.Fatalf("fragment %q: unexpected context directory %s", fragment, contextDir)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(contextDir, "Dockerfile"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Fatalf("fragment %q: expected %q, got %q", fragment, expected, string(b))
+		}
+	}
+
+	if _, err := cloneAndCheckout(gitDir, "nosuchref", filepath.Join(root, "clone-missing")); err == nil {
+		t.Fatal("expected cloning a missing ref to fail")
+	}
+}
@@ -10,6 +10,9 @@ If a lock with a given name does not exist when `Lock` is called, one is
 created.
 Lock references are automatically cleaned up on `Unlock` if nothing else is
 waiting for the lock.
+
+`TryLock` behaves like `Lock`, but gives up and returns false if the lock could
+not be acquired within the given timeout.
 */
 package locker
 
@@ -17,6 +20,7 @@ import (
 	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // ErrNoSuchLock is returned when the requested lock does not exist
@@ -30,12 +34,18 @@ type Locker struct {
 
 // lockCtr is used by Locker to represent a lock with a given name.
 type lockCtr struct {
-	mu sync.Mutex
+	// mu is a channel based mutex so that acquiring it can time out,
+	// it holds a value while the lock is taken.
+	mu chan struct{}
 	// waiters is the number of waiters waiting to acquire the lock
 	// this is int32 instead of uint32 so we can add `-1` in `dec()`
 	waiters int32
 }
 
+func newLockCtr() *lockCtr {
+	return &lockCtr{mu: make(chan struct{}, 1)}
+}
+
 // inc increments the number of waiters waiting for the lock
 func (l *lockCtr) inc() {
 	atomic.AddInt32(&l.waiters, 1)
@@ -53,12 +63,29 @@ func (l *lockCtr) count() int32 {
 
 // Lock locks the mutex
 func (l *lockCtr) Lock() {
-	l.mu.Lock()
+	l.mu <- struct{}{}
+}
+
+// TryLock locks the mutex unless it could not be acquired within timeout
+func (l *lockCtr) TryLock(timeout time.Duration) bool {
+	select {
+	case l.mu <- struct{}{}:
+		return true
+	default:
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case l.mu <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	}
 }
 
 // Unlock unlocks the mutex
 func (l *lockCtr) Unlock() {
-	l.mu.Unlock()
+	<-l.mu
 }
 
 // New creates a new Locker
@@ -77,7 +104,7 @@ func (l *Locker) Lock(name string) {
 
 	nameLock, exists := l.locks[name]
 	if !exists {
-		nameLock = &lockCtr{}
+		nameLock = newLockCtr()
 		l.locks[name] = nameLock
 	}
 
@@ -92,6 +119,39 @@ func (l *Locker) Lock(name string) {
 	nameLock.dec()
 }
 
+// TryLock locks a mutex with the given name like Lock, but gives up and
+// returns false if the lock could not be acquired within timeout.
+func (l *Locker) TryLock(name string, timeout time.Duration) bool {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*lockCtr)
+	}
+
+	nameLock, exists := l.locks[name]
+	if !exists {
+		nameLock = newLockCtr()
+		l.locks[name] = nameLock
+	}
+	nameLock.inc()
+	l.mu.Unlock()
+
+	if nameLock.TryLock(timeout) {
+		nameLock.dec()
+		return true
+	}
+
+	// The holder may have unlocked while we were giving up, in which case
+	// nobody else is going to clean up the lock reference.
+	l.mu.Lock()
+	nameLock.dec()
+	if nameLock.count() == 0 && nameLock.TryLock(0) {
+		delete(l.locks, name)
+		nameLock.Unlock()
+	}
+	l.mu.Unlock()
+	return false
+}
+
 // Unlock unlocks the mutex with the given name
 // If the given lock is not being waited on by any other callers, it is deleted
 func (l *Locker) Unlock(name string) error {
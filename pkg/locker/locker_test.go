@@ -122,3 +122,40 @@ func TestLockerConcurrency(t *testing.T) {
 		t.Fatalf("lock should not exist: %v", ctr)
 	}
 }
+
+func TestLockerTryLock(t *testing.T) {
+	l := New()
+
+	if !l.TryLock("test", time.Second) {
+		t.Fatal("expected to acquire free lock")
+	}
+	if l.TryLock("test", 10*time.Millisecond) {
+		t.Fatal("expected lock acquisition to time out while lock is held")
+	}
+
+	chDone := make(chan bool)
+	go func() {
+		chDone <- l.TryLock("test", 3*time.Second)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := l.Unlock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ok := <-chDone:
+		if !ok {
+			t.Fatal("expected to acquire lock after it was released")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for lock")
+	}
+
+	if err := l.Unlock("test"); err != nil {
+		t.Fatal(err)
+	}
+	if ctr, exists := l.locks["test"]; exists {
+		t.Fatalf("lock should not exist: %v", ctr)
+	}
+}
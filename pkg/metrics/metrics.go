@@ -0,0 +1,312 @@
+// Package metrics provides counters, gauges and histograms which can be
+// exposed over HTTP in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector is a named metric that can write itself in the Prometheus text
+// exposition format.
+type Collector interface {
+	// Name returns the name of the metric.
+	Name() string
+	// WriteTo writes the HELP and TYPE lines and all samples of the metric.
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// Registry holds a set of collectors.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry creates a new, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// DefaultRegistry is the registry the package level functions operate on.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a collector to the registry, replacing any collector that
+// was registered with the same name.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	r.collectors[c.Name()] = c
+	r.mu.Unlock()
+}
+
+// Unregister removes the collector with the given name from the registry.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.collectors, name)
+	r.mu.Unlock()
+}
+
+// WriteTo writes all registered collectors, sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]Collector, len(names))
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mu.RUnlock()
+
+	var written int64
+	for _, c := range collectors {
+		n, err := c.WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ServeHTTP serves the registered metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+// Handler returns an http.Handler serving the metrics of DefaultRegistry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// desc holds what is common to all metric types.
+type desc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d *desc) Name() string {
+	return d.name
+}
+
+func (d *desc) writeHeader(w io.Writer) (int, error) {
+	return fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+}
+
+// key returns the map key for a set of label values.
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// labels formats the label pairs, with an optional extra pair appended.
+func (d *desc) labels(labelValues []string, extra ...string) string {
+	if len(d.labelNames) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(d.labelNames)+1)
+	for i, name := range d.labelNames {
+		pairs = append(pairs, name+"="+strconv.Quote(labelValues[i]))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+"="+strconv.Quote(extra[1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys returns the keys of a labelled metric in a stable order.
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is a monotonically increasing value, optionally partitioned by
+// labels.
+type Counter struct {
+	desc
+	mu        sync.Mutex
+	values    map[string]float64
+	labelSets map[string][]string
+}
+
+// NewCounter creates a counter and registers it with DefaultRegistry.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:      desc{name: name, help: help, typ: "counter", labelNames: labelNames},
+		values:    make(map[string]float64),
+		labelSets: make(map[string][]string),
+	}
+	DefaultRegistry.Register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values by v, which must
+// not be negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.name))
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	c.values[k] += v
+	if _, ok := c.labelSets[k]; !ok {
+		c.labelSets[k] = append([]string(nil), labelValues...)
+	}
+	c.mu.Unlock()
+}
+
+// WriteTo writes the counter in the text exposition format.
+func (c *Counter) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	c.writeHeader(&buf)
+	c.mu.Lock()
+	for _, k := range sortedKeys(c.labelSets) {
+		fmt.Fprintf(&buf, "%s%s %s\n", c.name, c.labels(c.labelSets[k]), formatFloat(c.values[k]))
+	}
+	c.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// GaugeFunc is a gauge whose value is computed when it is collected.
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc creates a gauge reporting the value returned by fn and
+// registers it with DefaultRegistry.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: desc{name: name, help: help, typ: "gauge"},
+		fn:   fn,
+	}
+	DefaultRegistry.Register(g)
+	return g
+}
+
+// WriteTo writes the gauge in the text exposition format.
+func (g *GaugeFunc) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	g.writeHeader(&buf)
+	fmt.Fprintf(&buf, "%s %s\n", g.name, formatFloat(g.fn()))
+	return buf.WriteTo(w)
+}
+
+// DefBuckets are the default histogram buckets, suitable for latencies in
+// seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in configurable buckets, optionally
+// partitioned by labels.
+type Histogram struct {
+	desc
+	buckets   []float64
+	mu        sync.Mutex
+	series    map[string]*histogramSeries
+	labelSets map[string][]string
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds and
+// registers it with DefaultRegistry. DefBuckets are used if buckets is nil.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{
+		desc:      desc{name: name, help: help, typ: "histogram", labelNames: labelNames},
+		buckets:   b,
+		series:    make(map[string]*histogramSeries),
+		labelSets: make(map[string][]string),
+	}
+	DefaultRegistry.Register(h)
+	return h
+}
+
+// Observe adds an observation for the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+		h.labelSets[k] = append([]string(nil), labelValues...)
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// WriteTo writes the histogram in the text exposition format.
+func (h *Histogram) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	h.writeHeader(&buf)
+	h.mu.Lock()
+	for _, k := range sortedKeys(h.labelSets) {
+		s, lv := h.series[k], h.labelSets[k]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", h.name, h.labels(lv, "le", formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_bucket%s %d\n", h.name, h.labels(lv, "le", "+Inf"), s.count)
+		fmt.Fprintf(&buf, "%s_sum%s %s\n", h.name, h.labels(lv), formatFloat(s.sum))
+		fmt.Fprintf(&buf, "%s_count%s %d\n", h.name, h.labels(lv), s.count)
+	}
+	h.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
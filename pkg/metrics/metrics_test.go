@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("test_counter_total", "A test counter.", "action")
+	defer DefaultRegistry.Unregister(c.Name())
+
+	c.Inc("start")
+	c.Inc("start")
+	c.Add(3, "stop")
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_counter_total A test counter.
+# TYPE test_counter_total counter
+test_counter_total{action="start"} 2
+test_counter_total{action="stop"} 3
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestCounterLabelMismatch(t *testing.T) {
+	c := NewCounter("test_counter_mismatch_total", "A test counter.", "action")
+	defer DefaultRegistry.Unregister(c.Name())
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for missing label values")
+		}
+	}()
+	c.Inc()
+}
+
+func TestGaugeFunc(t *testing.T) {
+	v := 1.5
+	g := NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return v })
+	defer DefaultRegistry.Unregister(g.Name())
+
+	v = 4
+	var buf bytes.Buffer
+	if _, err := g.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "test_gauge 4\n") {
+		t.Fatalf("unexpected gauge output:\n%s", buf.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "A test histogram.", []float64{1, 0.1}, "method")
+	defer DefaultRegistry.Unregister(h.Name())
+
+	h.Observe(0.05, "create")
+	h.Observe(0.1, "create")
+	h.Observe(0.5, "create")
+	h.Observe(2, "create")
+
+	var buf bytes.Buffer
+	if _, err := h.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_duration_seconds A test histogram.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="create",le="0.1"} 2
+test_duration_seconds_bucket{method="create",le="1"} 3
+test_duration_seconds_bucket{method="create",le="+Inf"} 4
+test_duration_seconds_sum{method="create"} 2.65
+test_duration_seconds_count{method="create"} 4
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	b := &Counter{desc: desc{name: "b_total", help: "B.", typ: "counter"}, values: map[string]float64{}, labelSets: map[string][]string{}}
+	a := &GaugeFunc{desc: desc{name: "a", help: "A.", typ: "gauge"}, fn: func() float64 { return 1 }}
+	r.Register(b)
+	r.Register(a)
+	b.Inc()
+
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	if strings.Index(body, "# HELP a ") > strings.Index(body, "# HELP b_total ") {
+		t.Fatalf("expected metrics sorted by name:\n%s", body)
+	}
+	if !strings.Contains(body, "b_total 1\n") {
+		t.Fatalf("missing counter sample:\n%s", body)
+	}
+}
@@ -26,6 +26,21 @@ func NewProgressReader(in io.ReadCloser, out Output, size int64, id, action stri
 	}
 }
 
+// NewProgressReaderAt creates a new ProgressReader for the rest of a stream
+// of size bytes, from offset on. This is used to resume an interrupted
+// transfer where it stopped.
+func NewProgressReaderAt(in io.ReadCloser, out Output, size, offset int64, id, action string) *Reader {
+	return &Reader{
+		in:         in,
+		out:        out,
+		size:       size,
+		current:    offset,
+		lastUpdate: offset,
+		id:         id,
+		action:     action,
+	}
+}
+
 func (p *Reader) Read(buf []byte) (n int, err error) {
 	read, err := p.in.Read(buf)
 	p.current += int64(read)
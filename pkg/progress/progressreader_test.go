@@ -73,3 +73,29 @@ drainLoop:
 	default:
 	}
 }
+
+func TestProgressFromOffset(t *testing.T) {
+	content := []byte("TESTING")
+	reader := ioutil.NopCloser(bytes.NewReader(content[3:]))
+	progressChan := make(chan Progress, 10)
+
+	pr := NewProgressReaderAt(reader, ChanOutput(progressChan), int64(len(content)), 3, "Test", "Read")
+
+	out, err := ioutil.ReadAll(pr)
+	if err != nil {
+		pr.Close()
+		t.Fatal(err)
+	}
+	pr.Close()
+	if string(out) != "TING" {
+		t.Fatalf("Unexpected output %q from reader", string(out))
+	}
+
+	var last Progress
+	for len(progressChan) > 0 {
+		last = <-progressChan
+	}
+	if last.Current != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Fatalf("Expected the progress to reach %d of %d, got %d of %d", len(content), len(content), last.Current, last.Total)
+	}
+}
@@ -0,0 +1,86 @@
+package term
+
+import (
+	"io"
+)
+
+// EscapeError is returned by the reader created with NewEscapeProxy once
+// the escape sequence has been read.
+type EscapeError struct{}
+
+func (EscapeError) Error() string {
+	return "read escape sequence"
+}
+
+// escapeProxy is a reader that passes through the data read from r, except
+// for the escape sequence. Bytes that could be the start of the sequence are
+// held back until it is known whether the sequence is complete; if it is
+// not, they are passed through unchanged.
+type escapeProxy struct {
+	escapeKeys []byte
+	matched    int    // number of escape keys matched and held back
+	pending    []byte // data read from r but not yet returned
+	err        error  // error to return once pending has been drained
+	r          io.Reader
+}
+
+// NewEscapeProxy returns a reader that reads from r and returns an
+// EscapeError once escapeKeys have been read in sequence. The escape
+// sequence itself is never passed through, and anything read after it is
+// discarded.
+func NewEscapeProxy(r io.Reader, escapeKeys []byte) io.Reader {
+	if len(escapeKeys) == 0 {
+		return r
+	}
+	return &escapeProxy{
+		escapeKeys: escapeKeys,
+		r:          r,
+	}
+}
+
+func (p *escapeProxy) Read(buf []byte) (int, error) {
+	if len(p.pending) == 0 && p.err == nil {
+		p.fill(len(buf))
+	}
+	if len(p.pending) > 0 {
+		n := copy(buf, p.pending)
+		p.pending = p.pending[n:]
+		return n, nil
+	}
+	err := p.err
+	if _, ok := err.(EscapeError); !ok {
+		p.err = nil
+	}
+	return 0, err
+}
+
+// fill reads up to size bytes from the underlying reader and moves
+// everything that is not part of an escape sequence to p.pending.
+func (p *escapeProxy) fill(size int) {
+	in := make([]byte, size)
+	nr, err := p.r.Read(in)
+	out := make([]byte, 0, p.matched+nr)
+	for _, b := range in[:nr] {
+		if b != p.escapeKeys[p.matched] && p.matched > 0 {
+			// Not the sequence after all, release what was held back.
+			out = append(out, p.escapeKeys[:p.matched]...)
+			p.matched = 0
+		}
+		if b != p.escapeKeys[p.matched] {
+			out = append(out, b)
+			continue
+		}
+		p.matched++
+		if p.matched == len(p.escapeKeys) {
+			p.pending = out
+			p.err = EscapeError{}
+			return
+		}
+	}
+	if err != nil && p.matched > 0 {
+		out = append(out, p.escapeKeys[:p.matched]...)
+		p.matched = 0
+	}
+	p.pending = out
+	p.err = err
+}
@@ -0,0 +1,83 @@
+package term
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// chunkReader returns one chunk per Read, like keystrokes arriving on a tty.
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func readProxy(t *testing.T, keys string, chunks ...string) (string, error) {
+	escapeKeys, err := ToBytes(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	_, err = io.Copy(&out, NewEscapeProxy(&chunkReader{chunks}, escapeKeys))
+	return out.String(), err
+}
+
+func TestEscapeProxySeparateReads(t *testing.T) {
+	out, err := readProxy(t, "ctrl-p,ctrl-q", "a", "\x10", "\x11", "b")
+	if _, ok := err.(EscapeError); !ok {
+		t.Fatalf("expected EscapeError, got %v", err)
+	}
+	if out != "a" {
+		t.Fatalf("expected %q, got %q", "a", out)
+	}
+}
+
+func TestEscapeProxySingleRead(t *testing.T) {
+	out, err := readProxy(t, "ctrl-p,ctrl-q", "ab\x10\x11cd")
+	if _, ok := err.(EscapeError); !ok {
+		t.Fatalf("expected EscapeError, got %v", err)
+	}
+	if out != "ab" {
+		t.Fatalf("expected %q, got %q", "ab", out)
+	}
+}
+
+func TestEscapeProxyPartialMatchIsPreserved(t *testing.T) {
+	out, err := readProxy(t, "ctrl-p,ctrl-q", "\x10", "a", "\x10\x10", "\x11")
+	if _, ok := err.(EscapeError); !ok {
+		t.Fatalf("expected EscapeError, got %v", err)
+	}
+	if out != "\x10a\x10" {
+		t.Fatalf("expected %q, got %q", "\x10a\x10", out)
+	}
+}
+
+func TestEscapeProxyPartialMatchAtEOF(t *testing.T) {
+	out, err := readProxy(t, "ctrl-p,ctrl-q", "a", "\x10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a\x10" {
+		t.Fatalf("expected %q, got %q", "a\x10", out)
+	}
+}
+
+func TestEscapeProxyNoKeys(t *testing.T) {
+	r := &chunkReader{[]string{"\x10\x11"}}
+	out, err := ioutil.ReadAll(NewEscapeProxy(r, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "\x10\x11" {
+		t.Fatalf("expected the input unchanged, got %q", out)
+	}
+}
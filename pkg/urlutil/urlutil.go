@@ -3,6 +3,7 @@
 package urlutil
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 )
@@ -40,6 +41,21 @@ func IsTransportURL(str string) bool {
 	return checkURL(str, "transport")
 }
 
+// Redact returns str with the password in its user information, if it is
+// a URL with one, replaced by "xxxxx", so that it can be shown in output and
+// error messages.
+func Redact(str string) string {
+	u, err := url.Parse(str)
+	if err != nil || u.User == nil {
+		return str
+	}
+	if _, hasPassword := u.User.Password(); !hasPassword {
+		return str
+	}
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
 func checkURL(str, kind string) bool {
 	for _, prefix := range validPrefixes[kind] {
 		if strings.HasPrefix(str, prefix) {
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	registrytypes "github.com/docker/engine-api/types/registry"
+)
+
+const (
+	// mirrorFailureThreshold is the number of consecutive failures after
+	// which a mirror is skipped.
+	mirrorFailureThreshold = 3
+	// mirrorRetryInterval is how long a mirror is skipped for after it
+	// reached mirrorFailureThreshold. The interval doubles with every
+	// failed retry, up to mirrorMaxRetryInterval.
+	mirrorRetryInterval    = 30 * time.Second
+	mirrorMaxRetryInterval = 10 * time.Minute
+)
+
+// mirrorHealth tracks the health of the registry mirrors from the outcome of
+// the pulls going through them. It acts as a circuit breaker: a mirror that
+// failed mirrorFailureThreshold times in a row is skipped until its retry
+// time, after which one pull is let through to find out if it recovered.
+type mirrorHealth struct {
+	mu      sync.Mutex
+	mirrors map[string]*mirrorState // by mirror URL
+	now     func() time.Time
+}
+
+type mirrorState struct {
+	failures int
+	lastErr  string
+	retryAt  time.Time
+}
+
+func newMirrorHealth() *mirrorHealth {
+	return &mirrorHealth{
+		mirrors: make(map[string]*mirrorState),
+		now:     time.Now,
+	}
+}
+
+// succeeded resets the failures of mirror.
+func (h *mirrorHealth) succeeded(mirror string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.mirrors, mirror)
+}
+
+// failed records a failure of mirror, and opens its circuit once it failed
+// mirrorFailureThreshold times in a row.
+func (h *mirrorHealth) failed(mirror string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.mirrors[mirror]
+	if !ok {
+		s = &mirrorState{}
+		h.mirrors[mirror] = s
+	}
+	s.failures++
+	if err != nil {
+		s.lastErr = err.Error()
+	}
+	if s.failures >= mirrorFailureThreshold {
+		interval := mirrorRetryInterval << uint(s.failures-mirrorFailureThreshold)
+		if interval > mirrorMaxRetryInterval || interval <= 0 {
+			interval = mirrorMaxRetryInterval
+		}
+		s.retryAt = h.now().Add(interval)
+	}
+}
+
+// order returns the mirrors to try, in order of preference. Mirrors with
+// fewer consecutive failures come first, and mirrors that are not available
+// are left out. Mirrors with the same number of failures keep their
+// configured order.
+func (h *mirrorHealth) order(mirrors []*url.URL) []*url.URL {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	ordered := make([]*url.URL, 0, len(mirrors))
+	for _, m := range mirrors {
+		if s, ok := h.mirrors[m.String()]; ok && s.failures >= mirrorFailureThreshold && now.Before(s.retryAt) {
+			continue
+		}
+		ordered = append(ordered, m)
+	}
+	sort.Stable(byFailures{ordered, h.mirrors})
+	return ordered
+}
+
+// status returns the health of mirrors.
+func (h *mirrorHealth) status(mirrors []string) []registrytypes.MirrorStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	var status []registrytypes.MirrorStatus
+	for _, m := range mirrors {
+		u, err := parseMirrorURL(m)
+		if err != nil {
+			continue
+		}
+		ms := registrytypes.MirrorStatus{URL: u.String(), Available: true}
+		if s, ok := h.mirrors[ms.URL]; ok {
+			ms.Failures = s.failures
+			ms.LastError = s.lastErr
+			if s.failures >= mirrorFailureThreshold {
+				ms.RetryAt = s.retryAt
+				ms.Available = !now.Before(s.retryAt)
+			}
+		}
+		status = append(status, ms)
+	}
+	return status
+}
+
+type byFailures struct {
+	mirrors []*url.URL
+	state   map[string]*mirrorState
+}
+
+func (s byFailures) Len() int      { return len(s.mirrors) }
+func (s byFailures) Swap(i, j int) { s.mirrors[i], s.mirrors[j] = s.mirrors[j], s.mirrors[i] }
+func (s byFailures) Less(i, j int) bool {
+	return s.failures(i) < s.failures(j)
+}
+
+func (s byFailures) failures(i int) int {
+	if state, ok := s.state[s.mirrors[i].String()]; ok {
+		return state.failures
+	}
+	return 0
+}
+
+// parseMirrorURL parses a configured mirror, which defaults to HTTPS.
+func parseMirrorURL(mirror string) (*url.URL, error) {
+	if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
+		mirror = "https://" + mirror
+	}
+	return url.Parse(mirror)
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMirrorHealth(t *testing.T) {
+	now := time.Now()
+	h := newMirrorHealth()
+	h.now = func() time.Time { return now }
+
+	var mirrors []*url.URL
+	for _, m := range []string{"https://mirror1.local/", "https://mirror2.local/", "https://mirror3.local/"} {
+		u, err := url.Parse(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mirrors = append(mirrors, u)
+	}
+	checkOrder := func(expected ...string) {
+		ordered := h.order(mirrors)
+		if len(ordered) != len(expected) {
+			t.Fatalf("expected mirrors %v, got %v", expected, ordered)
+		}
+		for i, m := range ordered {
+			if m.String() != expected[i] {
+				t.Fatalf("expected mirrors %v, got %v", expected, ordered)
+			}
+		}
+	}
+
+	checkOrder("https://mirror1.local/", "https://mirror2.local/", "https://mirror3.local/")
+
+	// a failing mirror comes after the healthy ones
+	h.failed("https://mirror1.local/", errors.New("connection refused"))
+	checkOrder("https://mirror2.local/", "https://mirror3.local/", "https://mirror1.local/")
+
+	// until it is skipped
+	h.failed("https://mirror1.local/", errors.New("connection refused"))
+	h.failed("https://mirror1.local/", errors.New("connection refused"))
+	checkOrder("https://mirror2.local/", "https://mirror3.local/")
+
+	status := h.status([]string{"https://mirror1.local/", "mirror2.local"})
+	if len(status) != 2 {
+		t.Fatalf("expected the status of 2 mirrors, got %v", status)
+	}
+	if s := status[0]; s.Available || s.Failures != 3 || s.LastError != "connection refused" || !s.RetryAt.Equal(now.Add(mirrorRetryInterval)) {
+		t.Fatalf("unexpected status of a failing mirror: %+v", s)
+	}
+	if s := status[1]; s.URL != "https://mirror2.local" || !s.Available || s.Failures != 0 {
+		t.Fatalf("unexpected status of a healthy mirror: %+v", s)
+	}
+
+	// it is tried again, last, once its retry time has come
+	now = now.Add(mirrorRetryInterval)
+	checkOrder("https://mirror2.local/", "https://mirror3.local/", "https://mirror1.local/")
+
+	// and skipped for twice as long if it still fails
+	h.failed("https://mirror1.local/", errors.New("connection refused"))
+	now = now.Add(mirrorRetryInterval)
+	checkOrder("https://mirror2.local/", "https://mirror3.local/")
+	now = now.Add(mirrorRetryInterval)
+	checkOrder("https://mirror2.local/", "https://mirror3.local/", "https://mirror1.local/")
+
+	// a success makes it healthy again
+	h.succeeded("https://mirror1.local/")
+	checkOrder("https://mirror1.local/", "https://mirror2.local/", "https://mirror3.local/")
+}
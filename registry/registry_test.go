@@ -661,7 +661,7 @@ func TestMirrorEndpointLookup(t *testing.T) {
 		}
 		return false
 	}
-	s := Service{config: makeServiceConfig([]string{"my.mirror"}, nil)}
+	s := Service{config: makeServiceConfig([]string{"my.mirror"}, nil), mirrors: newMirrorHealth()}
 
 	imageName, err := reference.WithName(IndexName + "/test/image")
 	if err != nil {
@@ -684,6 +684,27 @@ func TestMirrorEndpointLookup(t *testing.T) {
 	}
 }
 
+func TestLoadInsecureRegistries(t *testing.T) {
+	s := NewService(ServiceOptions{Mirrors: []string{"https://my.mirror"}})
+	if index, err := s.ResolveIndex("example.com:5000"); err != nil || !index.Secure {
+		t.Fatalf("example.com:5000 should be secure before reloading: %v", err)
+	}
+
+	if err := s.LoadInsecureRegistries([]string{"example.com:5000"}); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := s.ResolveIndex("example.com:5000"); err != nil || index.Secure {
+		t.Fatalf("example.com:5000 should be insecure after reloading: %v", err)
+	}
+	if mirrors := s.ServiceConfig().Mirrors; len(mirrors) != 1 || mirrors[0] != "https://my.mirror" {
+		t.Fatalf("mirrors should be kept after reloading, got %v", mirrors)
+	}
+
+	if err := s.LoadInsecureRegistries([]string{"-invalid"}); err == nil {
+		t.Fatal("expected an error for an invalid registry name")
+	}
+}
+
 func TestPushRegistryTag(t *testing.T) {
 	r := spawnTestRegistrySession(t)
 	repoRef, err := reference.ParseNamed(REPO)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/reference"
@@ -16,20 +17,72 @@ import (
 // Service is a registry service. It tracks configuration data such as a list
 // of mirrors.
 type Service struct {
-	config *serviceConfig
+	mu      sync.Mutex
+	options ServiceOptions
+	config  *serviceConfig
+	mirrors *mirrorHealth
 }
 
 // NewService returns a new instance of Service ready to be
 // installed into an engine.
 func NewService(options ServiceOptions) *Service {
 	return &Service{
-		config: newServiceConfig(options),
+		options: options,
+		config:  newServiceConfig(options),
+		mirrors: newMirrorHealth(),
 	}
 }
 
-// ServiceConfig returns the public registry service configuration.
+// ServiceConfig returns the public registry service configuration, with the
+// current health of the mirrors.
 func (s *Service) ServiceConfig() *registrytypes.ServiceConfig {
-	return &s.config.ServiceConfig
+	config := s.currentConfig().ServiceConfig
+	config.MirrorStatus = s.mirrors.status(config.Mirrors)
+	return &config
+}
+
+// MirrorSucceeded records a pull from the mirror endpoint that succeeded.
+// It is a no-op if endpoint is not a mirror.
+func (s *Service) MirrorSucceeded(endpoint APIEndpoint) {
+	if endpoint.Mirror {
+		s.mirrors.succeeded(endpoint.URL.String())
+	}
+}
+
+// MirrorFailed records a pull from the mirror endpoint that failed because
+// of the mirror. A mirror that fails repeatedly is left out of the pull
+// endpoints for a while. It is a no-op if endpoint is not a mirror.
+func (s *Service) MirrorFailed(endpoint APIEndpoint, err error) {
+	if endpoint.Mirror {
+		s.mirrors.failed(endpoint.URL.String(), err)
+	}
+}
+
+// LoadInsecureRegistries replaces the list of insecure registries the
+// service was created with. The rest of the configuration is kept.
+func (s *Service) LoadInsecureRegistries(registries []string) error {
+	insecureRegistries := make([]string, 0, len(registries))
+	for _, r := range registries {
+		validated, err := ValidateIndexName(r)
+		if err != nil {
+			return err
+		}
+		insecureRegistries = append(insecureRegistries, validated)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options.InsecureRegistries = insecureRegistries
+	s.config = newServiceConfig(s.options)
+	return nil
+}
+
+// currentConfig returns the configuration in use. The configuration is
+// never modified once created, it is only replaced as a whole.
+func (s *Service) currentConfig() *serviceConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
 }
 
 // Auth contacts the public registry with the provided credentials,
@@ -97,7 +150,7 @@ func (s *Service) Search(term string, authConfig *types.AuthConfig, userAgent st
 
 	indexName, remoteName := splitReposSearchTerm(term)
 
-	index, err := newIndexInfo(s.config, indexName)
+	index, err := newIndexInfo(s.currentConfig(), indexName)
 	if err != nil {
 		return nil, err
 	}
@@ -128,12 +181,12 @@ func (s *Service) Search(term string, authConfig *types.AuthConfig, userAgent st
 // ResolveRepository splits a repository name into its components
 // and configuration of the associated registry.
 func (s *Service) ResolveRepository(name reference.Named) (*RepositoryInfo, error) {
-	return newRepositoryInfo(s.config, name)
+	return newRepositoryInfo(s.currentConfig(), name)
 }
 
 // ResolveIndex takes indexName and returns index info
 func (s *Service) ResolveIndex(name string) (*registrytypes.IndexInfo, error) {
-	return newIndexInfo(s.config, name)
+	return newIndexInfo(s.currentConfig(), name)
 }
 
 // APIEndpoint represents a remote API endpoint
@@ -153,7 +206,7 @@ func (e APIEndpoint) ToV1Endpoint(userAgent string, metaHeaders http.Header) (*V
 
 // TLSConfig constructs a client TLS configuration based on server defaults
 func (s *Service) TLSConfig(hostname string) (*tls.Config, error) {
-	return newTLSConfig(hostname, isSecureIndex(s.config, hostname))
+	return newTLSConfig(hostname, isSecureIndex(s.currentConfig(), hostname))
 }
 
 func (s *Service) tlsConfigForMirror(mirrorURL *url.URL) (*tls.Config, error) {
@@ -162,7 +215,8 @@ func (s *Service) tlsConfigForMirror(mirrorURL *url.URL) (*tls.Config, error) {
 
 // LookupPullEndpoints creates a list of endpoints to try to pull from, in order of preference.
 // It gives preference to v2 endpoints over v1, mirrors over the actual
-// registry, and HTTPS over plain HTTP.
+// registry, and HTTPS over plain HTTP. Mirrors that failed recently come
+// after the other mirrors, or are left out if they failed repeatedly.
 func (s *Service) LookupPullEndpoints(hostname string) (endpoints []APIEndpoint, err error) {
 	return s.lookupEndpoints(hostname)
 }
@@ -188,7 +242,7 @@ func (s *Service) lookupEndpoints(hostname string) (endpoints []APIEndpoint, err
 		return nil, err
 	}
 
-	if s.config.V2Only {
+	if s.currentConfig().V2Only {
 		return endpoints, nil
 	}
 
@@ -2,7 +2,6 @@ package registry
 
 import (
 	"net/url"
-	"strings"
 
 	"github.com/docker/go-connections/tlsconfig"
 )
@@ -12,14 +11,15 @@ func (s *Service) lookupV2Endpoints(hostname string) (endpoints []APIEndpoint, e
 	tlsConfig := &cfg
 	if hostname == DefaultNamespace || hostname == DefaultV1Registry.Host {
 		// v2 mirrors
-		for _, mirror := range s.config.Mirrors {
-			if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
-				mirror = "https://" + mirror
-			}
-			mirrorURL, err := url.Parse(mirror)
+		var mirrors []*url.URL
+		for _, mirror := range s.currentConfig().Mirrors {
+			mirrorURL, err := parseMirrorURL(mirror)
 			if err != nil {
 				return nil, err
 			}
+			mirrors = append(mirrors, mirrorURL)
+		}
+		for _, mirrorURL := range s.mirrors.order(mirrors) {
 			mirrorTLSConfig, err := s.tlsConfigForMirror(mirrorURL)
 			if err != nil {
 				return nil, err
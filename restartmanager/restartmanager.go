@@ -5,18 +5,27 @@ import (
 	"sync"
 	"time"
 
+	"github.com/docker/docker/pkg/random"
 	"github.com/docker/engine-api/types/container"
 )
 
 const (
 	backoffMultiplier = 2
 	defaultTimeout    = 100 * time.Millisecond
+	maxRestartTimeout = 1 * time.Minute
+
+	// DefaultResetWindow is how long a container has to run before its
+	// restart backoff is reset, unless configured otherwise.
+	DefaultResetWindow = 10 * time.Second
 )
 
 // RestartManager defines object that controls container restarting rules.
 type RestartManager interface {
 	Cancel() error
-	ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool) (bool, chan error, error)
+	ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error)
+	// NextRestart returns when the pending restart is due, or the zero
+	// time if no restart is pending.
+	NextRestart() time.Time
 }
 
 type restartManager struct {
@@ -25,6 +34,8 @@ type restartManager struct {
 	policy       container.RestartPolicy
 	restartCount int
 	timeout      time.Duration
+	resetWindow  time.Duration
+	nextRestart  time.Time
 	active       bool
 	cancel       chan struct{}
 	canceled     bool
@@ -32,7 +43,7 @@ type restartManager struct {
 
 // New returns a new restartmanager based on a policy.
 func New(policy container.RestartPolicy, restartCount int) RestartManager {
-	return &restartManager{policy: policy, restartCount: restartCount, cancel: make(chan struct{})}
+	return &restartManager{policy: policy, restartCount: restartCount, resetWindow: DefaultResetWindow, cancel: make(chan struct{})}
 }
 
 func (rm *restartManager) SetPolicy(policy container.RestartPolicy) {
@@ -41,7 +52,21 @@ func (rm *restartManager) SetPolicy(policy container.RestartPolicy) {
 	rm.Unlock()
 }
 
-func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool) (bool, chan error, error) {
+// SetResetWindow sets how long a container has to run before the backoff
+// between its restarts is reset.
+func (rm *restartManager) SetResetWindow(window time.Duration) {
+	rm.Lock()
+	rm.resetWindow = window
+	rm.Unlock()
+}
+
+func (rm *restartManager) NextRestart() time.Time {
+	rm.Lock()
+	defer rm.Unlock()
+	return rm.nextRestart
+}
+
+func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error) {
 	rm.Lock()
 	unlockOnExit := true
 	defer func() {
@@ -58,11 +83,20 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 		return false, nil, fmt.Errorf("invalid call on active restartmanager")
 	}
 
-	if rm.timeout == 0 {
+	// A container which ran long enough is considered healthy, and
+	// restarts with the initial timeout again.
+	if executionDuration >= rm.resetWindow {
+		rm.timeout = 0
+	}
+	switch {
+	case rm.timeout == 0:
 		rm.timeout = defaultTimeout
-	} else {
+	case rm.timeout < maxRestartTimeout:
 		rm.timeout *= backoffMultiplier
 	}
+	if rm.timeout > maxRestartTimeout {
+		rm.timeout = maxRestartTimeout
+	}
 
 	var restart bool
 	switch {
@@ -84,6 +118,11 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 
 	rm.restartCount++
 
+	// Wait between half and all of the timeout, so that containers which
+	// failed together do not keep restarting in lockstep.
+	delay := rm.timeout/2 + time.Duration(random.Rand.Int63n(int64(rm.timeout/2)+1))
+	rm.nextRestart = time.Now().Add(delay)
+
 	unlockOnExit = false
 	rm.active = true
 	rm.Unlock()
@@ -92,12 +131,16 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 	go func() {
 		select {
 		case <-rm.cancel:
+			rm.Lock()
+			rm.nextRestart = time.Time{}
+			rm.Unlock()
 			ch <- fmt.Errorf("restartmanager canceled")
 			close(ch)
-		case <-time.After(rm.timeout):
+		case <-time.After(delay):
 			rm.Lock()
 			close(ch)
 			rm.active = false
+			rm.nextRestart = time.Time{}
 			rm.Unlock()
 		}
 	}()
@@ -1,3 +1,139 @@
 package restartmanager
 
-// FIXME
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types/container"
+)
+
+func TestRestartManagerTimeout(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "always"}, 0).(*restartManager)
+	should, _, err := rm.ShouldRestart(0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("container should be restarted")
+	}
+	if rm.timeout != defaultTimeout {
+		t.Fatalf("restart manager should have a timeout of %v, got %v", defaultTimeout, rm.timeout)
+	}
+}
+
+func TestRestartManagerBackoff(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "always"}, 0).(*restartManager)
+	expected := defaultTimeout
+	for i := 0; i < 15; i++ {
+		rm.active = false
+		if _, _, err := rm.ShouldRestart(0, false, 0); err != nil {
+			t.Fatal(err)
+		}
+		if rm.timeout != expected {
+			t.Fatalf("restart %d: expected a timeout of %v, got %v", i, expected, rm.timeout)
+		}
+		expected *= backoffMultiplier
+		if expected > maxRestartTimeout {
+			expected = maxRestartTimeout
+		}
+	}
+	if rm.timeout != maxRestartTimeout {
+		t.Fatalf("expected the timeout to be capped at %v, got %v", maxRestartTimeout, rm.timeout)
+	}
+	rm.Cancel()
+}
+
+func TestRestartManagerResetWindow(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "always"}, 0).(*restartManager)
+	rm.SetResetWindow(time.Second)
+	rm.timeout = 10 * time.Second
+
+	if _, _, err := rm.ShouldRestart(0, false, 500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if rm.timeout != 20*time.Second {
+		t.Fatalf("expected the backoff to keep growing, got %v", rm.timeout)
+	}
+
+	rm.active = false
+	if _, _, err := rm.ShouldRestart(0, false, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if rm.timeout != defaultTimeout {
+		t.Fatalf("expected the backoff to be reset to %v, got %v", defaultTimeout, rm.timeout)
+	}
+	rm.Cancel()
+}
+
+func TestRestartManagerNextRestart(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "on-failure"}, 0)
+	if !rm.NextRestart().IsZero() {
+		t.Fatal("expected no pending restart")
+	}
+
+	before := time.Now()
+	should, wait, err := rm.ShouldRestart(1, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("container should be restarted")
+	}
+	next := rm.NextRestart()
+	if next.Before(before.Add(defaultTimeout/2)) || next.After(time.Now().Add(defaultTimeout)) {
+		t.Fatalf("next restart %v is not within the jittered timeout", next)
+	}
+
+	if err := <-wait; err != nil {
+		t.Fatal(err)
+	}
+	if time.Now().Before(next) {
+		t.Fatal("restart happened before it was due")
+	}
+	if !rm.NextRestart().IsZero() {
+		t.Fatal("expected no pending restart after the restart")
+	}
+}
+
+func TestRestartManagerOnFailureMaxRetries(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 1}, 0).(*restartManager)
+	should, _, err := rm.ShouldRestart(0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if should {
+		t.Fatal("container should not be restarted after a successful exit")
+	}
+
+	should, _, err = rm.ShouldRestart(1, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("container should be restarted")
+	}
+
+	rm.active = false
+	should, _, err = rm.ShouldRestart(1, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if should {
+		t.Fatal("container should not be restarted past the maximum retry count")
+	}
+}
+
+func TestRestartManagerCancel(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "always"}, 0)
+	_, wait, err := rm.ShouldRestart(0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm.Cancel()
+	if err := <-wait; err == nil {
+		t.Fatal("expected an error for a canceled restart")
+	}
+	if _, _, err := rm.ShouldRestart(0, false, 0); err == nil {
+		t.Fatal("expected an error from a canceled restart manager")
+	}
+}
@@ -22,7 +22,8 @@ func Compare(a, b *container.Config) bool {
 		len(a.Labels) != len(b.Labels) ||
 		len(a.ExposedPorts) != len(b.ExposedPorts) ||
 		len(a.Entrypoint) != len(b.Entrypoint) ||
-		len(a.Volumes) != len(b.Volumes) {
+		len(a.Volumes) != len(b.Volumes) ||
+		len(a.Shell) != len(b.Shell) {
 		return false
 	}
 
@@ -52,6 +53,11 @@ func Compare(a, b *container.Config) bool {
 			return false
 		}
 	}
+	for i := 0; i < len(a.Shell); i++ {
+		if a.Shell[i] != b.Shell[i] {
+			return false
+		}
+	}
 	for key := range a.Volumes {
 		if _, exists := b.Volumes[key]; !exists {
 			return false
@@ -40,3 +40,17 @@ func TestUlimitOpt(t *testing.T) {
 		t.Fatalf("Expected a ulimit list of 2, got %v", ulimits)
 	}
 }
+
+func TestNamedUlimitOpt(t *testing.T) {
+	ulimitMap := map[string]*units.Ulimit{}
+	ulimitOpt := NewNamedUlimitOpt("default-ulimits", &ulimitMap)
+	if ulimitOpt.Name() != "default-ulimits" {
+		t.Fatalf("Expected name default-ulimits, got %s", ulimitOpt.Name())
+	}
+	if err := ulimitOpt.Set("nofile=512:1024"); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := ulimitMap["nofile"]; !ok || l.Soft != 512 || l.Hard != 1024 {
+		t.Fatalf("Expected nofile=512:1024 to be set, got %v", ulimitMap)
+	}
+}
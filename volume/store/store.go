@@ -10,6 +10,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/boltdb/bolt"
+	"github.com/docker/docker/pkg/directory"
 	"github.com/docker/docker/pkg/locker"
 	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
@@ -455,6 +456,23 @@ func (s *VolumeStore) Refs(v volume.Volume) []string {
 	return refsOut
 }
 
+// Size returns the disk space used by the given volume. Only volumes of the
+// local driver can be measured; -1 is returned for volumes of other drivers.
+func (s *VolumeStore) Size(v volume.Volume) (int64, error) {
+	if v.DriverName() != volume.DefaultDriverName {
+		return -1, nil
+	}
+
+	s.locks.Lock(v.Name())
+	defer s.locks.Unlock(v.Name())
+
+	size, err := directory.Size(v.Path())
+	if err != nil {
+		return -1, &OpErr{Err: err, Name: v.Name(), Op: "size"}
+	}
+	return size, nil
+}
+
 // FilterByDriver returns the available volumes filtered by driver name
 func (s *VolumeStore) FilterByDriver(name string) ([]volume.Volume, error) {
 	vd, err := volumedrivers.GetDriver(name)
@@ -2,10 +2,15 @@ package store
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
+	"github.com/docker/docker/volume/local"
 	vt "github.com/docker/docker/volume/testutils"
 )
 
@@ -199,3 +204,45 @@ func TestDerefMultipleOfSameRef(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSize(t *testing.T) {
+	volumedrivers.Register(vt.NewFakeDriver("fake"), "fake")
+	defer volumedrivers.Unregister("fake")
+
+	rootDir, err := ioutil.TempDir("", "volume-store-size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	localDriver, err := local.New(rootDir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumedrivers.Register(localDriver, volume.DefaultDriverName)
+	defer volumedrivers.Unregister(volume.DefaultDriverName)
+
+	s, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake, err := s.Create("fake1", "fake", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.Size(fake); err != nil || size != -1 {
+		t.Fatalf("expected size -1 for a volume of another driver, got %d (%v)", size, err)
+	}
+
+	v, err := s.Create("local1", volume.DefaultDriverName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(v.Path(), "data"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.Size(v); err != nil || size != 100 {
+		t.Fatalf("expected size 100, got %d (%v)", size, err)
+	}
+}